@@ -0,0 +1,442 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"sync"
+	"time"
+
+	less_go "github.com/toakleaf/less.go/less"
+)
+
+// buildJob is one entrypoint of the build command
+type buildJob struct {
+	input  string
+	output string
+	err    error
+}
+
+// runBuild implements `lessc-go build`: it compiles every non-partial .less
+// file matching the patterns into the output directory, keeping the directory
+// structure, and returns the process exit code.
+func runBuild(args []string) int {
+	fset := flag.NewFlagSet("build", flag.ContinueOnError)
+	fset.Usage = printBuildUsage
+
+	var (
+		outDir   string
+		jobs     int
+		partials stringSliceFlag
+	)
+	fset.StringVar(&outDir, "out-dir", "", "Directory to write CSS files to (default: next to each source)")
+	fset.StringVar(&outDir, "o", "", "Output directory (shorthand)")
+	fset.IntVar(&jobs, "jobs", runtime.NumCPU(), "Number of files compiled in parallel")
+	fset.IntVar(&jobs, "j", runtime.NumCPU(), "Number of parallel jobs (shorthand)")
+	fset.Var(&partials, "partial", "Glob of partial files to skip (default: _*, can be repeated)")
+	flags := newCompileFlags(fset)
+
+	// Flags may follow the patterns, as in `build src/**/*.less --out-dir dist`
+	var patterns []string
+	rest := args
+	for {
+		if err := fset.Parse(rest); err != nil {
+			if errors.Is(err, flag.ErrHelp) {
+				return 0
+			}
+			return 2
+		}
+		rest = fset.Args()
+		if len(rest) == 0 {
+			break
+		}
+		patterns = append(patterns, rest[0])
+		rest = rest[1:]
+	}
+
+	flags.parsed(fset)
+
+	config, err := flags.loadConfig()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error loading config: %v\n", err)
+		return 2
+	}
+
+	// Without patterns, build the entrypoints of the project config
+	var buildJobs []*buildJob
+	skipped := 0
+	if len(patterns) == 0 {
+		if config == nil || len(config.Entrypoints) == 0 {
+			fmt.Fprintln(os.Stderr, "Error: No input files specified")
+			printBuildUsage()
+			return 2
+		}
+		buildJobs, err = config.buildJobs(outDir)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			return 2
+		}
+	} else {
+		if len(partials) == 0 {
+			partials = stringSliceFlag{"_*"}
+		}
+		buildJobs, skipped, err = collectBuildJobs(patterns, partials, outDir)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			return 2
+		}
+	}
+	if len(buildJobs) == 0 {
+		fmt.Fprintln(os.Stderr, "Error: No files to compile")
+		return 2
+	}
+	report, err := flags.reporter()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 2
+	}
+	return buildAll(buildJobs, skipped, jobs, flags, config, report)
+}
+
+// buildAll compiles the jobs with the given number of workers, reporting
+// each failure, then prints a summary and returns the exit code
+func buildAll(buildJobs []*buildJob, skipped, jobs int, flags *compileFlags, config *projectConfig, report *reporter) int {
+	if jobs < 1 {
+		jobs = 1
+	}
+
+	start := time.Now()
+	compiler := less_go.NewCompiler(flags.compileOptions(config, "", nil, ""))
+	defer compiler.Close()
+
+	queue := make(chan *buildJob)
+	var wg sync.WaitGroup
+	for i := 0; i < jobs; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for job := range queue {
+				result, warnings, err := buildFile(compiler, flags, config, job)
+				job.err = err
+
+				var message string
+				switch {
+				case result != nil && err != nil:
+					// Compiled, but writing the output failed
+					message = fmt.Sprintf("FAIL %s: %v\n", job.input, err)
+					if report.json {
+						message = report.format(job.input, job.output, warnings, err, flags.silent)
+					}
+				case err != nil:
+					message = report.format(job.input, job.output, warnings, err, flags.silent)
+					if !report.json {
+						message = fmt.Sprintf("FAIL %s\n", job.input) + message
+					}
+				default:
+					message = report.format(job.input, job.output, warnings, nil, flags.silent)
+					if !flags.silent {
+						message += fmt.Sprintf("Compiled %s -> %s\n", job.input, job.output)
+					}
+				}
+				report.write(message)
+			}
+		}()
+	}
+	for _, job := range buildJobs {
+		queue <- job
+	}
+	close(queue)
+	wg.Wait()
+
+	var failed []string
+	for _, job := range buildJobs {
+		if job.err != nil {
+			failed = append(failed, job.input)
+		}
+	}
+
+	summary := fmt.Sprintf("%d compiled, %d failed, %d partials skipped in %v",
+		len(buildJobs)-len(failed), len(failed), skipped, time.Since(start).Round(time.Millisecond))
+	if len(failed) > 0 {
+		if report.json {
+			return 1
+		}
+		fmt.Fprintf(os.Stderr, "Build failed: %s\nFailed files:\n  %s\n", summary, strings.Join(failed, "\n  "))
+		return 1
+	}
+	if !flags.silent {
+		fmt.Fprintf(os.Stderr, "Build succeeded: %s\n", summary)
+	}
+	return 0
+}
+
+// buildFile compiles one entrypoint and writes its CSS and source map. It
+// returns the warnings of the compilation; the result is nil if it failed.
+func buildFile(compiler *less_go.Compiler, flags *compileFlags, config *projectConfig, job *buildJob) (*less_go.CompileResult, []less_go.Diagnostic, error) {
+	absPath, err := filepath.Abs(job.input)
+	if err != nil {
+		return nil, nil, err
+	}
+	options := flags.compileOptions(config, absPath, []string{filepath.Dir(absPath)}, job.output)
+	warnings := collectWarnings(options)
+
+	result, err := compiler.WithOptions(options).CompileFile(absPath)
+	if err != nil {
+		return nil, warnings(), err
+	}
+	if err := os.MkdirAll(filepath.Dir(job.output), 0755); err != nil {
+		return result, warnings(), err
+	}
+	output := flags.output(options)
+	output.silent = true
+	return result, warnings(), output.write(result, job.input, job.output)
+}
+
+// collectBuildJobs expands the patterns into entrypoints, skipping partials,
+// and works out where each one is written. It also returns the number of
+// partials skipped.
+func collectBuildJobs(patterns, partials []string, outDir string) ([]*buildJob, int, error) {
+	type expanded struct {
+		base  string
+		files []string
+	}
+	var expansions []expanded
+	var literals []string
+	for _, pattern := range patterns {
+		base, files, err := expandPattern(pattern)
+		if err != nil {
+			return nil, 0, err
+		}
+		if len(files) == 0 {
+			return nil, 0, fmt.Errorf("no .less files match %s", pattern)
+		}
+		if base == "" {
+			literals = append(literals, files...)
+		}
+		expansions = append(expansions, expanded{base, files})
+	}
+
+	// Files given one by one, as the shell expands src/**/*.less, keep their
+	// paths below the directory they have in common
+	literalBase := ""
+	if len(literals) > 0 {
+		var err error
+		if literalBase, err = commonDir(literals); err != nil {
+			return nil, 0, err
+		}
+	}
+
+	var jobs []*buildJob
+	skipped := 0
+	seen := make(map[string]bool)
+	for _, e := range expansions {
+		for _, file := range e.files {
+			if seen[file] {
+				continue
+			}
+			seen[file] = true
+
+			base, target := e.base, file
+			if base == "" {
+				base = literalBase
+				abs, err := filepath.Abs(file)
+				if err != nil {
+					return nil, 0, err
+				}
+				target = abs
+			}
+			rel, err := filepath.Rel(base, target)
+			if err != nil {
+				return nil, 0, err
+			}
+			if isPartial(filepath.ToSlash(rel), partials) {
+				skipped++
+				continue
+			}
+
+			output := strings.TrimSuffix(file, filepath.Ext(file)) + ".css"
+			if outDir != "" {
+				output = filepath.Join(outDir, strings.TrimSuffix(rel, filepath.Ext(rel))+".css")
+			}
+			jobs = append(jobs, &buildJob{input: file, output: output})
+		}
+	}
+	if err := checkOutputs(jobs); err != nil {
+		return nil, 0, err
+	}
+	return jobs, skipped, nil
+}
+
+// checkOutputs returns an error if two jobs would write the same file
+func checkOutputs(jobs []*buildJob) error {
+	inputs := make(map[string]string, len(jobs))
+	for _, job := range jobs {
+		output, err := filepath.Abs(job.output)
+		if err != nil {
+			return err
+		}
+		if input, ok := inputs[output]; ok {
+			return fmt.Errorf("%s and %s would both be written to %s", input, job.input, job.output)
+		}
+		inputs[output] = job.input
+	}
+	return nil
+}
+
+// commonDir returns the absolute path of the deepest directory containing all
+// the files
+func commonDir(files []string) (string, error) {
+	var dir string
+	for i, file := range files {
+		abs, err := filepath.Abs(file)
+		if err != nil {
+			return "", err
+		}
+		if i == 0 {
+			dir = filepath.Dir(abs)
+			continue
+		}
+		for !isWithin(dir, abs) {
+			parent := filepath.Dir(dir)
+			if parent == dir {
+				return "", fmt.Errorf("%s and %s have no directory in common", files[0], file)
+			}
+			dir = parent
+		}
+	}
+	return dir, nil
+}
+
+// isWithin reports whether the absolute path file is below the directory dir
+func isWithin(dir, file string) bool {
+	rel, err := filepath.Rel(dir, file)
+	return err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
+}
+
+// expandPattern returns the .less files matching pattern, which may contain
+// wildcards, including ** for any number of directories, and the directory
+// output paths are made relative to: the part of the pattern before the first
+// wildcard. A directory stands for every .less file below it. For a single
+// file the directory is empty: it depends on the other files given, see
+// collectBuildJobs.
+func expandPattern(pattern string) (string, []string, error) {
+	parts := strings.Split(filepath.ToSlash(pattern), "/")
+	literal := len(parts)
+	for i, part := range parts {
+		if strings.ContainsAny(part, "*?[") {
+			literal = i
+			break
+		}
+	}
+
+	if literal == len(parts) {
+		info, err := os.Stat(pattern)
+		if err != nil {
+			return "", nil, err
+		}
+		if !info.IsDir() {
+			return "", []string{filepath.Clean(pattern)}, nil
+		}
+		parts = append(parts, "**", "*.less")
+	}
+
+	base := filepath.FromSlash(strings.Join(parts[:literal], "/"))
+	if base == "" {
+		base = "."
+		if literal > 0 {
+			base = "/"
+		}
+	}
+	var files []string
+	err := filepath.WalkDir(base, func(file string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() || filepath.Ext(file) != ".less" {
+			return nil
+		}
+		rel, err := filepath.Rel(base, file)
+		if err != nil {
+			return err
+		}
+		if matchPath(parts[literal:], strings.Split(filepath.ToSlash(rel), "/")) {
+			files = append(files, file)
+		}
+		return nil
+	})
+	return base, files, err
+}
+
+// matchPath matches path components against glob components, where ** matches
+// any number of components
+func matchPath(pattern, name []string) bool {
+	for len(pattern) > 0 {
+		if pattern[0] == "**" {
+			for i := 0; i <= len(name); i++ {
+				if matchPath(pattern[1:], name[i:]) {
+					return true
+				}
+			}
+			return false
+		}
+		if len(name) == 0 {
+			return false
+		}
+		if ok, _ := path.Match(pattern[0], name[0]); !ok {
+			return false
+		}
+		pattern, name = pattern[1:], name[1:]
+	}
+	return len(name) == 0
+}
+
+// isPartial reports whether the file at rel (slash-separated) matches one of
+// the partial globs. Globs without a slash are matched against the file name.
+func isPartial(rel string, partials []string) bool {
+	for _, partial := range partials {
+		if strings.Contains(partial, "/") {
+			if matchPath(strings.Split(partial, "/"), strings.Split(rel, "/")) {
+				return true
+			}
+		} else if ok, _ := path.Match(partial, path.Base(rel)); ok {
+			return true
+		}
+	}
+	return false
+}
+
+func printBuildUsage() {
+	fmt.Printf(`Usage: lessc-go build [options] <pattern|dir>...
+
+Compiles every .less file matching the patterns, except partials, writing each
+to the output directory with the same relative path and a .css extension.
+Patterns may use * and ** (any number of directories); quote them so the shell
+does not expand them. Paths are kept relative to the part of the pattern
+before the first wildcard, to the directory given, or for files given one by
+one to the directory they have in common. Two files written to the same
+output are an error.
+
+Examples:
+  lessc-go build 'src/**/*.less' --out-dir dist
+  lessc-go build src --out-dir dist --jobs 4 --compress
+  lessc-go build 'themes/*/main.less' -o public/css
+
+Build Options:
+  -o, --out-dir=DIR        Write CSS files to DIR (default: next to each source)
+  -j, --jobs=N             Compile N files in parallel (default: number of CPUs)
+  --partial=GLOB           Skip files matching GLOB (default: _*); globs without
+                           a slash match the file name. Repeatable.
+
+Compilation options (--compress, --include-path, --source-map, --plugin, ...)
+are the same as for a single file; see lessc-go --help.
+
+Exit status is 0 when every file compiled, 1 when any failed (all files are
+still attempted and the failures are listed at the end), and 2 for usage errors.
+`)
+}
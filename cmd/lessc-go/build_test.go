@@ -0,0 +1,231 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// writeFiles creates files with the given contents below dir
+func writeFiles(t *testing.T, dir string, files map[string]string) {
+	t.Helper()
+	for name, content := range files {
+		file := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(file), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(file, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestExpandPattern(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{
+		"src/main.less":         "",
+		"src/_vars.less":        "",
+		"src/themes/dark.less":  "",
+		"src/themes/light.less": "",
+		"src/themes/notes.txt":  "",
+	})
+	src := filepath.Join(dir, "src")
+
+	tests := []struct {
+		pattern string
+		base    string
+		files   []string
+	}{
+		{"src/*.less", "src", []string{"src/_vars.less", "src/main.less"}},
+		{"src/**/*.less", "src", []string{"src/_vars.less", "src/main.less", "src/themes/dark.less", "src/themes/light.less"}},
+		{"src/themes/d*.less", "src/themes", []string{"src/themes/dark.less"}},
+		{"src/themes", "src/themes", []string{"src/themes/dark.less", "src/themes/light.less"}},
+		// A single file has no base of its own
+		{"src/themes/dark.less", "", []string{"src/themes/dark.less"}},
+	}
+	for _, tt := range tests {
+		base, files, err := expandPattern(filepath.Join(dir, filepath.FromSlash(tt.pattern)))
+		if err != nil {
+			t.Errorf("%s: %v", tt.pattern, err)
+			continue
+		}
+		wantBase := ""
+		if tt.base != "" {
+			wantBase = filepath.Join(dir, filepath.FromSlash(tt.base))
+		}
+		if base != wantBase {
+			t.Errorf("%s: expected base %q, got %q", tt.pattern, wantBase, base)
+		}
+		var want []string
+		for _, file := range tt.files {
+			want = append(want, filepath.Join(dir, filepath.FromSlash(file)))
+		}
+		if !reflect.DeepEqual(files, want) {
+			t.Errorf("%s: expected %v, got %v", tt.pattern, want, files)
+		}
+	}
+
+	if _, _, err := expandPattern(filepath.Join(src, "missing.less")); err == nil {
+		t.Error("expected an error for a missing file")
+	}
+}
+
+func TestIsPartial(t *testing.T) {
+	tests := []struct {
+		rel      string
+		partials []string
+		want     bool
+	}{
+		{"_vars.less", []string{"_*"}, true},
+		{"themes/_mixins.less", []string{"_*"}, true},
+		{"themes/dark.less", []string{"_*"}, false},
+		{"themes/dark.less", []string{"themes/*"}, true},
+		{"lib/themes/dark.less", []string{"themes/*"}, false},
+		{"lib/themes/dark.less", []string{"**/themes/*"}, true},
+		{"main.less", []string{"_*", "main.*"}, true},
+		{"main.less", nil, false},
+	}
+	for _, tt := range tests {
+		if got := isPartial(tt.rel, tt.partials); got != tt.want {
+			t.Errorf("isPartial(%q, %v) = %v, want %v", tt.rel, tt.partials, got, tt.want)
+		}
+	}
+}
+
+func TestCollectBuildJobs(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{
+		"src/a/main.less":   "",
+		"src/a/_vars.less":  "",
+		"src/b/main.less":   "",
+		"src/b/theme.less":  "",
+		"lib/reset.less":    "",
+		"lib/_helpers.less": "",
+	})
+	path := func(name string) string {
+		return filepath.Join(dir, filepath.FromSlash(name))
+	}
+	out := path("out")
+
+	tests := []struct {
+		name     string
+		patterns []string
+		outDir   string
+		jobs     map[string]string
+		skipped  int
+		err      string
+	}{
+		{
+			name:     "wildcard",
+			patterns: []string{path("src/**/*.less")},
+			outDir:   out,
+			jobs: map[string]string{
+				"src/a/main.less":  "out/a/main.css",
+				"src/b/main.less":  "out/b/main.css",
+				"src/b/theme.less": "out/b/theme.css",
+			},
+			skipped: 1,
+		},
+		{
+			// As the shell expands an unquoted src/**/*.less
+			name:     "literal files keep their common directory",
+			patterns: []string{path("src/a/main.less"), path("src/a/_vars.less"), path("src/b/main.less")},
+			outDir:   out,
+			jobs: map[string]string{
+				"src/a/main.less": "out/a/main.css",
+				"src/b/main.less": "out/b/main.css",
+			},
+			skipped: 1,
+		},
+		{
+			name:     "single literal file",
+			patterns: []string{path("src/b/theme.less")},
+			outDir:   out,
+			jobs:     map[string]string{"src/b/theme.less": "out/theme.css"},
+		},
+		{
+			name:     "next to the sources",
+			patterns: []string{path("lib")},
+			jobs:     map[string]string{"lib/reset.less": "lib/reset.css"},
+			skipped:  1,
+		},
+		{
+			name:     "same output",
+			patterns: []string{path("src/a/*.less"), path("src/b/*.less")},
+			outDir:   out,
+			err:      "would both be written to " + filepath.Join(out, "main.css"),
+		},
+		{
+			name:     "no match",
+			patterns: []string{path("src/*.less")},
+			err:      "no .less files match",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			jobs, skipped, err := collectBuildJobs(tt.patterns, []string{"_*"}, tt.outDir)
+			if tt.err != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.err) {
+					t.Fatalf("expected an error with %q, got %v", tt.err, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			got := make(map[string]string)
+			for _, job := range jobs {
+				input, _ := filepath.Rel(dir, job.input)
+				output, _ := filepath.Rel(dir, job.output)
+				got[filepath.ToSlash(input)] = filepath.ToSlash(output)
+			}
+			if !reflect.DeepEqual(got, tt.jobs) {
+				t.Errorf("expected jobs %v, got %v", tt.jobs, got)
+			}
+			if skipped != tt.skipped {
+				t.Errorf("expected %d partials skipped, got %d", tt.skipped, skipped)
+			}
+		})
+	}
+}
+
+func TestConfigBuildJobs(t *testing.T) {
+	dir := t.TempDir()
+	config := &projectConfig{
+		path: filepath.Join(dir, configFileName),
+		Entrypoints: []configEntrypoint{
+			{Input: "src/a/main.less"},
+			{Input: "src/b/main.less"},
+			{Input: "src/site.less", Output: "public/site.css"},
+		},
+	}
+
+	tests := []struct {
+		outDir  string
+		outputs []string
+	}{
+		{"", []string{"src/a/main.css", "src/b/main.css", "public/site.css"}},
+		{filepath.Join(dir, "out"), []string{"out/a/main.css", "out/b/main.css", "public/site.css"}},
+	}
+	for _, tt := range tests {
+		jobs, err := config.buildJobs(tt.outDir)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var outputs []string
+		for _, job := range jobs {
+			output, _ := filepath.Rel(dir, job.output)
+			outputs = append(outputs, filepath.ToSlash(output))
+		}
+		if !reflect.DeepEqual(outputs, tt.outputs) {
+			t.Errorf("out-dir %q: expected %v, got %v", tt.outDir, tt.outputs, outputs)
+		}
+	}
+
+	config.Entrypoints[2].Output = "src/a/main.css"
+	if _, err := config.buildJobs(""); err == nil || !strings.Contains(err.Error(), "would both be written to") {
+		t.Errorf("expected an error for two entrypoints with the same output, got %v", err)
+	}
+}
@@ -0,0 +1,428 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	less_go "github.com/toakleaf/less.go/less"
+)
+
+// configFileName is the project config file looked up from the working directory upwards
+const configFileName = "lessgo.json"
+
+// projectConfig is the contents of lessgo.json, or of the "lessgo" key in
+// package.json. Top-level options apply to every compilation; each entrypoint
+// may override them. Relative paths are resolved against the config file's
+// directory.
+//
+//	{
+//	  "paths": ["src/lib"],
+//	  "math": "parens",
+//	  "globalVars": {"brand": "#336699"},
+//	  "sourceMap": true,
+//	  "plugins": ["./build/plugin.js", {"name": "clean-css", "options": "--s1"}],
+//	  "entrypoints": [
+//	    {"input": "src/site.less", "output": "dist/site.css"},
+//	    {"input": "src/admin.less", "output": "dist/admin.css", "compress": true}
+//	  ]
+//	}
+type projectConfig struct {
+	configOptions
+	Entrypoints []configEntrypoint `json:"entrypoints"`
+
+	// path is the file the config was read from
+	path string
+}
+
+// configOptions mirror less.CompileOptions. Fields left out of the file keep
+// the value from the level above.
+type configOptions struct {
+	Paths                   []string                `json:"paths"`
+	Compress                *bool                   `json:"compress"`
+	StrictUnits             *bool                   `json:"strictUnits"`
+	Math                    string                  `json:"math"`
+	RewriteUrls             string                  `json:"rewriteUrls"`
+	ResolveModernColors     string                  `json:"resolveModernColors"`
+	MinContrastRatio        *float64                `json:"minContrastRatio"`
+	Rootpath                *string                 `json:"rootpath"`
+	UrlArgs                 *string                 `json:"urlArgs"`
+	EnableJavaScriptPlugins *bool                   `json:"enableJavaScriptPlugins"`
+	JavascriptEnabled       *bool                   `json:"javascriptEnabled"`
+	Plugins                 []configPlugin          `json:"plugins"`
+	GlobalVars              map[string]any          `json:"globalVars"`
+	ModifyVars              map[string]any          `json:"modifyVars"`
+	SourceMap               *bool                   `json:"sourceMap"`
+	SourceMapOptions        *configSourceMapOptions `json:"sourceMapOptions"`
+}
+
+// configSourceMapOptions mirror less.SourceMapOptions
+type configSourceMapOptions struct {
+	SourceMapFilename          *string `json:"sourceMapFilename"`
+	SourceMapURL               *string `json:"sourceMapURL"`
+	SourceMapBasepath          *string `json:"sourceMapBasepath"`
+	SourceMapRootpath          *string `json:"sourceMapRootpath"`
+	SourceMapOutputFilename    *string `json:"sourceMapOutputFilename"`
+	OutputSourceFiles          *bool   `json:"outputSourceFiles"`
+	SourceMapFileInline        *bool   `json:"sourceMapFileInline"`
+	DisableSourcemapAnnotation *bool   `json:"disableSourcemapAnnotation"`
+}
+
+// configPlugin is a plugin spec, written either as "name", "name=options" or
+// {"name": ..., "options": ...}
+type configPlugin less_go.PluginSpec
+
+func (p *configPlugin) UnmarshalJSON(data []byte) error {
+	var name string
+	if err := json.Unmarshal(data, &name); err == nil {
+		var spec pluginSliceFlag
+		spec.Set(name)
+		*p = configPlugin(spec[0])
+		return nil
+	}
+	var spec struct {
+		Name    string `json:"name"`
+		Options string `json:"options"`
+	}
+	if err := strictUnmarshal(data, &spec); err != nil {
+		return err
+	}
+	*p = configPlugin{Name: spec.Name, Options: spec.Options}
+	return nil
+}
+
+// configEntrypoint is a file to compile, with options overriding the top-level
+// ones. It may be written as just the input path.
+type configEntrypoint struct {
+	Input  string `json:"input"`
+	Output string `json:"output"`
+	configOptions
+}
+
+func (e *configEntrypoint) UnmarshalJSON(data []byte) error {
+	var input string
+	if err := json.Unmarshal(data, &input); err == nil {
+		*e = configEntrypoint{Input: input}
+		return nil
+	}
+	type plain configEntrypoint
+	if err := strictUnmarshal(data, (*plain)(e)); err != nil {
+		return err
+	}
+	if e.Input == "" {
+		return errors.New(`entrypoint without "input"`)
+	}
+	return nil
+}
+
+// strictUnmarshal decodes data into v, rejecting unknown keys so that typos are reported
+func strictUnmarshal(data []byte, v any) error {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.DisallowUnknownFields()
+	return decoder.Decode(v)
+}
+
+// loadConfig reads the config file at path, or when path is empty looks for
+// lessgo.json, or a package.json with a "lessgo" key, in the working directory
+// and its parents. It returns nil if there is none.
+func loadConfig(path string) (*projectConfig, error) {
+	if path != "" {
+		return readConfig(path)
+	}
+
+	dir, err := os.Getwd()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		candidate := filepath.Join(dir, configFileName)
+		if _, err := os.Stat(candidate); err == nil {
+			return readConfig(candidate)
+		}
+		candidate = filepath.Join(dir, "package.json")
+		if _, err := os.Stat(candidate); err == nil {
+			config, err := readConfig(candidate)
+			if config != nil || err != nil {
+				return config, err
+			}
+		}
+
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return nil, nil
+		}
+		dir = parent
+	}
+}
+
+// readConfig parses a lessgo.json file, or the "lessgo" key of a package.json.
+// A package.json without the key yields nil.
+func readConfig(path string) (*projectConfig, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	if filepath.Base(path) == "package.json" {
+		var pkg map[string]json.RawMessage
+		if err := json.Unmarshal(data, &pkg); err != nil {
+			return nil, fmt.Errorf("%s: %w", path, err)
+		}
+		raw, ok := pkg["lessgo"]
+		if !ok {
+			return nil, nil
+		}
+		data = raw
+	}
+
+	config := &projectConfig{}
+	if err := strictUnmarshal(data, config); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	config.path, err = filepath.Abs(path)
+	if err != nil {
+		return nil, err
+	}
+	for _, options := range config.allOptions() {
+		if err := options.validate(); err != nil {
+			return nil, fmt.Errorf("%s: %w", path, err)
+		}
+	}
+	return config, nil
+}
+
+func (c *projectConfig) allOptions() []*configOptions {
+	all := []*configOptions{&c.configOptions}
+	for i := range c.Entrypoints {
+		all = append(all, &c.Entrypoints[i].configOptions)
+	}
+	return all
+}
+
+// resolve makes path relative to the config file's directory
+func (c *projectConfig) resolve(path string) string {
+	if path == "" || filepath.IsAbs(path) {
+		return path
+	}
+	return filepath.Join(filepath.Dir(c.path), path)
+}
+
+// entrypoint returns the entrypoint whose input is the file at absPath, if any
+func (c *projectConfig) entrypoint(absPath string) *configEntrypoint {
+	if c == nil {
+		return nil
+	}
+	for i := range c.Entrypoints {
+		if c.resolve(c.Entrypoints[i].Input) == absPath {
+			return &c.Entrypoints[i]
+		}
+	}
+	return nil
+}
+
+// buildJobs returns the entrypoints to compile. Those without an output are
+// written to outDir, or next to their input if it is empty, with a .css
+// extension; in outDir they keep their paths below the directory their inputs
+// have in common. Two entrypoints written to the same file are an error.
+func (c *projectConfig) buildJobs(outDir string) ([]*buildJob, error) {
+	base := ""
+	if outDir != "" {
+		var inputs []string
+		for _, entry := range c.Entrypoints {
+			if entry.Output == "" {
+				inputs = append(inputs, c.resolve(entry.Input))
+			}
+		}
+		if len(inputs) > 0 {
+			var err error
+			if base, err = commonDir(inputs); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	jobs := make([]*buildJob, 0, len(c.Entrypoints))
+	for _, entry := range c.Entrypoints {
+		input := c.resolve(entry.Input)
+		output := c.resolve(entry.Output)
+		if output == "" {
+			output = strings.TrimSuffix(input, filepath.Ext(input)) + ".css"
+			if outDir != "" {
+				rel, err := filepath.Rel(base, output)
+				if err != nil {
+					return nil, err
+				}
+				output = filepath.Join(outDir, rel)
+			}
+		}
+		jobs = append(jobs, &buildJob{input: input, output: output})
+	}
+	if err := checkOutputs(jobs); err != nil {
+		return nil, err
+	}
+	return jobs, nil
+}
+
+// apply sets the config's options for compiling the file at absPath on
+// options: the top-level ones, then those of the matching entrypoint
+func (c *projectConfig) apply(options *less_go.CompileOptions, absPath string) {
+	if c == nil {
+		return
+	}
+	c.configOptions.apply(options, c)
+	if entry := c.entrypoint(absPath); entry != nil {
+		entry.configOptions.apply(options, c)
+	}
+}
+
+func (o *configOptions) validate() error {
+	if _, ok := parseMath(o.Math); !ok && o.Math != "" {
+		return fmt.Errorf("invalid math %q, expected always, parens-division or parens", o.Math)
+	}
+	if _, ok := parseRewriteUrls(o.RewriteUrls); !ok && o.RewriteUrls != "" {
+		return fmt.Errorf("invalid rewriteUrls %q, expected off, local or all", o.RewriteUrls)
+	}
+	if _, ok := parseModernColors(o.ResolveModernColors); !ok && o.ResolveModernColors != "" {
+		return fmt.Errorf("invalid resolveModernColors %q, expected off or srgb-hex", o.ResolveModernColors)
+	}
+	return nil
+}
+
+// apply copies the options set in the file to options. Include paths and
+// plugins are added to those already set; variables are merged.
+func (o *configOptions) apply(options *less_go.CompileOptions, config *projectConfig) {
+	for _, path := range o.Paths {
+		options.Paths = append(options.Paths, config.resolve(path))
+	}
+	if o.Compress != nil {
+		options.Compress = *o.Compress
+	}
+	if o.StrictUnits != nil {
+		options.StrictUnits = *o.StrictUnits
+	}
+	if math, ok := parseMath(o.Math); ok {
+		options.Math = math
+	}
+	if rewriteUrls, ok := parseRewriteUrls(o.RewriteUrls); ok {
+		options.RewriteUrls = rewriteUrls
+	}
+	if modernColors, ok := parseModernColors(o.ResolveModernColors); ok {
+		options.ResolveModernColors = modernColors
+	}
+	if o.MinContrastRatio != nil {
+		options.MinContrastRatio = *o.MinContrastRatio
+	}
+	if o.Rootpath != nil {
+		options.Rootpath = *o.Rootpath
+	}
+	if o.UrlArgs != nil {
+		options.UrlArgs = *o.UrlArgs
+	}
+	if o.EnableJavaScriptPlugins != nil {
+		options.EnableJavaScriptPlugins = *o.EnableJavaScriptPlugins
+	}
+	if o.JavascriptEnabled != nil {
+		options.JavascriptEnabled = *o.JavascriptEnabled
+		// As with --js, inline JavaScript needs the Node.js runtime
+		options.EnableJavaScriptPlugins = options.EnableJavaScriptPlugins || *o.JavascriptEnabled
+	}
+	for _, plugin := range o.Plugins {
+		// Local plugin files are relative to the config file, npm modules are left as is
+		if strings.HasPrefix(plugin.Name, ".") {
+			plugin.Name = config.resolve(plugin.Name)
+		}
+		options.Plugins = append(options.Plugins, less_go.PluginSpec(plugin))
+	}
+	options.GlobalVars = mergeVars(options.GlobalVars, o.GlobalVars)
+	options.ModifyVars = mergeVars(options.ModifyVars, o.ModifyVars)
+
+	if o.SourceMap != nil {
+		options.SourceMap = *o.SourceMap
+	}
+	if sm := o.SourceMapOptions; sm != nil {
+		options.SourceMap = true
+		if options.SourceMapOptions == nil {
+			options.SourceMapOptions = &less_go.SourceMapOptions{OutputSourceFiles: true}
+		}
+		target := options.SourceMapOptions
+		if sm.SourceMapFilename != nil {
+			target.SourceMapFilename = config.resolve(*sm.SourceMapFilename)
+		}
+		setString(&target.SourceMapURL, sm.SourceMapURL)
+		setString(&target.SourceMapBasepath, sm.SourceMapBasepath)
+		setString(&target.SourceMapRootpath, sm.SourceMapRootpath)
+		setString(&target.SourceMapOutputFilename, sm.SourceMapOutputFilename)
+		setBool(&target.OutputSourceFiles, sm.OutputSourceFiles)
+		setBool(&target.SourceMapFileInline, sm.SourceMapFileInline)
+		setBool(&target.DisableSourcemapAnnotation, sm.DisableSourcemapAnnotation)
+	}
+}
+
+// mergeVars returns vars with overrides added, copying so the config is not modified
+func mergeVars(vars, overrides map[string]any) map[string]any {
+	if len(overrides) == 0 {
+		return vars
+	}
+	merged := make(map[string]any, len(vars)+len(overrides))
+	for k, v := range vars {
+		merged[k] = v
+	}
+	for k, v := range overrides {
+		merged[k] = v
+	}
+	return merged
+}
+
+func setString(target *string, value *string) {
+	if value != nil {
+		*target = *value
+	}
+}
+
+func setBool(target *bool, value *bool) {
+	if value != nil {
+		*target = *value
+	}
+}
+
+// parseMath parses a math mode as accepted by --math
+func parseMath(mode string) (less_go.MathType, bool) {
+	switch strings.ToLower(mode) {
+	case "always":
+		return less_go.Math.Always, true
+	case "parens-division", "parens_division":
+		return less_go.Math.ParensDivision, true
+	case "parens", "strict":
+		return less_go.Math.Parens, true
+	}
+	return 0, false
+}
+
+// parseRewriteUrls parses a URL rewriting mode as accepted by --rewrite-urls
+func parseRewriteUrls(mode string) (less_go.RewriteUrlsType, bool) {
+	switch strings.ToLower(mode) {
+	case "all":
+		return less_go.RewriteUrls.All, true
+	case "local":
+		return less_go.RewriteUrls.Local, true
+	case "off":
+		return less_go.RewriteUrls.Off, true
+	}
+	return 0, false
+}
+
+// parseModernColors parses a target for modern colors as accepted by
+// --resolve-modern-colors
+func parseModernColors(target string) (less_go.ModernColorsType, bool) {
+	switch strings.ToLower(target) {
+	case "srgb-hex":
+		return less_go.ModernColors.SRGBHex, true
+	case "off":
+		return less_go.ModernColors.Off, true
+	}
+	return 0, false
+}
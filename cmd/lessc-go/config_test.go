@@ -0,0 +1,244 @@
+package main
+
+import (
+	"flag"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	less_go "github.com/toakleaf/less.go/less"
+)
+
+// chdir changes the working directory for the rest of the test
+func chdir(t *testing.T, dir string) {
+	t.Helper()
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chdir(dir); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.Chdir(wd) })
+}
+
+func TestReadConfig(t *testing.T) {
+	tests := []struct {
+		name        string
+		file        string
+		content     string
+		entrypoints []string
+		math        string
+		err         string
+	}{
+		{
+			name:        "lessgo.json",
+			file:        "lessgo.json",
+			content:     `{"math": "parens", "entrypoints": ["src/site.less", {"input": "src/admin.less", "output": "dist/admin.css"}]}`,
+			entrypoints: []string{"src/site.less", "src/admin.less"},
+			math:        "parens",
+		},
+		{
+			name:        "package.json",
+			file:        "package.json",
+			content:     `{"name": "site", "lessgo": {"math": "always", "entrypoints": ["main.less"]}}`,
+			entrypoints: []string{"main.less"},
+			math:        "always",
+		},
+		{
+			name:    "package.json without lessgo",
+			file:    "package.json",
+			content: `{"name": "site"}`,
+		},
+		{
+			name:    "unknown key",
+			file:    "lessgo.json",
+			content: `{"compres": true}`,
+			err:     `unknown field "compres"`,
+		},
+		{
+			name:    "invalid math",
+			file:    "lessgo.json",
+			content: `{"entrypoints": [{"input": "a.less", "math": "sometimes"}]}`,
+			err:     `invalid math "sometimes"`,
+		},
+		{
+			name:    "entrypoint without input",
+			file:    "lessgo.json",
+			content: `{"entrypoints": [{"output": "a.css"}]}`,
+			err:     `entrypoint without "input"`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), tt.file)
+			if err := os.WriteFile(path, []byte(tt.content), 0644); err != nil {
+				t.Fatal(err)
+			}
+			config, err := readConfig(path)
+			if tt.err != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.err) {
+					t.Fatalf("expected an error with %q, got %v", tt.err, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if tt.entrypoints == nil {
+				if config != nil {
+					t.Fatalf("expected no config, got %+v", config)
+				}
+				return
+			}
+			var inputs []string
+			for _, entry := range config.Entrypoints {
+				inputs = append(inputs, entry.Input)
+			}
+			if !reflect.DeepEqual(inputs, tt.entrypoints) {
+				t.Errorf("expected entrypoints %v, got %v", tt.entrypoints, inputs)
+			}
+			if config.Math != tt.math {
+				t.Errorf("expected math %q, got %q", tt.math, config.Math)
+			}
+			if config.path != path {
+				t.Errorf("expected path %q, got %q", path, config.path)
+			}
+		})
+	}
+}
+
+func TestLoadConfig(t *testing.T) {
+	dir, err := filepath.EvalSymlinks(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	writeFiles(t, dir, map[string]string{
+		"package.json":           `{"lessgo": {"entrypoints": ["site.less"]}}`,
+		"app/package.json":       `{"name": "app"}`,
+		"app/src/.keep":          "",
+		"theme/lessgo.json":      `{"entrypoints": ["theme.less"]}`,
+		"theme/package.json":     `{"lessgo": {"entrypoints": ["ignored.less"]}}`,
+		"theme/src/styles/.keep": "",
+	})
+
+	tests := []struct {
+		wd   string
+		want string
+	}{
+		// A package.json without "lessgo" is skipped
+		{"app/src", "package.json"},
+		// lessgo.json comes before the package.json next to it
+		{"theme/src/styles", "theme/lessgo.json"},
+	}
+	for _, tt := range tests {
+		chdir(t, filepath.Join(dir, filepath.FromSlash(tt.wd)))
+		config, err := loadConfig("")
+		if err != nil {
+			t.Fatalf("%s: %v", tt.wd, err)
+		}
+		if want := filepath.Join(dir, filepath.FromSlash(tt.want)); config == nil || config.path != want {
+			t.Errorf("%s: expected the config at %s, got %+v", tt.wd, want, config)
+		}
+	}
+}
+
+func TestCompileOptionsPrecedence(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{
+		"lessgo.json": `{
+  "paths": ["lib"],
+  "compress": true,
+  "math": "parens",
+  "rootpath": "/assets/",
+  "globalVars": {"brand": "red", "accent": "blue"},
+  "plugins": ["./plugins/config.js"],
+  "entrypoints": [
+    {"input": "src/admin.less", "strictUnits": true, "math": "always", "globalVars": {"accent": "green"}},
+    "src/site.less"
+  ]
+}`,
+	})
+	config, err := readConfig(filepath.Join(dir, "lessgo.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	admin := filepath.Join(dir, "src", "admin.less")
+	site := filepath.Join(dir, "src", "site.less")
+
+	tests := []struct {
+		name  string
+		args  []string
+		file  string
+		check func(t *testing.T, options *less_go.CompileOptions)
+	}{
+		{
+			name: "config",
+			file: site,
+			check: func(t *testing.T, options *less_go.CompileOptions) {
+				if !options.Compress || options.StrictUnits || options.Math != less_go.Math.Parens || options.Rootpath != "/assets/" {
+					t.Errorf("expected the top-level options, got %+v", options)
+				}
+				want := []string{"base", filepath.Join(dir, "lib")}
+				if !reflect.DeepEqual(options.Paths, want) {
+					t.Errorf("expected paths %v, got %v", want, options.Paths)
+				}
+			},
+		},
+		{
+			name: "entrypoint over config",
+			file: admin,
+			check: func(t *testing.T, options *less_go.CompileOptions) {
+				if !options.StrictUnits || options.Math != less_go.Math.Always {
+					t.Errorf("expected the entrypoint's options, got %+v", options)
+				}
+				want := map[string]any{"brand": "red", "accent": "green"}
+				if !reflect.DeepEqual(options.GlobalVars, want) {
+					t.Errorf("expected global vars %v, got %v", want, options.GlobalVars)
+				}
+			},
+		},
+		{
+			name: "flags over entrypoint",
+			args: []string{"--compress=false", "--math=parens-division", "--rootpath=/cdn/", "--global-var=accent=black", "--include-path=flags", "--plugin=cli-plugin"},
+			file: admin,
+			check: func(t *testing.T, options *less_go.CompileOptions) {
+				if options.Compress || options.Math != less_go.Math.ParensDivision || options.Rootpath != "/cdn/" {
+					t.Errorf("expected the flags' options, got %+v", options)
+				}
+				// Flags not given keep the config's value
+				if !options.StrictUnits {
+					t.Error("expected strictUnits from the entrypoint")
+				}
+				want := map[string]any{"brand": "red", "accent": "black"}
+				if !reflect.DeepEqual(options.GlobalVars, want) {
+					t.Errorf("expected global vars %v, got %v", want, options.GlobalVars)
+				}
+				paths := []string{"base", "flags", filepath.Join(dir, "lib")}
+				if !reflect.DeepEqual(options.Paths, paths) {
+					t.Errorf("expected paths %v, got %v", paths, options.Paths)
+				}
+				var plugins []string
+				for _, plugin := range options.Plugins {
+					plugins = append(plugins, plugin.Name)
+				}
+				if want := []string{filepath.Join(dir, "plugins", "config.js"), "cli-plugin"}; !reflect.DeepEqual(plugins, want) {
+					t.Errorf("expected plugins %v, got %v", want, plugins)
+				}
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fset := flag.NewFlagSet("test", flag.ContinueOnError)
+			flags := newCompileFlags(fset)
+			if err := fset.Parse(tt.args); err != nil {
+				t.Fatal(err)
+			}
+			flags.parsed(fset)
+			tt.check(t, flags.compileOptions(config, tt.file, []string{"base"}, ""))
+		})
+	}
+}
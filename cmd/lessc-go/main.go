@@ -7,6 +7,7 @@ import (
 	"io"
 	"os"
 	"path/filepath"
+	"runtime"
 	"strings"
 
 	less_go "github.com/toakleaf/less.go/less"
@@ -52,6 +53,15 @@ func (kv *keyValueFlag) Set(value string) error {
 	return nil
 }
 
+// vars returns the variables as compile option values
+func (kv keyValueFlag) vars() map[string]any {
+	vars := make(map[string]any, len(kv))
+	for k, v := range kv {
+		vars[k] = v
+	}
+	return vars
+}
+
 // pluginSliceFlag for --plugin (supports name or name=options format)
 type pluginSliceFlag []less_go.PluginSpec
 
@@ -80,59 +90,238 @@ func (p *pluginSliceFlag) Set(value string) error {
 	return nil
 }
 
+// compileFlags holds the flags that control compilation, shared by the default
+// command and the build command
+type compileFlags struct {
+	compress        bool
+	sourceMap       bool
+	sourceMapInline bool
+	strictUnits     bool
+	jsEnabled       bool
+	silent          bool
+	noConfig        bool
+	collectErrors   bool
+	configPath      string
+	format          string
+	errorFormat     string
+	mathMode        string
+	rewriteUrls     string
+	modernColors    string
+	minContrast     float64
+	rootpath        string
+	urlArgs         string
+	includePaths    stringSliceFlag
+	plugins         pluginSliceFlag
+	globalVars      keyValueFlag
+	modifyVars      keyValueFlag
+
+	// set holds the flags given on the command line, by long name; only
+	// these override the project config
+	set map[string]bool
+}
+
+// newCompileFlags defines the compilation flags on fs
+func newCompileFlags(fs *flag.FlagSet) *compileFlags {
+	f := &compileFlags{
+		globalVars: make(keyValueFlag),
+		modifyVars: make(keyValueFlag),
+		set:        make(map[string]bool),
+	}
+
+	// Boolean flags
+	fs.BoolVar(&f.compress, "compress", false, "Compress output CSS")
+	fs.BoolVar(&f.compress, "x", false, "Compress output CSS (shorthand)")
+	fs.BoolVar(&f.sourceMap, "source-map", false, "Generate source map")
+	fs.BoolVar(&f.sourceMapInline, "source-map-inline", false, "Inline source map in CSS output")
+	fs.BoolVar(&f.strictUnits, "strict-units", false, "Enable strict unit checking")
+	fs.BoolVar(&f.jsEnabled, "js", false, "Enable inline JavaScript evaluation")
+	fs.BoolVar(&f.silent, "silent", false, "Suppress output messages")
+	fs.BoolVar(&f.silent, "s", false, "Suppress output messages (shorthand)")
+	fs.BoolVar(&f.noConfig, "no-config", false, "Do not load lessgo.json or package.json")
+	fs.BoolVar(&f.collectErrors, "collect-errors", false, "Report all errors instead of stopping at the first")
+
+	// String flags
+	fs.StringVar(&f.configPath, "config", "", "Project config file (default: lessgo.json or package.json in the working directory or above)")
+	fs.StringVar(&f.format, "format", "text", "Report errors and warnings as text or json")
+	fs.StringVar(&f.errorFormat, "error-format", "frame", "Text error format: frame (code frame) or gcc (file:line:col: message)")
+	fs.StringVar(&f.mathMode, "math", "parens-division", "Math mode: always, parens-division, parens, strict")
+	fs.StringVar(&f.rewriteUrls, "rewrite-urls", "", "URL rewriting: off, local, all")
+	fs.StringVar(&f.rootpath, "rootpath", "", "Set rootpath for URL rewriting")
+	fs.StringVar(&f.urlArgs, "url-args", "", "Query string to append to URLs")
+	fs.StringVar(&f.modernColors, "resolve-modern-colors", "", "Add fallbacks for color-mix() and relative colors: off, srgb-hex")
+	fs.Float64Var(&f.minContrast, "min-contrast-ratio", 0, "Warn about rulesets whose color and background have a lower WCAG contrast ratio, e.g. 4.5")
+
+	// Multi-value flags
+	fs.Var(&f.includePaths, "include-path", "Include path for @import (can be specified multiple times, or use OS path separator)")
+	fs.Var(&f.includePaths, "I", "Include path (shorthand)")
+	fs.Var(&f.globalVars, "global-var", "Define a global variable (format: name=value)")
+	fs.Var(&f.modifyVars, "modify-var", "Modify a variable (format: name=value)")
+	fs.Var(&f.plugins, "plugin", "Load a plugin (format: name or name=options, can be repeated)")
+
+	return f
+}
+
+// parsed records which flags were given once fs has been parsed
+func (f *compileFlags) parsed(fs *flag.FlagSet) {
+	fs.Visit(func(fl *flag.Flag) {
+		switch fl.Name {
+		case "x":
+			f.set["compress"] = true
+		case "s":
+			f.set["silent"] = true
+		default:
+			f.set[fl.Name] = true
+		}
+	})
+}
+
+// reporter returns the reporter selected with --format and --error-format.
+// With --format=json, the other messages on stderr are turned off so that it
+// only holds the JSON reports.
+func (f *compileFlags) reporter() (*reporter, error) {
+	r, err := newReporter(f.format, f.errorFormat)
+	if err != nil {
+		return nil, err
+	}
+	if r.json {
+		f.silent = true
+	}
+	return r, nil
+}
+
+// loadConfig returns the project config named by --config or found from the
+// working directory, or nil if there is none or --no-config was given
+func (f *compileFlags) loadConfig() (*projectConfig, error) {
+	if f.noConfig {
+		return nil, nil
+	}
+	return loadConfig(f.configPath)
+}
+
+// compileOptions builds the options for compiling filename into outputFile:
+// the project config's, overridden by those of the matching entrypoint and
+// then by the flags given. Imports are resolved from baseDirs, then the
+// include paths of the flags and of the config.
+func (f *compileFlags) compileOptions(config *projectConfig, filename string, baseDirs []string, outputFile string) *less_go.CompileOptions {
+	options := &less_go.CompileOptions{
+		Filename: filename,
+		Paths:    append(append([]string{}, baseDirs...), f.includePaths...),
+		Math:     less_go.Math.ParensDivision,
+	}
+	config.apply(options, filename)
+
+	if f.set["compress"] {
+		options.Compress = f.compress
+	}
+	if f.set["strict-units"] {
+		options.StrictUnits = f.strictUnits
+	}
+	options.CollectErrors = f.collectErrors
+
+	// Enable JavaScript if requested
+	if f.jsEnabled {
+		options.EnableJavaScriptPlugins = true
+		options.JavascriptEnabled = true
+	}
+
+	// Plugins from the command line load after those of the config
+	// (auto-enables JavaScript plugin support)
+	options.Plugins = append(options.Plugins, f.plugins...)
+
+	if math, ok := parseMath(f.mathMode); ok && f.set["math"] {
+		options.Math = math
+	}
+	if rewriteUrls, ok := parseRewriteUrls(f.rewriteUrls); ok {
+		options.RewriteUrls = rewriteUrls
+	}
+	if f.set["rootpath"] {
+		options.Rootpath = f.rootpath
+	}
+	if f.set["url-args"] {
+		options.UrlArgs = f.urlArgs
+	}
+	if modernColors, ok := parseModernColors(f.modernColors); ok {
+		options.ResolveModernColors = modernColors
+	}
+	if f.set["min-contrast-ratio"] {
+		options.MinContrastRatio = f.minContrast
+	}
+
+	// Variables from the command line win over those of the config
+	options.GlobalVars = mergeVars(options.GlobalVars, f.globalVars.vars())
+	options.ModifyVars = mergeVars(options.ModifyVars, f.modifyVars.vars())
+
+	if f.sourceMap || f.sourceMapInline {
+		options.SourceMap = true
+	}
+	if options.SourceMap {
+		if options.SourceMapOptions == nil {
+			options.SourceMapOptions = &less_go.SourceMapOptions{
+				OutputSourceFiles: true, // Include source content in the source map
+			}
+		}
+		if f.sourceMapInline {
+			options.SourceMapOptions.SourceMapFileInline = true
+		}
+		// Set source map filename based on output file
+		if outputFile != "" {
+			sm := options.SourceMapOptions
+			if sm.SourceMapFilename == "" {
+				sm.SourceMapFilename = outputFile + ".map"
+			}
+			if sm.SourceMapURL == "" {
+				// Use relative path for the sourceMappingURL
+				sm.SourceMapURL = filepath.Base(sm.SourceMapFilename)
+			}
+			if sm.SourceMapOutputFilename == "" {
+				sm.SourceMapOutputFilename = outputFile
+			}
+		}
+	}
+
+	return options
+}
+
+// output returns how the results of compiling with options are written
+func (f *compileFlags) output(options *less_go.CompileOptions) outputOptions {
+	output := outputOptions{silent: f.silent}
+	if options.SourceMap && options.SourceMapOptions != nil {
+		output.sourceMapInline = options.SourceMapOptions.SourceMapFileInline
+		output.sourceMap = !output.sourceMapInline
+		output.sourceMapFile = options.SourceMapOptions.SourceMapFilename
+	}
+	return output
+}
+
 func main() {
+	if len(os.Args) > 1 && os.Args[1] == "build" {
+		os.Exit(runBuild(os.Args[2:]))
+	}
+	if len(os.Args) > 1 && os.Args[1] == "vars" {
+		os.Exit(runVars(os.Args[2:]))
+	}
+
 	// Define flags
 	var (
-		showVersion     bool
-		showHelp        bool
-		compress        bool
-		sourceMap       bool
-		sourceMapInline bool
-		strictUnits     bool
-		jsEnabled       bool
-		silent          bool
-		mathMode        string
-		rewriteUrls     string
-		rootpath        string
-		urlArgs         string
-		includePaths    stringSliceFlag
-		plugins         pluginSliceFlag
-		globalVars      = make(keyValueFlag)
-		modifyVars      = make(keyValueFlag)
+		showVersion bool
+		showHelp    bool
+		watch       bool
 	)
 
 	// Custom usage message
 	flag.Usage = printUsage
 
-	// Boolean flags
 	flag.BoolVar(&showVersion, "v", false, "Print version number and exit")
 	flag.BoolVar(&showVersion, "version", false, "Print version number and exit")
 	flag.BoolVar(&showHelp, "h", false, "Print help and exit")
 	flag.BoolVar(&showHelp, "help", false, "Print help and exit")
-	flag.BoolVar(&compress, "compress", false, "Compress output CSS")
-	flag.BoolVar(&compress, "x", false, "Compress output CSS (shorthand)")
-	flag.BoolVar(&sourceMap, "source-map", false, "Generate source map")
-	flag.BoolVar(&sourceMapInline, "source-map-inline", false, "Inline source map in CSS output")
-	flag.BoolVar(&strictUnits, "strict-units", false, "Enable strict unit checking")
-	flag.BoolVar(&jsEnabled, "js", false, "Enable inline JavaScript evaluation")
-	flag.BoolVar(&silent, "silent", false, "Suppress output messages")
-	flag.BoolVar(&silent, "s", false, "Suppress output messages (shorthand)")
-
-	// String flags
-	flag.StringVar(&mathMode, "math", "parens-division", "Math mode: always, parens-division, parens, strict")
-	flag.StringVar(&rewriteUrls, "rewrite-urls", "", "URL rewriting: off, local, all")
-	flag.StringVar(&rootpath, "rootpath", "", "Set rootpath for URL rewriting")
-	flag.StringVar(&urlArgs, "url-args", "", "Query string to append to URLs")
-
-	// Multi-value flags
-	flag.Var(&includePaths, "include-path", "Include path for @import (can be specified multiple times, or use OS path separator)")
-	flag.Var(&includePaths, "I", "Include path (shorthand)")
-	flag.Var(&globalVars, "global-var", "Define a global variable (format: name=value)")
-	flag.Var(&modifyVars, "modify-var", "Modify a variable (format: name=value)")
-	flag.Var(&plugins, "plugin", "Load a plugin (format: name or name=options, can be repeated)")
+	flag.BoolVar(&watch, "watch", false, "Recompile when the input or any file it depends on changes")
+	flag.BoolVar(&watch, "w", false, "Watch for changes (shorthand)")
+	flags := newCompileFlags(flag.CommandLine)
 
 	// Parse flags
 	flag.Parse()
+	flags.parsed(flag.CommandLine)
 
 	// Handle version and help
 	if showVersion {
@@ -145,9 +334,33 @@ func main() {
 		os.Exit(0)
 	}
 
+	report, err := flags.reporter()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	config, err := flags.loadConfig()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error loading config: %v\n", err)
+		os.Exit(1)
+	}
+
 	// Get positional arguments (input and optional output file)
 	args := flag.Args()
 	if len(args) < 1 {
+		// Without arguments, compile the entrypoints of the project config
+		if config != nil && len(config.Entrypoints) > 0 {
+			jobs, err := config.buildJobs("")
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+				os.Exit(1)
+			}
+			if watch {
+				os.Exit(watchAndCompile(watchTargets(jobs, flags, config), flags.silent, report))
+			}
+			os.Exit(buildAll(jobs, 0, runtime.NumCPU(), flags, config, report))
+		}
 		fmt.Fprintln(os.Stderr, "Error: No input file specified")
 		printUsage()
 		os.Exit(1)
@@ -159,10 +372,11 @@ func main() {
 		outputFile = args[1]
 	}
 
+	var includePaths []string
+
 	// Read input (from stdin or file)
 	var inputContent []byte
 	var absPath string
-	var err error
 
 	if inputFile == "-" {
 		// Read from stdin
@@ -175,7 +389,7 @@ func main() {
 
 		// For stdin, use current directory as base path for imports
 		cwd, _ := os.Getwd()
-		if len(includePaths) == 0 {
+		if len(flags.includePaths) == 0 {
 			includePaths = append(includePaths, cwd)
 		}
 	} else {
@@ -185,7 +399,7 @@ func main() {
 			inputContent = []byte(inputFile)
 			absPath = "inline"
 			cwd, _ := os.Getwd()
-			if len(includePaths) == 0 {
+			if len(flags.includePaths) == 0 {
 				includePaths = append(includePaths, cwd)
 			}
 		} else {
@@ -203,119 +417,76 @@ func main() {
 			}
 
 			// Add file's directory to include paths
-			fileDir := filepath.Dir(absPath)
-			includePaths = append([]string{fileDir}, includePaths...)
-		}
-	}
+			includePaths = []string{filepath.Dir(absPath)}
 
-	// Build compile options
-	options := &less_go.CompileOptions{
-		Filename:    absPath,
-		Paths:       includePaths,
-		Compress:    compress,
-		StrictUnits: strictUnits,
-	}
-
-	// Enable JavaScript if requested
-	if jsEnabled {
-		options.EnableJavaScriptPlugins = true
-		options.JavascriptEnabled = true
-	}
-
-	// Set plugins (auto-enables JavaScript plugin support)
-	if len(plugins) > 0 {
-		options.Plugins = plugins
-	}
-
-	// Set math mode
-	switch strings.ToLower(mathMode) {
-	case "always":
-		options.Math = less_go.Math.Always
-	case "parens-division", "parens_division":
-		options.Math = less_go.Math.ParensDivision
-	case "parens", "strict":
-		options.Math = less_go.Math.Parens
-	}
-
-	// Set rewrite URLs mode
-	switch strings.ToLower(rewriteUrls) {
-	case "all":
-		options.RewriteUrls = less_go.RewriteUrls.All
-	case "local":
-		options.RewriteUrls = less_go.RewriteUrls.Local
-	case "off":
-		options.RewriteUrls = less_go.RewriteUrls.Off
-	}
-
-	if rootpath != "" {
-		options.Rootpath = rootpath
-	}
-
-	if urlArgs != "" {
-		options.UrlArgs = urlArgs
-	}
-
-	// Handle global vars
-	if len(globalVars) > 0 {
-		options.GlobalVars = make(map[string]any)
-		for k, v := range globalVars {
-			options.GlobalVars[k] = v
+			// An entrypoint of the config is written to its output by default
+			if entry := config.entrypoint(absPath); entry != nil && outputFile == "" {
+				outputFile = config.resolve(entry.Output)
+			}
 		}
 	}
 
-	// Handle modify vars
-	if len(modifyVars) > 0 {
-		options.ModifyVars = make(map[string]any)
-		for k, v := range modifyVars {
-			options.ModifyVars[k] = v
-		}
-	}
+	// Build compile options
+	options := flags.compileOptions(config, absPath, includePaths, outputFile)
+	output := flags.output(options)
 
-	// Handle source map options
-	if sourceMap || sourceMapInline {
-		options.SourceMap = true
-		options.SourceMapOptions = &less_go.SourceMapOptions{
-			SourceMapFileInline:   sourceMapInline,
-			OutputSourceFiles:     true, // Include source content in the source map
-		}
-		// Set source map filename based on output file
-		if outputFile != "" {
-			// Use relative path for the sourceMappingURL
-			options.SourceMapOptions.SourceMapURL = filepath.Base(outputFile) + ".map"
-			options.SourceMapOptions.SourceMapFilename = outputFile + ".map"
-			options.SourceMapOptions.SourceMapOutputFilename = outputFile
+	if watch {
+		if inputFile == "-" || absPath == "inline" {
+			fmt.Fprintln(os.Stderr, "Error: --watch requires an input file")
+			os.Exit(1)
 		}
+		os.Exit(watchAndCompile([]watchTarget{{inputFile, outputFile, options, output}}, flags.silent, report))
 	}
 
 	// Compile the LESS content
+	warnings := collectWarnings(options)
 	result, err := less_go.Compile(string(inputContent), options)
+	report.report(inputFile, outputFile, warnings(), err, flags.silent)
 	if err != nil {
-		fmt.Fprintf(os.Stderr, "Compilation error: %v\n", err)
 		os.Exit(1)
 	}
 
+	if err := output.write(result, inputFile, outputFile); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+// outputOptions controls how compiled CSS and source maps are written
+type outputOptions struct {
+	sourceMap       bool
+	sourceMapInline bool
+	sourceMapFile   string
+	silent          bool
+}
+
+// write writes the CSS to outputFile, or stdout if it is empty, and the
+// external source map next to it
+func (o outputOptions) write(result *less_go.CompileResult, inputFile, outputFile string) error {
 	css := result.CSS
 
 	// Handle source map output
 	var sourceMapContent string
-	if sourceMap || sourceMapInline {
+	if o.sourceMap || o.sourceMapInline {
 		sourceMapContent = result.Map
 
-		if sourceMapInline && sourceMapContent != "" {
+		if o.sourceMapInline && sourceMapContent != "" {
 			// For inline source maps, the library should have added them
 			// If not present, we skip since source map generation needs work
-		} else if sourceMap && outputFile != "" && sourceMapContent != "" {
+		} else if o.sourceMap && outputFile != "" && sourceMapContent != "" {
 			// Write external source map file
-			mapFile := outputFile + ".map"
+			mapFile := o.sourceMapFile
+			if mapFile == "" {
+				mapFile = outputFile + ".map"
+			}
 			if err := os.WriteFile(mapFile, []byte(sourceMapContent), 0644); err != nil {
-				fmt.Fprintf(os.Stderr, "Error writing source map file %s: %v\n", mapFile, err)
-				os.Exit(1)
+				return fmt.Errorf("writing source map file %s: %w", mapFile, err)
 			}
 
 			// Note: The library already appends the sourceMappingURL comment,
 			// so we don't need to add it here
 
-			if !silent {
+			if !o.silent {
 				fmt.Fprintf(os.Stderr, "Source map written to %s\n", mapFile)
 			}
 		}
@@ -323,12 +494,10 @@ func main() {
 
 	// Output result
 	if outputFile != "" {
-		err = os.WriteFile(outputFile, []byte(css), 0644)
-		if err != nil {
-			fmt.Fprintf(os.Stderr, "Error writing output file %s: %v\n", outputFile, err)
-			os.Exit(1)
+		if err := os.WriteFile(outputFile, []byte(css), 0644); err != nil {
+			return fmt.Errorf("writing output file %s: %w", outputFile, err)
 		}
-		if !silent {
+		if !o.silent {
 			fmt.Fprintf(os.Stderr, "Compiled %s -> %s\n", inputFile, outputFile)
 		}
 	} else {
@@ -337,11 +506,15 @@ func main() {
 		writer.WriteString(css)
 		writer.Flush()
 	}
+	return nil
 }
 
 func printUsage() {
 	fmt.Printf(`lessc-go %s (Less Compiler - Go Port)
 Usage: lessc-go [options] <input.less|-|"less code"> [output.css]
+       lessc-go [options]                (compile the entrypoints of lessgo.json)
+       lessc-go build [options] <pattern|dir>... --out-dir DIR
+       lessc-go vars [options] <input.less|-> [output.json]
 
 Input:
   <input.less>       Compile a LESS file
@@ -354,6 +527,9 @@ Examples:
   lessc-go --compress style.less out.css   # Minified output
   cat style.less | lessc-go - out.css      # Read from stdin
   echo "@color: red; .a { color: @color; }" | lessc-go -
+  lessc-go --watch style.less style.css    # Recompile on changes
+  lessc-go build 'src/**/*.less' --out-dir dist   # Compile a tree (see build --help)
+  lessc-go vars theme.less tokens.json     # Export evaluated variables (see vars --help)
 
 Options:
   -h, --help               Print this help message
@@ -364,6 +540,15 @@ Compilation:
   --strict-units           Enable strict unit checking in math operations
   --math=MODE              Math mode: always, parens-division (default), parens
   --js                     Enable inline JavaScript evaluation
+  --resolve-modern-colors=TARGET
+                           Add a fallback before each declaration that uses
+                           color-mix() or relative colors, with the colors
+                           computed when constant: srgb-hex, or off (default)
+  --min-contrast-ratio=RATIO
+                           Warn about each ruleset whose color has a lower
+                           WCAG contrast ratio against its background, e.g.
+                           4.5 for AA; @accessibility AA in a stylesheet does
+                           the same
 
 Import Paths:
   -I, --include-path=PATH  Add path for @import resolution (repeatable)
@@ -394,7 +579,37 @@ Plugins:
                              --plugin=autoprefix="browsers: last 2 versions"
 
 Output Control:
-  -s, --silent             Suppress informational messages
+  -s, --silent             Suppress informational messages and warnings
+  --format=FORMAT          How errors and warnings are reported on stderr:
+                           text (default) or json, one object per compiled
+                           file with the error's type, message, filename,
+                           line, column, extract and call site, and the
+                           warnings. json turns off the other messages.
+  --error-format=FORMAT    Text errors as a code frame with a caret under the
+                           column (default, colored on a terminal unless
+                           NO_COLOR is set), or gcc: file:line:col: message
+  --collect-errors         Report all syntax and evaluation errors instead of
+                           stopping at the first; with --format=json they are
+                           listed in "errors"
+
+Project Config:
+  --config=PATH            Read options from PATH instead of looking for
+                           lessgo.json, or a "lessgo" key in package.json, in
+                           the working directory and its parents
+  --no-config              Ignore the project config
+                           The config holds compile options (paths, math,
+                           rewriteUrls, globalVars, modifyVars, plugins,
+                           sourceMap, sourceMapOptions, ...) and entrypoints,
+                           each of which may override them. Flags given on the
+                           command line override the config; include paths
+                           and plugins are added, variables merged.
+
+Watch Mode:
+  -w, --watch              Compile, then recompile whenever the input or any
+                           file it depends on changes (imports, data-uri
+                           assets, plugins). Errors are reported and watching
+                           continues; JavaScript plugins reuse one Node.js
+                           process. Stop with Ctrl+C.
 
 `, version)
 }
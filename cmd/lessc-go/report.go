@@ -0,0 +1,346 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+
+	less_go "github.com/toakleaf/less.go/less"
+)
+
+// ANSI escape sequences of the text report
+const (
+	ansiReset  = "\x1b[0m"
+	ansiBold   = "\x1b[1m"
+	ansiRed    = "\x1b[31m"
+	ansiYellow = "\x1b[33m"
+	ansiGrey   = "\x1b[90m"
+)
+
+// reporter prints the errors and warnings of compilations on stderr, as
+// chosen with --format and --error-format. It is safe for concurrent use.
+type reporter struct {
+	json  bool // one JSON object per compilation
+	gcc   bool // file:line:col: message, one line per error or warning
+	color bool
+	out   io.Writer
+	mu    sync.Mutex
+}
+
+// reportError is the JSON form of a compilation error. Columns are 1-based,
+// as in warnings.
+type reportError struct {
+	Type        string               `json:"type"`
+	Message     string               `json:"message"`
+	Filename    string               `json:"filename,omitempty"`
+	Line        int                  `json:"line,omitempty"`
+	Column      int                  `json:"column,omitempty"`
+	Index       *int                 `json:"index,omitempty"`
+	Extract     []string             `json:"extract,omitempty"`
+	CallLine    int                  `json:"callLine,omitempty"`
+	CallExtract string               `json:"callExtract,omitempty"`
+	Stack       string               `json:"stack,omitempty"`
+	Suggestions []string             `json:"suggestions,omitempty"`
+	Candidates  []string             `json:"candidates,omitempty"`
+	Frames      []less_go.ErrorFrame `json:"frames,omitempty"`
+}
+
+// compileReport is the JSON object printed for each compilation with --format=json
+type compileReport struct {
+	Input    string               `json:"input"`
+	Output   string               `json:"output,omitempty"`
+	Success  bool                 `json:"success"`
+	Error    *reportError         `json:"error,omitempty"`
+	Errors   []*reportError       `json:"errors,omitempty"` // all of them with --collect-errors
+	Warnings []less_go.Diagnostic `json:"warnings"`
+}
+
+// newReporter returns a reporter for the given --format and --error-format
+func newReporter(format, errorFormat string) (*reporter, error) {
+	r := &reporter{out: os.Stderr}
+	switch format {
+	case "", "text":
+	case "json":
+		r.json = true
+	default:
+		return nil, fmt.Errorf("invalid --format %q, expected text or json", format)
+	}
+	switch errorFormat {
+	case "", "frame":
+	case "gcc":
+		r.gcc = true
+	default:
+		return nil, fmt.Errorf("invalid --error-format %q, expected frame or gcc", errorFormat)
+	}
+	r.color = !r.json && !r.gcc && os.Getenv("NO_COLOR") == "" && isTerminal(os.Stderr)
+	return r, nil
+}
+
+// isTerminal reports whether f is a terminal rather than a file or pipe
+func isTerminal(f *os.File) bool {
+	info, err := f.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
+
+// report prints the warnings of compiling input into output and its error, if
+// any. In text mode warnings are only printed unless silent; the JSON report
+// is always printed.
+func (r *reporter) report(input, output string, warnings []less_go.Diagnostic, err error, silent bool) {
+	r.write(r.format(input, output, warnings, err, silent))
+}
+
+// write prints a message formatted by format, in one piece so that the reports
+// of parallel compilations do not interleave
+func (r *reporter) write(message string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	io.WriteString(r.out, message)
+}
+
+// format returns what report prints
+func (r *reporter) format(input, output string, warnings []less_go.Diagnostic, err error, silent bool) string {
+	if r.json {
+		rep := compileReport{Input: input, Output: output, Success: err == nil, Warnings: warnings}
+		if rep.Warnings == nil {
+			rep.Warnings = []less_go.Diagnostic{}
+		}
+		if err != nil {
+			rep.Error = newReportError(err)
+		}
+		var multi *less_go.MultiError
+		if errors.As(err, &multi) {
+			for _, e := range multi.Errors {
+				rep.Errors = append(rep.Errors, newReportError(e))
+			}
+		}
+		data, _ := json.Marshal(rep)
+		return string(data) + "\n"
+	}
+
+	var b strings.Builder
+	if !silent {
+		for _, warning := range warnings {
+			if r.color {
+				b.WriteString(strings.Replace(warning.String(), "warning:", ansiYellow+"warning:"+ansiReset, 1) + "\n")
+			} else {
+				b.WriteString(warning.String() + "\n")
+			}
+		}
+	}
+	if err == nil {
+		return b.String()
+	}
+
+	for _, err := range splitErrors(err) {
+		var lessErr *less_go.LessError
+		switch {
+		case r.gcc:
+			b.WriteString(gccError(input, err) + "\n")
+		case errors.As(err, &lessErr):
+			b.WriteString(r.codeFrame(lessErr))
+		default:
+			fmt.Fprintf(&b, "Compilation error: %v\n", err)
+		}
+	}
+	return b.String()
+}
+
+// splitErrors returns the errors of a MultiError, or err alone
+func splitErrors(err error) []error {
+	var multi *less_go.MultiError
+	if !errors.As(err, &multi) {
+		return []error{err}
+	}
+	errs := make([]error, len(multi.Errors))
+	for i, e := range multi.Errors {
+		errs[i] = e
+	}
+	return errs
+}
+
+// collectWarnings records the warnings of compiling with options, including
+// those of a compilation that fails, and returns a function listing them
+func collectWarnings(options *less_go.CompileOptions) func() []less_go.Diagnostic {
+	var warnings []less_go.Diagnostic
+	options.OnDiagnostic = func(d less_go.Diagnostic) {
+		warnings = append(warnings, d)
+	}
+	return func() []less_go.Diagnostic {
+		return warnings
+	}
+}
+
+// newReportError returns the JSON form of err, with the details of the
+// LessError it wraps if there is one
+func newReportError(err error) *reportError {
+	var lessErr *less_go.LessError
+	if !errors.As(err, &lessErr) {
+		return &reportError{Type: "Compilation", Message: err.Error()}
+	}
+	rep := &reportError{
+		Type:        errorType(lessErr),
+		Message:     lessErr.Message,
+		Filename:    lessErr.Filename,
+		CallExtract: lessErr.CallExtract,
+		Stack:       lessErr.Stack,
+		Suggestions: lessErr.Suggestions,
+		Candidates:  lessErr.Candidates,
+		Frames:      lessErr.Frames,
+	}
+	if index, ok := lessErr.Index.(int); ok {
+		rep.Index = &index
+	}
+	if lessErr.HasLineColumn() {
+		rep.Line = lessErr.LineNumber()
+		rep.Column = lessErr.ColumnNumber() + 1
+		rep.Extract = lessErr.Extract
+	}
+	if lessErr.CallLine != nil {
+		rep.CallLine = *lessErr.CallLine
+	}
+	return rep
+}
+
+// errorType returns the type of a LessError as less.js prints it, e.g. NameError
+func errorType(err *less_go.LessError) string {
+	if err.Type == "" {
+		return "SyntaxError"
+	}
+	return err.Type + "Error"
+}
+
+// gccError formats err as file:line:col: error: message
+func gccError(input string, err error) string {
+	var lessErr *less_go.LessError
+	if !errors.As(err, &lessErr) {
+		return fmt.Sprintf("%s: error: %v", input, err)
+	}
+	location := lessErr.Filename
+	if location == "" {
+		location = input
+	}
+	if lessErr.HasLineColumn() {
+		location = fmt.Sprintf("%s:%d:%d", location, lessErr.LineNumber(), lessErr.ColumnNumber()+1)
+	}
+	lines := []string{fmt.Sprintf("%s: error: %s: %s", location, errorType(lessErr), lessErr.Message)}
+	if len(lessErr.Suggestions) > 0 {
+		lines = append(lines, fmt.Sprintf("%s: note: did you mean %s?", location, orList(lessErr.Suggestions)))
+	}
+	for _, candidate := range lessErr.Candidates {
+		lines = append(lines, fmt.Sprintf("%s: note: candidate: %s", location, candidate))
+	}
+	for _, frame := range lessErr.Frames {
+		lines = append(lines, fmt.Sprintf("%s: note: in %s", frameLocation(frame), frame.Call))
+	}
+	return strings.Join(lines, "\n")
+}
+
+// frameLocation formats the position of a frame as file:line:col
+func frameLocation(frame less_go.ErrorFrame) string {
+	if frame.Line == 0 {
+		return frame.Filename
+	}
+	return fmt.Sprintf("%s:%d:%d", frame.Filename, frame.Line, frame.Column)
+}
+
+// orList joins names as "a, b or c"
+func orList(names []string) string {
+	if len(names) == 1 {
+		return names[0]
+	}
+	return strings.Join(names[:len(names)-1], ", ") + " or " + names[len(names)-1]
+}
+
+// codeFrame formats err for humans: the message, then the lines around the
+// error with a caret under its column, and the call site if there is one
+func (r *reporter) codeFrame(err *less_go.LessError) string {
+	var b strings.Builder
+	b.WriteString(r.style(errorType(err)+": "+err.Message, ansiBold+ansiRed))
+	if err.Filename != "" {
+		b.WriteString(" in " + err.Filename)
+	}
+	if !err.HasLineColumn() {
+		b.WriteString("\n" + r.stack(err) + r.hints(err))
+		return b.String()
+	}
+	line := err.LineNumber()
+	column := err.ColumnNumber()
+	if column < 0 {
+		column = 0
+	}
+	fmt.Fprintf(&b, " on line %d, column %d:\n", line, column+1)
+
+	width := len(fmt.Sprint(line + 1))
+	gutter := func(marker string, n int) string {
+		number := ""
+		if n > 0 {
+			number = fmt.Sprint(n)
+		}
+		return marker + r.style(fmt.Sprintf(" %*s | ", width, number), ansiGrey)
+	}
+	if line > 1 && err.Extract[0] != "" {
+		b.WriteString(gutter(" ", line-1) + err.Extract[0] + "\n")
+	}
+	b.WriteString(gutter(r.style(">", ansiBold+ansiRed), line) + err.Extract[1] + "\n")
+	// Keep tabs so that the caret lines up with the source
+	var indent strings.Builder
+	for i, c := range []rune(err.Extract[1]) {
+		if i >= column {
+			break
+		}
+		if c == '\t' {
+			indent.WriteRune('\t')
+		} else {
+			indent.WriteRune(' ')
+		}
+	}
+	b.WriteString(gutter(" ", 0) + indent.String() + r.style("^", ansiBold+ansiRed) + "\n")
+	if err.Extract[2] != "" {
+		b.WriteString(gutter(" ", line+1) + err.Extract[2] + "\n")
+	}
+
+	if err.CallLine != nil {
+		b.WriteString(r.style("from ", ansiRed) + err.Filename + "\n")
+		b.WriteString(gutter(" ", *err.CallLine) + err.CallExtract + "\n")
+	}
+	b.WriteString(r.stack(err))
+	b.WriteString(r.hints(err))
+	return b.String()
+}
+
+// stack lists the calls the error happened in, innermost first, as a stack trace
+func (r *reporter) stack(err *less_go.LessError) string {
+	var b strings.Builder
+	for _, frame := range err.Frames {
+		b.WriteString("    at " + frame.Call + r.style(" ("+frameLocation(frame)+")", ansiGrey) + "\n")
+	}
+	return b.String()
+}
+
+// hints returns the names suggested for a misspelled one and the mixins that
+// did not match a call, if any
+func (r *reporter) hints(err *less_go.LessError) string {
+	var b strings.Builder
+	if len(err.Suggestions) > 0 {
+		b.WriteString("Did you mean " + r.style(orList(err.Suggestions), ansiBold) + "?\n")
+	}
+	if len(err.Candidates) > 0 {
+		b.WriteString("Candidates:\n")
+		for _, candidate := range err.Candidates {
+			b.WriteString("  " + candidate + "\n")
+		}
+	}
+	return b.String()
+}
+
+// style wraps s in the escape sequence when colors are enabled
+func (r *reporter) style(s, escape string) string {
+	if !r.color {
+		return s
+	}
+	return escape + s + ansiReset
+}
@@ -0,0 +1,182 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+
+	less_go "github.com/toakleaf/less.go/less"
+)
+
+func TestReporterFormat(t *testing.T) {
+	_, lessErr := less_go.Compile(".mixin() {}\n.a {\n  .mxin();\n}\n", &less_go.CompileOptions{Filename: "main.less"})
+	if lessErr == nil {
+		t.Fatal("expected a compilation error")
+	}
+	_, tabErr := less_go.Compile(".a {\n\tcolor: red;\n\t.b {\n\t\t.missing();\n\t}\n}\n", &less_go.CompileOptions{Filename: "tabs.less"})
+	if tabErr == nil {
+		t.Fatal("expected a compilation error")
+	}
+	warnings := []less_go.Diagnostic{{Code: less_go.DiagnosticDotSlashDeprecated, Message: "old syntax", Filename: "main.less", Line: 1, Column: 2}}
+
+	tests := []struct {
+		name        string
+		format      string
+		errorFormat string
+		err         error
+		silent      bool
+		want        string
+	}{
+		{
+			name:   "code frame",
+			format: "text",
+			err:    lessErr,
+			want: "main.less:1:2: warning: old syntax [dot-slash-deprecated]\n" +
+				"NameError: .mxin is undefined in main.less on line 3, column 3:\n" +
+				"  2 | .a {\n" +
+				"> 3 |   .mxin();\n" +
+				"    |   ^\n" +
+				"  4 | }\n" +
+				"Did you mean .mixin?\n",
+		},
+		{
+			name:   "code frame keeps tabs before the caret",
+			format: "text",
+			err:    tabErr,
+			silent: true,
+			want: "NameError: .missing is undefined in tabs.less on line 4, column 3:\n" +
+				"  3 | \t.b {\n" +
+				"> 4 | \t\t.missing();\n" +
+				"    | \t\t^\n" +
+				"  5 | \t}\n",
+		},
+		{
+			name:   "silent text drops warnings",
+			format: "text",
+			silent: true,
+			want:   "",
+		},
+		{
+			name:        "gcc",
+			format:      "text",
+			errorFormat: "gcc",
+			err:         lessErr,
+			want: "main.less:1:2: warning: old syntax [dot-slash-deprecated]\n" +
+				"main.less:3:3: error: NameError: .mxin is undefined\n" +
+				"main.less:3:3: note: did you mean .mixin?\n",
+		},
+		{
+			name:        "gcc without a position",
+			format:      "text",
+			errorFormat: "gcc",
+			err:         errors.New("open main.less: no such file or directory"),
+			silent:      true,
+			want:        "main.less: error: open main.less: no such file or directory\n",
+		},
+		{
+			name:   "other errors",
+			format: "text",
+			err:    errors.New("boom"),
+			silent: true,
+			want:   "Compilation error: boom\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r, err := newReporter(tt.format, tt.errorFormat)
+			if err != nil {
+				t.Fatal(err)
+			}
+			r.color = false
+			if got := r.format("main.less", "main.css", warnings, tt.err, tt.silent); got != tt.want {
+				t.Errorf("unexpected report:\n%s\nwant:\n%s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestReporterJSON(t *testing.T) {
+	_, lessErr := less_go.Compile(".mixin() {}\n.a {\n  .mxin();\n}\n", &less_go.CompileOptions{Filename: "main.less"})
+	warnings := []less_go.Diagnostic{{Code: less_go.DiagnosticDotSlashDeprecated, Message: "old syntax", Filename: "main.less", Line: 1, Column: 2}}
+
+	tests := []struct {
+		name     string
+		err      error
+		warnings []less_go.Diagnostic
+		check    func(t *testing.T, rep compileReport)
+	}{
+		{
+			name: "success",
+			check: func(t *testing.T, rep compileReport) {
+				if !rep.Success || rep.Error != nil || rep.Warnings == nil || len(rep.Warnings) != 0 {
+					t.Errorf("expected a success with an empty list of warnings, got %+v", rep)
+				}
+			},
+		},
+		{
+			name:     "less error",
+			err:      lessErr,
+			warnings: warnings,
+			check: func(t *testing.T, rep compileReport) {
+				e := rep.Error
+				if rep.Success || e == nil {
+					t.Fatalf("expected a failure, got %+v", rep)
+				}
+				if e.Type != "NameError" || e.Message != ".mxin is undefined" || e.Filename != "main.less" || e.Line != 3 || e.Column != 3 {
+					t.Errorf("unexpected error %+v", e)
+				}
+				if len(e.Suggestions) != 1 || e.Suggestions[0] != ".mixin" {
+					t.Errorf("expected the suggestion .mixin, got %v", e.Suggestions)
+				}
+				if len(rep.Warnings) != 1 || rep.Warnings[0].Code != less_go.DiagnosticDotSlashDeprecated {
+					t.Errorf("expected the warning, got %v", rep.Warnings)
+				}
+			},
+		},
+		{
+			name: "other error",
+			err:  errors.New("boom"),
+			check: func(t *testing.T, rep compileReport) {
+				if rep.Error == nil || rep.Error.Type != "Compilation" || rep.Error.Message != "boom" {
+					t.Errorf("unexpected error %+v", rep.Error)
+				}
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r, err := newReporter("json", "")
+			if err != nil {
+				t.Fatal(err)
+			}
+			// The JSON report ignores silent
+			out := r.format("main.less", "main.css", tt.warnings, tt.err, true)
+			if strings.Count(out, "\n") != 1 || !strings.HasSuffix(out, "\n") {
+				t.Errorf("expected one line, got %q", out)
+			}
+			var rep compileReport
+			if err := json.Unmarshal([]byte(out), &rep); err != nil {
+				t.Fatalf("invalid JSON %q: %v", out, err)
+			}
+			if rep.Input != "main.less" || rep.Output != "main.css" {
+				t.Errorf("unexpected input and output in %q", out)
+			}
+			tt.check(t, rep)
+		})
+	}
+}
+
+func TestNewReporterRejectsUnknownFormats(t *testing.T) {
+	tests := []struct {
+		format, errorFormat, want string
+	}{
+		{"xml", "", `invalid --format "xml"`},
+		{"text", "msvc", `invalid --error-format "msvc"`},
+	}
+	for _, tt := range tests {
+		if _, err := newReporter(tt.format, tt.errorFormat); err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("expected an error with %q, got %v", tt.want, err)
+		}
+	}
+}
@@ -0,0 +1,136 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+
+	less_go "github.com/toakleaf/less.go/less"
+)
+
+// runVars implements `lessc-go vars`: it compiles a stylesheet and prints its
+// root-level variables and detached rulesets, evaluated, as JSON, and returns
+// the process exit code.
+func runVars(args []string) int {
+	fset := flag.NewFlagSet("vars", flag.ContinueOnError)
+	fset.Usage = printVarsUsage
+	flags := newCompileFlags(fset)
+
+	// Flags may follow the input, as in `vars theme.less --modify-var brand=red`
+	var files []string
+	rest := args
+	for {
+		if err := fset.Parse(rest); err != nil {
+			if errors.Is(err, flag.ErrHelp) {
+				return 0
+			}
+			return 2
+		}
+		rest = fset.Args()
+		if len(rest) == 0 {
+			break
+		}
+		files = append(files, rest[0])
+		rest = rest[1:]
+	}
+	flags.parsed(fset)
+
+	if len(files) < 1 || len(files) > 2 {
+		fmt.Fprintln(os.Stderr, "Error: Expected an input file and an optional output file")
+		printVarsUsage()
+		return 2
+	}
+	inputFile := files[0]
+	var outputFile string
+	if len(files) > 1 {
+		outputFile = files[1]
+	}
+
+	report, err := flags.reporter()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 2
+	}
+	config, err := flags.loadConfig()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error loading config: %v\n", err)
+		return 2
+	}
+
+	var input []byte
+	var filename string
+	var baseDirs []string
+	if inputFile == "-" {
+		if input, err = io.ReadAll(os.Stdin); err != nil {
+			fmt.Fprintf(os.Stderr, "Error reading from stdin: %v\n", err)
+			return 1
+		}
+		filename = "stdin"
+		cwd, _ := os.Getwd()
+		baseDirs = []string{cwd}
+	} else {
+		if input, err = os.ReadFile(inputFile); err != nil {
+			fmt.Fprintf(os.Stderr, "Error reading file %s: %v\n", inputFile, err)
+			return 1
+		}
+		if filename, err = filepath.Abs(inputFile); err != nil {
+			fmt.Fprintf(os.Stderr, "Error getting absolute path: %v\n", err)
+			return 1
+		}
+		baseDirs = []string{filepath.Dir(filename)}
+	}
+
+	options := flags.compileOptions(config, filename, baseDirs, "")
+	warnings := collectWarnings(options)
+	variables, err := less_go.ResolveVariables(string(input), options)
+	report.report(inputFile, outputFile, warnings(), err, flags.silent)
+	if err != nil {
+		return 1
+	}
+
+	if variables == nil {
+		variables = []less_go.ResolvedVariable{}
+	}
+	data, err := json.MarshalIndent(variables, "", "  ")
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+	data = append(data, '\n')
+	if outputFile == "" {
+		os.Stdout.Write(data)
+		return 0
+	}
+	if err := os.WriteFile(outputFile, data, 0644); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: writing output file %s: %v\n", outputFile, err)
+		return 1
+	}
+	if !flags.silent {
+		fmt.Fprintf(os.Stderr, "Variables of %s written to %s\n", inputFile, outputFile)
+	}
+	return 0
+}
+
+func printVarsUsage() {
+	fmt.Printf(`Usage: lessc-go vars [options] <input.less|-> [output.json]
+
+Compiles a stylesheet and prints its root-level variables, including those of
+its imports, as JSON: an array in order of definition, each with its name,
+evaluated value and definition location (filename, line, column). Values are
+typed: color (hex and rgba), dimension (number and unit), string, keyword,
+list (items and separator), ruleset (the members of a detached ruleset) or
+other, and always have their CSS except for rulesets.
+
+Examples:
+  lessc-go vars theme.less                     # Print to stdout
+  lessc-go vars theme.less tokens.json         # Write to a file
+  lessc-go vars theme.less --modify-var brand=red
+
+Compilation options (--include-path, --global-var, --modify-var, --math,
+--config, ...) are the same as for compiling; see lessc-go --help.
+`)
+}
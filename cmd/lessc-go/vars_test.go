@@ -0,0 +1,91 @@
+package main
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	less_go "github.com/toakleaf/less.go/less"
+)
+
+func TestRunVars(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{
+		"tokens.less": "@import \"base\";\n@brand: red;\n@overlay: fade(@brand, 50%);\n@space: 4px 8px;\n",
+		"base.less":   "@radius: 2px;\n",
+		"broken.less": "@a: ;\n.x { y: @a(); }\n",
+	})
+	path := func(name string) string {
+		return filepath.Join(dir, name)
+	}
+
+	tests := []struct {
+		name string
+		args []string
+		code int
+		want map[string]any // values by variable name
+	}{
+		{
+			name: "flags after the files",
+			args: []string{path("tokens.less"), path("out.json"), "--modify-var", "brand=#0000ff", "--no-config", "--silent"},
+			want: map[string]any{
+				"@radius":  float64(2),
+				"@brand":   "#0000ff",
+				"@overlay": "#0000ff80",
+				"@space":   nil,
+			},
+		},
+		{
+			name: "compilation error",
+			args: []string{path("broken.less"), path("out.json"), "--no-config", "--format=json"},
+			code: 1,
+		},
+		{
+			name: "too many files",
+			args: []string{path("tokens.less"), path("a.json"), path("b.json")},
+			code: 2,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			os.Remove(path("out.json"))
+			if code := discardOutput(t, func() int { return runVars(tt.args) }); code != tt.code {
+				t.Fatalf("expected exit code %d, got %d", tt.code, code)
+			}
+			if tt.want == nil {
+				return
+			}
+			data, err := os.ReadFile(path("out.json"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			var variables []less_go.ResolvedVariable
+			if err := json.Unmarshal(data, &variables); err != nil {
+				t.Fatal(err)
+			}
+			got := make(map[string]any)
+			for _, v := range variables {
+				got[v.Name] = v.Value
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("expected values %v, got %v", tt.want, got)
+			}
+		})
+	}
+}
+
+// discardOutput runs fn with stdout and stderr discarded and returns its result
+func discardOutput(t *testing.T, fn func() int) int {
+	t.Helper()
+	devNull, err := os.OpenFile(os.DevNull, os.O_WRONLY, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer devNull.Close()
+	stderr, stdout := os.Stderr, os.Stdout
+	os.Stderr, os.Stdout = devNull, devNull
+	defer func() { os.Stderr, os.Stdout = stderr, stdout }()
+	return fn()
+}
@@ -0,0 +1,232 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"sort"
+	"strings"
+	"syscall"
+	"time"
+
+	less_go "github.com/toakleaf/less.go/less"
+)
+
+const (
+	// watchInterval is how often the watched files are checked for changes
+	watchInterval = 100 * time.Millisecond
+
+	// watchDebounce is how long files must stay unchanged before recompiling,
+	// so that saving several files at once triggers a single compilation
+	watchDebounce = 200 * time.Millisecond
+)
+
+// fileState is what a watched file is compared on; a missing file has the zero value
+type fileState struct {
+	modTime time.Time
+	size    int64
+}
+
+// watchTarget is a file compiled in watch mode
+type watchTarget struct {
+	input   string
+	output  string
+	options *less_go.CompileOptions
+	write   outputOptions
+}
+
+// watchTargets returns the watch targets of build jobs
+func watchTargets(jobs []*buildJob, flags *compileFlags, config *projectConfig) []watchTarget {
+	targets := make([]watchTarget, 0, len(jobs))
+	for _, job := range jobs {
+		absPath, err := filepath.Abs(job.input)
+		if err != nil {
+			absPath = job.input
+		}
+		options := flags.compileOptions(config, absPath, []string{filepath.Dir(absPath)}, job.output)
+		targets = append(targets, watchTarget{job.input, job.output, options, flags.output(options)})
+	}
+	return targets
+}
+
+// watchAndCompile compiles the targets, then recompiles each one whenever a
+// file in its dependency graph changes, until interrupted. Compilation errors
+// are reported and watching continues. It returns the process exit code.
+func watchAndCompile(targets []watchTarget, silent bool, report *reporter) int {
+	compiler := less_go.NewCompiler(targets[0].options)
+	defer compiler.Close()
+
+	interrupt := make(chan os.Signal, 1)
+	signal.Notify(interrupt, os.Interrupt, syscall.SIGTERM)
+	defer signal.Stop(interrupt)
+
+	failed := make(map[string]bool, len(targets))
+	compile := func(target watchTarget) {
+		start := time.Now()
+		options := *target.options
+		warnings := collectWarnings(&options)
+		err := compileOnce(compiler.WithOptions(&options), target.input, target.output, target.options.Filename, target.write)
+		failed[target.options.Filename] = err != nil
+
+		message := report.format(target.input, target.output, warnings(), err, silent)
+		if err != nil && !report.json {
+			message = fmt.Sprintf("[%s] Compilation failed: %s\n", start.Format("15:04:05"), target.input) + message
+		} else if err == nil && !silent {
+			output := target.output
+			if output == "" {
+				output = "stdout"
+			}
+			message += fmt.Sprintf("[%s] Compiled %s -> %s in %v\n", start.Format("15:04:05"), target.input, output, time.Since(start).Round(time.Millisecond))
+		}
+		report.write(message)
+	}
+	watchedFiles := func(previous map[string]fileState) map[string]fileState {
+		var files []string
+		anyFailed := false
+		for _, target := range targets {
+			if graph := compiler.Dependencies(target.options.Filename); graph != nil {
+				files = append(files, graph.Files()...)
+			}
+			files = append(files, pluginFiles(target.options)...)
+			anyFailed = anyFailed || failed[target.options.Filename]
+		}
+		if anyFailed {
+			// A failed compilation stops at the first error; keep watching the
+			// files of the last graph so that restoring one of them is noticed
+			files = append(files, keys(previous)...)
+		}
+		return statFiles(files)
+	}
+
+	for _, target := range targets {
+		compile(target)
+	}
+	watched := watchedFiles(nil)
+	if !silent {
+		fmt.Fprintf(os.Stderr, "Watching %d files for changes (Ctrl+C to stop)\n", len(watched))
+	}
+
+	ticker := time.NewTicker(watchInterval)
+	defer ticker.Stop()
+
+	changed := map[string]bool{}
+	var lastChange time.Time
+	for {
+		select {
+		case <-interrupt:
+			return 0
+		case now := <-ticker.C:
+			current := statFiles(keys(watched))
+			for file, state := range current {
+				if state != watched[file] {
+					changed[file] = true
+					lastChange = now
+				}
+			}
+			watched = current
+
+			// Wait for the files to settle before recompiling
+			if len(changed) == 0 || now.Sub(lastChange) < watchDebounce {
+				continue
+			}
+			if !silent {
+				fmt.Fprintf(os.Stderr, "Changed: %s\n", strings.Join(keys(changed), ", "))
+			}
+			for _, target := range rebuildTargets(compiler, targets, changed, failed) {
+				compile(target)
+			}
+			changed = map[string]bool{}
+			watched = watchedFiles(watched)
+		}
+	}
+}
+
+// rebuildTargets invalidates the changed files in the compiler's cache and
+// returns the targets to compile again: those whose dependency graph holds one
+// of them or one of whose plugins changed, and those that failed last time,
+// whose graph may be incomplete
+func rebuildTargets(compiler *less_go.Compiler, targets []watchTarget, changed, failed map[string]bool) []watchTarget {
+	affected := map[string]bool{}
+	for _, entrypoint := range compiler.Invalidate(keys(changed)...) {
+		affected[entrypoint] = true
+	}
+	var rebuild []watchTarget
+	for _, target := range targets {
+		if affected[target.options.Filename] || failed[target.options.Filename] || dependsOnPlugin(target.options, changed) {
+			rebuild = append(rebuild, target)
+		}
+	}
+	return rebuild
+}
+
+// dependsOnPlugin reports whether one of the changed files is a plugin given in options
+func dependsOnPlugin(options *less_go.CompileOptions, changed map[string]bool) bool {
+	for _, file := range pluginFiles(options) {
+		if changed[file] {
+			return true
+		}
+	}
+	return false
+}
+
+// compileOnce reads and compiles inputFile and writes the result. Progress,
+// warnings and errors are reported by the caller.
+func compileOnce(compiler *less_go.Compiler, inputFile, outputFile, filename string, output outputOptions) error {
+	content, err := os.ReadFile(inputFile)
+	if err != nil {
+		return err
+	}
+	result, err := compiler.Compile(string(content), filename)
+	if err != nil {
+		return err
+	}
+	output.silent = true
+	return output.write(result, inputFile, outputFile)
+}
+
+// pluginFiles returns the local script files of plugins given with --plugin
+// or in the project config.
+// Plugins installed from npm are not watched.
+func pluginFiles(options *less_go.CompileOptions) []string {
+	var files []string
+	for _, plugin := range options.Plugins {
+		name := plugin.Name
+		if !strings.HasPrefix(name, ".") && !filepath.IsAbs(name) {
+			continue
+		}
+		if !filepath.IsAbs(name) {
+			name = filepath.Join(filepath.Dir(options.Filename), name)
+		}
+		if filepath.Ext(name) == "" {
+			name += ".js"
+		}
+		files = append(files, name)
+	}
+	return files
+}
+
+// statFiles returns the current state of each local file
+func statFiles(files []string) map[string]fileState {
+	states := make(map[string]fileState, len(files))
+	for _, file := range files {
+		if strings.Contains(file, "://") {
+			continue
+		}
+		var state fileState
+		if info, err := os.Stat(file); err == nil {
+			state = fileState{modTime: info.ModTime(), size: info.Size()}
+		}
+		states[file] = state
+	}
+	return states
+}
+
+func keys[V any](m map[string]V) []string {
+	result := make([]string, 0, len(m))
+	for k := range m {
+		result = append(result, k)
+	}
+	sort.Strings(result)
+	return result
+}
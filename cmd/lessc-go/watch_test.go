@@ -0,0 +1,104 @@
+package main
+
+import (
+	"flag"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	less_go "github.com/toakleaf/less.go/less"
+)
+
+func TestRebuildTargets(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{
+		"shared.less":     "@color: red;",
+		"site-only.less":  ".site { width: 1px; }",
+		"site.less":       "@import \"shared\"; @import \"site-only\"; .a { color: @color; }",
+		"admin.less":      "@import \"shared\"; .b { color: @color; }",
+		"plugins/size.js": "",
+		"unrelated.less":  "",
+	})
+	path := func(name string) string {
+		return filepath.Join(dir, filepath.FromSlash(name))
+	}
+
+	flags := newCompileFlags(flag.NewFlagSet("test", flag.ContinueOnError))
+	targets := watchTargets([]*buildJob{
+		{input: path("site.less"), output: path("site.css")},
+		{input: path("admin.less"), output: path("admin.css")},
+	}, flags, nil)
+
+	compiler := less_go.NewCompiler(targets[0].options)
+	defer compiler.Close()
+	compileAll := func() {
+		t.Helper()
+		for _, target := range targets {
+			if err := compileOnce(compiler.WithOptions(target.options), target.input, target.output, target.options.Filename, target.write); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+	compileAll()
+
+	// The decision only looks at the plugins' paths, so they are not loaded
+	watched := append([]watchTarget{}, targets...)
+	withPlugin := *targets[1].options
+	withPlugin.Plugins = []less_go.PluginSpec{{Name: "./plugins/size"}}
+	watched[1].options = &withPlugin
+
+	tests := []struct {
+		name    string
+		changed []string
+		failed  []string
+		want    []string
+	}{
+		{"shared import", []string{"shared.less"}, nil, []string{"site.less", "admin.less"}},
+		{"import of one entrypoint", []string{"site-only.less"}, nil, []string{"site.less"}},
+		{"entrypoint itself", []string{"admin.less"}, nil, []string{"admin.less"}},
+		{"plugin", []string{"plugins/size.js"}, nil, []string{"admin.less"}},
+		{"unrelated file", []string{"unrelated.less"}, nil, nil},
+		// A failed compilation may not have seen all its imports
+		{"failed target", []string{"unrelated.less"}, []string{"site.less"}, []string{"site.less"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			changed := map[string]bool{}
+			for _, file := range tt.changed {
+				changed[path(file)] = true
+			}
+			failed := map[string]bool{}
+			for _, file := range tt.failed {
+				failed[path(file)] = true
+			}
+			var got []string
+			for _, target := range rebuildTargets(compiler, watched, changed, failed) {
+				got = append(got, filepath.Base(target.input))
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("expected %v to be rebuilt, got %v", tt.want, got)
+			}
+			// Invalidate forgets the graphs, so compile again for the next case
+			compileAll()
+		})
+	}
+
+	// The rebuild sees the new contents of an invalidated import
+	writeFiles(t, dir, map[string]string{"shared.less": "@color: blue;"})
+	for _, target := range rebuildTargets(compiler, targets, map[string]bool{path("shared.less"): true}, nil) {
+		if err := compileOnce(compiler.WithOptions(target.options), target.input, target.output, target.options.Filename, target.write); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, output := range []string{"site.css", "admin.css"} {
+		css, err := os.ReadFile(path(output))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if want := "color: blue;"; !strings.Contains(string(css), want) {
+			t.Errorf("expected %q in %s:\n%s", want, output, css)
+		}
+	}
+}
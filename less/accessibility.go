@@ -0,0 +1,161 @@
+package less_go
+
+import (
+	"fmt"
+	"math"
+	"strings"
+)
+
+// contrastVisitor warns about the rulesets whose color has a WCAG contrast
+// ratio below a minimum against their background-color, or a background of a
+// single color. The minimum is the MinContrastRatio option, or that of an
+// @accessibility directive at the root of the stylesheet:
+//
+//	@accessibility AA;  // 4.5:1, as AAA is 7:1; a number is a ratio, off disables the check
+//
+// The directives are removed from the output.
+type contrastVisitor struct {
+	visitor     *Visitor
+	minRatio    float64
+	diagnostics *diagnosticCollector
+	contents    map[string]string
+}
+
+func newContrastVisitor(minRatio float64, diagnostics *diagnosticCollector, contents map[string]string) *contrastVisitor {
+	return &contrastVisitor{minRatio: minRatio, diagnostics: diagnostics, contents: contents}
+}
+
+// Run reads the directives of root, then visits it only if there is a minimum
+// ratio, so that compilations without one don't pay for the Visitor
+func (cv *contrastVisitor) Run(root any) any {
+	if ruleset, ok := root.(*Ruleset); ok {
+		cv.readDirectives(ruleset)
+	}
+	if cv.minRatio <= 0 {
+		return root
+	}
+	if cv.visitor == nil {
+		cv.visitor = NewVisitor(cv)
+	}
+	return cv.visitor.Visit(root)
+}
+
+func (cv *contrastVisitor) IsReplacing() bool {
+	return false
+}
+
+func (cv *contrastVisitor) VisitNode(node any, visitArgs *VisitArgs) (any, bool) {
+	if ruleset, ok := node.(*Ruleset); ok && !ruleset.Root {
+		cv.VisitRuleset(ruleset, visitArgs)
+	}
+	return node, true
+}
+
+func (cv *contrastVisitor) VisitNodeOut(node any) bool {
+	return true
+}
+
+// readDirectives removes the @accessibility directives of the root and sets
+// the minimum ratio to that of the last one
+func (cv *contrastVisitor) readDirectives(root *Ruleset) {
+	var rules []any
+	for i, rule := range root.Rules {
+		atRule, ok := rule.(*AtRule)
+		if !ok || !strings.EqualFold(atRule.Name, "@accessibility") {
+			if rules != nil {
+				rules = append(rules, rule)
+			}
+			continue
+		}
+		if rules == nil {
+			rules = append(make([]any, 0, len(root.Rules)), root.Rules[:i]...)
+		}
+
+		level := ""
+		switch v := atRule.Value.(type) {
+		case *Keyword:
+			level = v.value
+		case *Anonymous:
+			level = fmt.Sprint(v.Value)
+		case *Dimension:
+			if v.Unit == nil || v.Unit.IsEmpty() {
+				cv.minRatio = v.Value
+				continue
+			}
+		}
+		switch strings.ToLower(strings.TrimSpace(level)) {
+		case "aa":
+			cv.minRatio = 4.5
+		case "aaa":
+			cv.minRatio = 7
+		case "off":
+			cv.minRatio = 0
+		default:
+			filename, _ := atRule.FileInfo()["filename"].(string)
+			panic(&LessError{
+				Type:     "Syntax",
+				Message:  "@accessibility expects AA, AAA, off or a contrast ratio such as 4.5",
+				Filename: filename,
+				Index:    atRule.GetIndex(),
+			})
+		}
+	}
+	if rules != nil {
+		root.Rules = rules
+	}
+}
+
+func (cv *contrastVisitor) VisitRuleset(ruleset *Ruleset, visitArgs *VisitArgs) {
+	// The last declarations win, as in the browser
+	var fg *Declaration
+	var fgColor, bgColor *Color
+	for _, rule := range ruleset.Rules {
+		d, ok := rule.(*Declaration)
+		if !ok || d.variable {
+			continue
+		}
+		switch strings.ToLower(d.GetName()) {
+		case "color":
+			fg, fgColor = d, declarationColor(d)
+		case "background-color", "background":
+			bgColor = declarationColor(d)
+		}
+	}
+	// A translucent background shows what is behind it, which is not known
+	if fgColor == nil || bgColor == nil || bgColor.Alpha < 1 {
+		return
+	}
+	ratio := contrastRatio(fgColor, bgColor)
+	if ratio >= cv.minRatio {
+		return
+	}
+	filename, _ := fg.FileInfo()["filename"].(string)
+	cv.diagnostics.warnAt(DiagnosticLowContrast,
+		fmt.Sprintf("color %s on background %s has a contrast ratio of %.2f:1, below %g:1",
+			fgColor.ToCSS(nil), bgColor.ToCSS(nil), math.Floor(ratio*100)/100, cv.minRatio),
+		filename, fg.GetIndex(), cv.contents)
+}
+
+// declarationColor returns the value of a declaration if it is a single
+// color, or nil
+func declarationColor(d *Declaration) *Color {
+	var value any = d.Value
+	for {
+		switch v := value.(type) {
+		case *Value:
+			if len(v.Value) != 1 {
+				return nil
+			}
+			value = v.Value[0]
+		case *Expression:
+			if len(v.Value) != 1 {
+				return nil
+			}
+			value = v.Value[0]
+		case *Color:
+			return v
+		default:
+			return nil
+		}
+	}
+}
@@ -0,0 +1,156 @@
+package less_go
+
+import (
+	"errors"
+	"math"
+	"strings"
+	"testing"
+)
+
+func TestContrastFunctions(t *testing.T) {
+	hex := func(s string) *Color { return NewColor(s, 1, "") }
+	tests := []struct {
+		name string
+		got  any
+		want float64
+	}{
+		{"black on white", ColorContrastRatio(hex("000000"), hex("ffffff")), 21},
+		{"same colors", ColorContrastRatio(hex("777777"), hex("777777")), 1},
+		{"order does not matter", ColorContrastRatio(hex("ffffff"), hex("777777")), 4.47808945},
+		{"translucent over white", ColorContrastRatio(NewColor([]float64{0, 0, 0}, 0.5, ""), hex("ffffff")), 3.98},
+		{"APCA black on white", ColorAPCAContrast(hex("000000"), hex("ffffff")), 106.04067321},
+		{"APCA white on black", ColorAPCAContrast(hex("ffffff"), hex("000000")), -107.88473318},
+		{"APCA too close", ColorAPCAContrast(hex("fafafa"), hex("ffffff")), 0},
+	}
+	for _, tt := range tests {
+		dim, ok := tt.got.(*Dimension)
+		if !ok {
+			t.Errorf("%s: expected a number, got %v", tt.name, tt.got)
+			continue
+		}
+		if math.Abs(dim.Value-tt.want) > 0.01 {
+			t.Errorf("%s: got %v, want %v", tt.name, dim.Value, tt.want)
+		}
+	}
+
+	if _, ok := ColorContrastRatio(NewKeyword("foo"), hex("ffffff")).(*LessError); !ok {
+		t.Error("expected an error for an argument that is not a color")
+	}
+}
+
+func TestEnsureContrast(t *testing.T) {
+	white, black := NewColor("ffffff", 1, ""), NewColor("000000", 1, "")
+	ratio := func(r float64) *Dimension {
+		d, _ := NewDimension(r, nil)
+		return d
+	}
+	tests := []struct {
+		name              string
+		color, background *Color
+		ratio             any
+		want              string
+	}{
+		{"darkened for AA", NewColor("aaaaaa", 1, ""), white, nil, "#767676"},
+		{"lightened for AAA", NewColor("555555", 1, ""), black, ratio(7), "#959595"},
+		{"hue and saturation kept", NewColor("66aaff", 1, ""), white, nil, "#006ffa"},
+		{"already enough", NewColor("336699", 1, ""), white, ratio(4.5), "#336699"},
+		{"unreachable", NewColor("777777", 1, ""), NewColor("777777", 1, ""), ratio(21), "#000000"},
+	}
+	for _, tt := range tests {
+		c, ok := ColorEnsureContrast(tt.color, tt.background, tt.ratio).(*Color)
+		if !ok {
+			t.Errorf("%s: expected a color", tt.name)
+			continue
+		}
+		if got := c.ToRGB(); got != tt.want {
+			t.Errorf("%s: got %s, want %s", tt.name, got, tt.want)
+		}
+		target := 4.5
+		if d, ok := tt.ratio.(*Dimension); ok {
+			target = d.Value
+		}
+		if tt.name != "unreachable" && contrastRatio(NewColor(c.ToRGB()[1:], 1, ""), tt.background) < target {
+			t.Errorf("%s: %s does not reach %v:1", tt.name, c.ToRGB(), target)
+		}
+	}
+}
+
+func TestCompile_ContrastWarnings(t *testing.T) {
+	input := `.low { color: #777; background: #fff; }
+.ok { color: #767676; background-color: white; }
+.translucent { color: fade(black, 30%); background: #fff; }
+.image { color: #777; background: url(x.png) #fff; }
+.transparent { color: #777; background-color: transparent; }
+.color-only { color: #eee; }
+@media print {
+  .nested { color: #aaa; background-color: #fff; }
+}
+`
+	var reported []Diagnostic
+	result, err := Compile(input, &CompileOptions{
+		Filename:         "theme.less",
+		MinContrastRatio: 4.5,
+		OnDiagnostic:     func(d Diagnostic) { reported = append(reported, d) },
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []struct {
+		line    int
+		message string
+	}{
+		{1, "color #777 on background #fff has a contrast ratio of 4.47:1, below 4.5:1"},
+		{3, "color rgba(0, 0, 0, 0.3) on background #fff has a contrast ratio of 2.10:1, below 4.5:1"},
+		{8, "color #aaa on background #fff has a contrast ratio of 2.32:1, below 4.5:1"},
+	}
+	if len(result.Warnings) != len(want) || len(reported) != len(want) {
+		t.Fatalf("expected %d warnings, got %v", len(want), result.Warnings)
+	}
+	for i, w := range want {
+		d := result.Warnings[i]
+		if d.Code != DiagnosticLowContrast || d.Message != w.message || d.Filename != "theme.less" || d.Line != w.line {
+			t.Errorf("got %+v, want line %d: %s", d, w.line, w.message)
+		}
+	}
+
+	// Without the option, nothing is checked
+	result, err = Compile(input, nil)
+	if err != nil || len(result.Warnings) != 0 {
+		t.Errorf("expected no warnings, got %v, %v", result, err)
+	}
+}
+
+func TestCompile_AccessibilityDirective(t *testing.T) {
+	rules := ".a { color: #777; background: #fff; }\n.b { color: #666; background: #fff; }\n"
+	tests := []struct {
+		source   string
+		option   float64
+		warnings int
+	}{
+		{"@accessibility AA;\n" + rules, 0, 1},
+		{"@accessibility AAA;\n" + rules, 0, 2},
+		{"@accessibility 3;\n" + rules, 0, 0},
+		{"@level: AA;\n@accessibility @level;\n" + rules, 0, 1},
+		{"@accessibility off;\n" + rules, 4.5, 0},
+		{"@accessibility AAA;\n" + rules, 3, 2},
+	}
+	for _, tt := range tests {
+		result, err := Compile(tt.source, &CompileOptions{MinContrastRatio: tt.option})
+		if err != nil {
+			t.Errorf("%q: %v", tt.source, err)
+			continue
+		}
+		if len(result.Warnings) != tt.warnings {
+			t.Errorf("%q: expected %d warnings, got %v", tt.source, tt.warnings, result.Warnings)
+		}
+		if strings.Contains(result.CSS, "@accessibility") {
+			t.Errorf("%q: expected the directive to be removed:\n%s", tt.source, result.CSS)
+		}
+	}
+
+	_, err := Compile("@accessibility AB;\n"+rules, nil)
+	var lessErr *LessError
+	if !errors.As(err, &lessErr) || lessErr.Line == nil || *lessErr.Line != 1 || !strings.Contains(lessErr.Message, "@accessibility expects") {
+		t.Errorf("expected a located error for an invalid level, got %v", err)
+	}
+}
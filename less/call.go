@@ -1,6 +1,7 @@
 package less_go
 
 import (
+	"errors"
 	"fmt"
 	"os"
 	"strings"
@@ -348,21 +349,7 @@ func (c *DefaultParserFunctionCaller) Call(args []any) (any, error) {
 	// Create a simplified context for function calling if needed
 	if !needsEval {
 		// For functions that don't need evaluated args, create a context with proper EvalContext
-		// We need a registry that contains this function
-		tempRegistry := NewRegistryFunctionAdapter(DefaultRegistry.Inherit())
-		tempRegistry.registry.Add(c.name, c.funcDef)
-
-		funcContext := &Context{
-			Frames: []*Frame{
-				{
-					FunctionRegistry: tempRegistry,
-					EvalContext:      c.context,  // Pass the evaluation context for variable resolution
-					CurrentFileInfo:  c.fileInfo, // Pass the current file information
-				},
-			},
-		}
-
-		return c.funcDef.CallCtx(funcContext, args...)
+		return c.funcDef.CallCtx(c.functionContext(), args...)
 	}
 
 	// For functions that need evaluated args, evaluate them first
@@ -411,9 +398,37 @@ func (c *DefaultParserFunctionCaller) Call(args []any) (any, error) {
 	// This handles cases like @color2: #FFF/* comment2 */;
 	filteredArgs := c.filterCommentsFromArgs(evaluatedArgs)
 
+	// Go functions from CompileOptions.Functions get the evaluation context too
+	if fn, ok := c.funcDef.(Function); ok {
+		result, err := fn(c.functionContext(), filteredArgs...)
+		var lessErr *LessError
+		if err != nil && !errors.As(err, &lessErr) {
+			// Report plain errors like the built-ins report invalid arguments, so
+			// that they fail the compilation instead of leaving the call as CSS
+			err = &LessError{Type: "Argument", Message: err.Error()}
+		}
+		return result, err
+	}
 	return c.funcDef.Call(filteredArgs...)
 }
 
+// functionContext creates the context passed to a function's CallCtx
+func (c *DefaultParserFunctionCaller) functionContext() *Context {
+	// We need a registry that contains this function
+	tempRegistry := NewRegistryFunctionAdapter(DefaultRegistry.Inherit())
+	tempRegistry.registry.Add(c.name, c.funcDef)
+
+	return &Context{
+		Frames: []*Frame{
+			{
+				FunctionRegistry: tempRegistry,
+				EvalContext:      c.context,  // Pass the evaluation context for variable resolution
+				CurrentFileInfo:  c.fileInfo, // Pass the current file information
+			},
+		},
+	}
+}
+
 func (c *DefaultParserFunctionCaller) filterCommentsFromArgs(args []any) []any {
 	isComment := func(node any) bool {
 		if comment, ok := node.(*Comment); ok {
@@ -604,7 +619,15 @@ func (c *Call) FileInfo() map[string]any {
 	return c._fileInfo
 }
 
+// Eval evaluates the function call, on the call stack of errors
 func (c *Call) Eval(context any) (any, error) {
+	calls := callStackOf(context)
+	depth := calls.enter("function", c)
+	result, err := c.eval(context)
+	return result, calls.leave(depth, err)
+}
+
+func (c *Call) eval(context any) (any, error) {
 	// Convert context to EvalContext if it's a map
 	var evalContext EvalContext
 	if ctx, ok := context.(EvalContext); ok {
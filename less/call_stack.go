@@ -0,0 +1,195 @@
+package less_go
+
+import "strings"
+
+// ErrorFrame is a call that was being evaluated when an error happened: a
+// mixin call, a function call, a detached ruleset call or the @import of the
+// file the error is in
+type ErrorFrame struct {
+	// Kind is "mixin", "function", "ruleset" or "import"
+	Kind string `json:"kind"`
+	// Call is the call as written, as .button-variant(@color) or @import "lib.less"
+	Call     string `json:"call"`
+	Filename string `json:"filename,omitempty"`
+	// Line and Column are 1-based, or 0 when the position is not known
+	Line   int `json:"line,omitempty"`
+	Column int `json:"column,omitempty"`
+	index  int
+}
+
+// callStack is the stack of the calls being evaluated in a compilation, shared
+// by its evaluation contexts, from which errors get their Frames. A nil stack
+// records nothing.
+//
+// A call is left when it returns; a panic leaves the calls it goes through on
+// the stack, so that it can be traced where it is recovered. The rules being
+// evaluated or output are pushed too, with no kind, to locate internal errors;
+// they are not frames.
+type callStack struct {
+	calls []stackCall
+	// importedFrom maps an imported file to the @import that imported it first
+	importedFrom map[string]*Import
+}
+
+type stackCall struct {
+	kind string
+	node any
+}
+
+func newCallStack() *callStack {
+	return &callStack{importedFrom: make(map[string]*Import)}
+}
+
+// callStackOf returns the stack of an *Eval or map context, or nil
+func callStackOf(context any) *callStack {
+	switch c := context.(type) {
+	case *Eval:
+		return c.calls
+	case map[string]any:
+		if calls, ok := c["callStack"].(*callStack); ok {
+			return calls
+		}
+		if evalCtx, ok := c["_evalContext"].(*Eval); ok {
+			return evalCtx.calls
+		}
+	}
+	return nil
+}
+
+// enter pushes a call of node and returns the depth to pass to leave
+func (s *callStack) enter(kind string, node any) int {
+	if s == nil {
+		return 0
+	}
+	s.calls = append(s.calls, stackCall{kind, node})
+	return len(s.calls) - 1
+}
+
+// visit pushes rule, a rule being evaluated or output, and returns the depth to
+// pass to leave
+func (s *callStack) visit(rule any) int {
+	return s.enter("", rule)
+}
+
+// leave pops the calls from depth on, tracing err first if the call failed
+func (s *callStack) leave(depth int, err error) error {
+	if s == nil {
+		return err
+	}
+	if err != nil {
+		s.trace(err)
+	}
+	s.calls = s.calls[:min(depth, len(s.calls))]
+	return err
+}
+
+// depth returns the number of calls on the stack
+func (s *callStack) depth() int {
+	if s == nil {
+		return 0
+	}
+	return len(s.calls)
+}
+
+// imported records that filename was imported by i
+func (s *callStack) imported(i *Import, filename string) {
+	if s == nil || filename == "" {
+		return
+	}
+	if _, ok := s.importedFrom[filename]; !ok {
+		s.importedFrom[filename] = i
+	}
+}
+
+// trace sets the Frames of a *LessError or *MixinCallError that has none to
+// the calls on the stack, innermost first, followed by the imports of the file
+// of the outermost call
+func (s *callStack) trace(err any) {
+	if s == nil {
+		return
+	}
+	var frames *[]ErrorFrame
+	var filename string
+	var index any
+	switch e := err.(type) {
+	case *LessError:
+		frames, filename, index = &e.Frames, e.Filename, e.Index
+	case *MixinCallError:
+		frames, filename, index = &e.Frames, e.Filename, e.Index
+	default:
+		return
+	}
+	if *frames != nil {
+		return
+	}
+	trace := []ErrorFrame{}
+	for i := len(s.calls) - 1; i >= 0; i-- {
+		if s.calls[i].kind == "" {
+			continue
+		}
+		frame := s.frame(s.calls[i].kind, s.calls[i].node)
+		// A call that failed itself is where the error is, not a frame
+		if len(trace) == 0 && frame.Filename == filename && index == frame.index {
+			continue
+		}
+		trace = append(trace, frame)
+	}
+	if len(trace) > 0 {
+		filename = trace[len(trace)-1].Filename
+	}
+	seen := make(map[string]bool)
+	for i := s.importedFrom[filename]; i != nil && !seen[filename]; i = s.importedFrom[filename] {
+		seen[filename] = true
+		frame := s.frame("import", i)
+		trace = append(trace, frame)
+		filename = frame.Filename
+	}
+	*frames = trace
+}
+
+// current returns the innermost call or rule on the stack that has a position,
+// as its filename and index, or ok false if there is none
+func (s *callStack) current() (filename string, index int, ok bool) {
+	if s == nil {
+		return "", 0, false
+	}
+	for i := len(s.calls) - 1; i >= 0; i-- {
+		frame := s.frame(s.calls[i].kind, s.calls[i].node)
+		if frame.Filename != "" {
+			return frame.Filename, frame.index, true
+		}
+	}
+	return "", 0, false
+}
+
+// frame returns the frame of a call of node, without its line and column
+func (s *callStack) frame(kind string, node any) ErrorFrame {
+	frame := ErrorFrame{Kind: kind}
+	switch n := node.(type) {
+	case *Call:
+		frame.Call = n.Name + "()"
+	case *MixinCall, *VariableCall, *Import:
+		frame.Call = strings.TrimSuffix(Print(n), ";")
+	}
+	if located, ok := node.(interface {
+		GetIndex() int
+		FileInfo() map[string]any
+	}); ok {
+		frame.index = located.GetIndex()
+		frame.Filename = getFilename(located.FileInfo())
+	}
+	return frame
+}
+
+// positionFrames works out the lines and columns of frames from contents
+func positionFrames(frames []ErrorFrame, contents map[string]string) {
+	for i := range frames {
+		source, ok := contents[frames[i].Filename]
+		if !ok || frames[i].Line > 0 || frames[i].index < 0 || frames[i].index > len(source) {
+			continue
+		}
+		if loc := GetLocation(frames[i].index, source); loc.Line != nil {
+			frames[i].Line, frames[i].Column = *loc.Line+1, loc.Column+1
+		}
+	}
+}
@@ -0,0 +1,86 @@
+package less_go
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+// callStackFS is a mixin library whose inner mixin fails for non-numbers
+var callStackFS = fstest.MapFS{
+	"lib.less": {Data: []byte(".inner(@v) {\n  width: percentage(@v);\n}\n.outer(@v) {\n  .inner(@v);\n}\n")},
+}
+
+// frameList formats the frames of the *LessError of err as "kind call file:line:column"
+func frameList(t *testing.T, err error) []string {
+	t.Helper()
+	var lessErr *LessError
+	if !errors.As(err, &lessErr) {
+		t.Fatalf("expected a *LessError, got %v", err)
+	}
+	var frames []string
+	for _, f := range lessErr.Frames {
+		frames = append(frames, fmt.Sprintf("%s %s %s:%d:%d", f.Kind, f.Call, f.Filename, f.Line, f.Column))
+	}
+	return frames
+}
+
+func TestCompile_ErrorFrames(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		want  []string
+	}{
+		{
+			name:  "nested mixins",
+			input: "@import \"lib.less\";\n.a {\n  .outer(\"x\");\n}\n",
+			want:  []string{"mixin .inner(@v) lib.less:5:3", `mixin .outer("x") main.less:3:3`},
+		},
+		{
+			name:  "detached ruleset",
+			input: "@import \"lib.less\";\n@dr: { .outer(\"x\"); };\n.a { @dr(); }\n",
+			want:  []string{"mixin .inner(@v) lib.less:5:3", `mixin .outer("x") main.less:2:8`, "ruleset @dr() main.less:3:6"},
+		},
+		{
+			name:  "each callback",
+			input: "@import \"lib.less\";\n.b { each(1 2, { .inner(\"y\"); }); }\n",
+			want:  []string{`mixin .inner("y") main.less:2:18`, "function each() main.less:2:6"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for _, collect := range []bool{false, true} {
+				_, err := Compile(tt.input, &CompileOptions{Filename: "main.less", FS: callStackFS, CollectErrors: collect})
+				if got := frameList(t, err); strings.Join(got, ", ") != strings.Join(tt.want, ", ") {
+					t.Errorf("CollectErrors=%v: got %v, want %v", collect, got, tt.want)
+				}
+			}
+		})
+	}
+}
+
+func TestCompile_ErrorFramesOfImports(t *testing.T) {
+	fsys := fstest.MapFS{
+		"a.less": {Data: []byte("@import \"b.less\";\n")},
+		"b.less": {Data: []byte(".x { width: percentage(\"x\"); }\n")},
+	}
+	_, err := Compile("// main\n@import \"a.less\";\n", &CompileOptions{Filename: "main.less", FS: fsys})
+	want := []string{`import @import "b.less" a.less:1:1`, `import @import "a.less" main.less:2:1`}
+	if got := frameList(t, err); strings.Join(got, ", ") != strings.Join(want, ", ") {
+		t.Errorf("got %v, want %v", got, want)
+	}
+}
+
+func TestCompile_ErrorFramesAtCall(t *testing.T) {
+	// An error moved to the call of the mixin it happened in does not list that call
+	_, err := Compile(".m() { .nope(); }\n.b { .m(); }\n", nil)
+	if got := frameList(t, err); len(got) != 0 {
+		t.Errorf("expected no frames, got %v", got)
+	}
+	_, err = Compile("@a: percentage(\"x\");", nil)
+	if got := frameList(t, err); len(got) != 0 {
+		t.Errorf("expected no frames, got %v", got)
+	}
+}
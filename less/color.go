@@ -28,6 +28,16 @@ type Color struct {
 	RGB   []float64
 	Alpha float64
 	Value string
+
+	// Space is the color space of CSS Color 4 the color was written in, such
+	// as "oklch" or "display-p3", and Coords its coordinates in it; RGB is then
+	// the color mapped into the sRGB gamut. Space is empty for sRGB colors.
+	Space  string
+	Coords []float64
+
+	// written is the call the color was written as, e.g. hwb(120 0% 0%), which
+	// it is output as; colors computed by functions have none
+	written *Call
 }
 
 func NewColor(rgb any, alpha float64, originalForm string) *Color {
@@ -183,6 +193,25 @@ func (c *Color) ToCSS(context any) string {
 		}
 	}
 
+	if c.written != nil {
+		var builder strings.Builder
+		c.written.GenCSS(context, &CSSOutput{
+			Add: func(chunk any, fileInfo any, index any) {
+				if chunk != nil {
+					builder.WriteString(fmt.Sprint(chunk))
+				}
+			},
+			IsEmpty: func() bool {
+				return builder.Len() == 0
+			},
+		})
+		return builder.String()
+	}
+
+	if c.Space != "" {
+		return c.spaceCSS(context)
+	}
+
 	compress := false
 	if ctx, ok := context.(map[string]any); ok {
 		if comp, ok := ctx["compress"].(bool); ok {
@@ -413,6 +413,105 @@ func colorHSLA(h, s, l, a float64) *Color {
 	return NewColor(rgb, a, "hsla")
 }
 
+// Color functions of CSS Color 4
+
+// colorInSpace creates a color in space from the channels of its function, or
+// returns nil to output the call as written if they are not constant
+func colorInSpace(space string, channels any) any {
+	expr, ok := channels.(*Expression)
+	if !ok {
+		return nil
+	}
+	coords, alpha, ok := modernColorArgs(expr.Value, colorSpaces[space].percents)
+	if !ok {
+		return nil
+	}
+	color := NewColorInSpace(space, coords[:], alpha)
+	color.written = NewCall(space, []any{expr}, 0, nil)
+	return color
+}
+
+// ColorOKLCH creates a color from OKLCH values, e.g. oklch(70% 0.1 200 / 50%)
+func ColorOKLCH(channels any) any {
+	return colorInSpace("oklch", channels)
+}
+
+// ColorOKLab creates a color from OKLab values, e.g. oklab(0.7 -0.05 0.1)
+func ColorOKLab(channels any) any {
+	return colorInSpace("oklab", channels)
+}
+
+// ColorLab creates a color from CIE Lab values, e.g. lab(50% 40 -20)
+func ColorLab(channels any) any {
+	return colorInSpace("lab", channels)
+}
+
+// ColorLCH creates a color from CIE LCH values, e.g. lch(50% 60 300)
+func ColorLCH(channels any) any {
+	return colorInSpace("lch", channels)
+}
+
+// ColorHWB creates a color from hue, whiteness and blackness, e.g.
+// hwb(200 10% 20%). HWB is a form of sRGB, so the color is output as hex.
+func ColorHWB(channels any) any {
+	expr, ok := channels.(*Expression)
+	if !ok {
+		return nil
+	}
+	hwb, alpha, ok := modernColorArgs(expr.Value, [3]float64{0, 100, 100})
+	if !ok {
+		return nil
+	}
+	color := NewColor(hwbToRGB(hwb[0], hwb[1]/100, hwb[2]/100), alpha, "rgb")
+	color.written = NewCall("hwb", []any{expr}, 0, nil)
+	return color
+}
+
+// hwbToRGB converts a hue in degrees, whiteness and blackness from 0 to 1 to
+// sRGB channels from 0 to 255
+func hwbToRGB(h, w, b float64) []float64 {
+	w, b = clampUnit(w), clampUnit(b)
+	if w+b >= 1 {
+		gray := w / (w + b) * 255
+		return []float64{gray, gray, gray}
+	}
+	hue := colorHSLA(math.Mod(math.Mod(h, 360)+360, 360), 1, 0.5, 1)
+	rgb := make([]float64, 3)
+	for i, v := range hue.RGB {
+		rgb[i] = v*(1-w-b) + w*255
+	}
+	return rgb
+}
+
+// colorFromSpace creates a color from the Expression of color() of CSS Color 4,
+// e.g. color(display-p3 1 0.5 0), or returns nil if the channels are not
+// constant; ok is false if the color space is not one of color()
+func colorFromSpace(expr *Expression) (color any, ok bool) {
+	if len(expr.Value) == 0 {
+		return nil, false
+	}
+	kw, isKeyword := expr.Value[0].(*Keyword)
+	if !isKeyword {
+		return nil, false
+	}
+	space := strings.ToLower(kw.value)
+	switch space {
+	case "oklab", "oklch", "lab", "lch":
+		return nil, false
+	}
+	cs, known := colorSpaces[space]
+	if !known {
+		return nil, false
+	}
+	coords, alpha, constant := modernColorArgs(expr.Value[1:], cs.percents)
+	if !constant {
+		return nil, true
+	}
+	c := NewColorInSpace(space, coords[:], alpha)
+	c.written = NewCall("color", []any{expr}, 0, nil)
+	return c, true
+}
+
 // ColorHSV creates a color from HSV values
 func ColorHSV(h, s, v any) any {
 	return ColorHSVA(h, s, v, 1.0)
@@ -490,6 +589,17 @@ func ColorFunction(colorStr any) any {
 		return result
 	}
 
+	// color(display-p3 1 0 0) of CSS Color 4, output as written in the relative
+	// color syntax, color(from red display-p3 r g b)
+	if expr, ok := colorStr.(*Expression); ok {
+		if isRelativeColor([]any{expr}) {
+			return nil
+		}
+		if color, ok := colorFromSpace(expr); ok {
+			return color
+		}
+	}
+
 	// Get the string value
 	var str string
 	if quoted, ok := colorStr.(*Quoted); ok {
@@ -1038,6 +1148,160 @@ func ColorContrast(color, dark, light, threshold any) any {
 	return darkColor
 }
 
+// ColorContrastRatio returns the WCAG 2.x contrast ratio of two colors, from 1
+// to 21, e.g. 4.5 is the minimum of AA for normal text. A translucent first
+// color is blended over the second.
+func ColorContrastRatio(color1, color2 any) any {
+	a, b := toColor(color1), toColor(color2)
+	if a == nil || b == nil {
+		return &LessError{
+			Type:    "Argument",
+			Message: "Argument cannot be evaluated to a color",
+		}
+	}
+	return makeDimension(contrastRatio(a, b), nil)
+}
+
+// ColorAPCAContrast returns the APCA lightness contrast Lc of text on a
+// background (APCA 0.0.98G-4g): about 106 for black on white, -108 for white
+// on black, 0 for colors too close to read
+func ColorAPCAContrast(text, background any) any {
+	txt, bg := toColor(text), toColor(background)
+	if txt == nil || bg == nil {
+		return &LessError{
+			Type:    "Argument",
+			Message: "Argument cannot be evaluated to a color",
+		}
+	}
+	return makeDimension(apcaContrast(blendOver(txt, bg), bg), nil)
+}
+
+// ColorEnsureContrast returns color with its lightness changed as little as
+// needed for a WCAG contrast ratio of at least ratio, 4.5 by default, against
+// background. If no lightness reaches it, the one with the highest contrast is
+// used.
+func ColorEnsureContrast(color, background, ratio any) any {
+	c, bg := toColor(color), toColor(background)
+	if c == nil || bg == nil {
+		return &LessError{
+			Type:    "Argument",
+			Message: "Argument cannot be evaluated to a color",
+		}
+	}
+	target := 4.5
+	if ratio != nil {
+		dim, ok := ratio.(*Dimension)
+		if !ok {
+			return &LessError{
+				Type:    "Argument",
+				Message: "ensure-contrast takes a contrast ratio as third argument, e.g. 4.5",
+			}
+		}
+		target = dim.Value
+	}
+	if contrastRatio(c, bg) >= target {
+		return c
+	}
+
+	// Luminance grows with lightness, so the lightness closest to that of the
+	// color reaching the ratio is found by bisection, darker and lighter
+	hsl := c.ToHSL()
+	ratioAt := func(l float64) float64 {
+		candidate := colorHSLA(hsl.H, hsl.S, l, hsl.A)
+		for i, v := range candidate.RGB {
+			candidate.RGB[i] = math.Round(v)
+		}
+		return contrastRatio(candidate, bg)
+	}
+	bisect := func(from, to float64) float64 {
+		for i := 0; i < 30; i++ {
+			mid := (from + to) / 2
+			if ratioAt(mid) >= target {
+				to = mid
+			} else {
+				from = mid
+			}
+		}
+		return to
+	}
+	best := -1.0
+	if ratioAt(0) >= target {
+		best = bisect(hsl.L, 0)
+	}
+	if ratioAt(1) >= target {
+		if lighter := bisect(hsl.L, 1); best < 0 || lighter-hsl.L < hsl.L-best {
+			best = lighter
+		}
+	}
+	if best < 0 {
+		best = 0
+		if ratioAt(1) > ratioAt(0) {
+			best = 1
+		}
+	}
+	return hslaHelper(c, hsl.H, hsl.S, best, hsl.A)
+}
+
+// contrastRatio is the WCAG 2.x contrast ratio of a color, blended over the
+// background if translucent, and the background
+func contrastRatio(color, background *Color) float64 {
+	l1, l2 := blendOver(color, background).Luma(), background.Luma()
+	if l1 < l2 {
+		l1, l2 = l2, l1
+	}
+	return (l1 + 0.05) / (l2 + 0.05)
+}
+
+// blendOver returns a translucent color composited over the background
+func blendOver(color, background *Color) *Color {
+	if color.Alpha >= 1 || len(color.RGB) < 3 || len(background.RGB) < 3 {
+		return color
+	}
+	rgb := make([]float64, 3)
+	for i := range rgb {
+		rgb[i] = color.RGB[i]*color.Alpha + background.RGB[i]*(1-color.Alpha)
+	}
+	return NewColor(rgb, background.Alpha, "")
+}
+
+// apcaContrast is the lightness contrast Lc of APCA 0.0.98G-4g
+func apcaContrast(text, background *Color) float64 {
+	const (
+		blkThrs, blkClmp  = 0.022, 1.414
+		normBG, normTXT   = 0.56, 0.57
+		revTXT, revBG     = 0.62, 0.65
+		scale, loOffset   = 1.14, 0.027
+		loClip, deltaYmin = 0.1, 0.0005
+	)
+	y := func(c *Color) float64 {
+		if len(c.RGB) < 3 {
+			return 0
+		}
+		v := 0.2126729*math.Pow(c.RGB[0]/255, 2.4) + 0.7151522*math.Pow(c.RGB[1]/255, 2.4) + 0.0721750*math.Pow(c.RGB[2]/255, 2.4)
+		if v < blkThrs {
+			v += math.Pow(blkThrs-v, blkClmp)
+		}
+		return v
+	}
+	yText, yBG := y(text), y(background)
+	if math.Abs(yBG-yText) < deltaYmin {
+		return 0
+	}
+	if yBG > yText {
+		// Dark text on a light background
+		sapc := (math.Pow(yBG, normBG) - math.Pow(yText, normTXT)) * scale
+		if sapc < loClip {
+			return 0
+		}
+		return (sapc - loOffset) * 100
+	}
+	sapc := (math.Pow(yBG, revBG) - math.Pow(yText, revTXT)) * scale
+	if sapc > -loClip {
+		return 0
+	}
+	return (sapc + loOffset) * 100
+}
+
 // ColorTint mixes with white
 func ColorTint(color, amount any) any {
 	white := NewColor([]float64{255, 255, 255}, 1.0, "")
@@ -1062,6 +1326,11 @@ func GetColorFunctions() map[string]any {
 		"hsva":   ColorHSVA,
 		"argb":   ColorARGB,
 		"color":  ColorFunction,
+		"oklch":  ColorOKLCH,
+		"oklab":  ColorOKLab,
+		"lab":    ColorLab,
+		"lch":    ColorLCH,
+		"hwb":    ColorHWB,
 		
 		// Channel extraction
 		"red":           ColorRed,
@@ -1092,6 +1361,11 @@ func GetColorFunctions() map[string]any {
 		"contrast":   ColorContrast,
 		"tint":       ColorTint,
 		"shade":      ColorShade,
+
+		// Accessibility
+		"contrast-ratio":  ColorContrastRatio,
+		"apca-contrast":   ColorAPCAContrast,
+		"ensure-contrast": ColorEnsureContrast,
 	}
 }
 
@@ -1206,6 +1480,14 @@ func (w *ColorFunctionWrapper) Call(args ...any) (any, error) {
 			}
 		}
 		return nil, fmt.Errorf("function %s expects 1 argument, got %d", w.name, len(args))
+	case "oklch", "oklab", "lab", "lch", "hwb":
+		// Other forms, e.g. with commas, are output as written
+		if len(args) == 1 {
+			if fn, ok := w.fn.(func(any) any); ok {
+				return fn(args[0]), nil
+			}
+		}
+		return nil, nil
 	case "red", "green", "blue", "alpha", "hue", "saturation", "lightness", 
 	     "hsvhue", "hsvsaturation", "hsvvalue", "luma", "luminance":
 		if len(args) == 1 {
@@ -1312,6 +1594,17 @@ func (w *ColorFunctionWrapper) Call(args ...any) (any, error) {
 			}
 		}
 		return nil, fmt.Errorf("function %s expects 1-4 arguments, got %d", w.name, len(args))
+	case "ensure-contrast":
+		if len(args) >= 2 && len(args) <= 3 {
+			if fn, ok := w.fn.(func(any, any, any) any); ok {
+				var ratio any
+				if len(args) == 3 {
+					ratio = args[2]
+				}
+				return checkLessError(fn(args[0], args[1], ratio))
+			}
+		}
+		return nil, fmt.Errorf("function %s expects 2 or 3 arguments, got %d", w.name, len(args))
 	case "tint", "shade":
 		if len(args) == 2 {
 			if fn, ok := w.fn.(func(any, any) any); ok {
@@ -0,0 +1,422 @@
+package less_go
+
+import (
+	"math"
+	"strings"
+)
+
+// colorSpace is a color space of CSS Color 4 that a Color can be written in,
+// with the conversions of its coordinates to and from CIE XYZ with a D65 white
+// point. Coordinates are CSS numbers, e.g. 0 to 100 for the lightness of lab()
+// but 0 to 1 for that of oklch().
+type colorSpace struct {
+	// percents are the values of 100% for each channel, 0 for a hue
+	percents [3]float64
+	toXYZ    func(c [3]float64) [3]float64
+	fromXYZ  func(xyz [3]float64) [3]float64
+}
+
+// colorSpaces are the color spaces of the oklab(), oklch(), lab() and lch()
+// functions, and of color() as color(display-p3 1 0 0)
+var colorSpaces = map[string]*colorSpace{
+	"oklab": {percents: [3]float64{1, 0.4, 0.4}, toXYZ: oklabToXYZ, fromXYZ: xyzToOKLab},
+	"oklch": {
+		percents: [3]float64{1, 0.4, 0},
+		toXYZ:    func(c [3]float64) [3]float64 { return oklabToXYZ(lchToLab(c)) },
+		fromXYZ:  func(xyz [3]float64) [3]float64 { return labToLCH(xyzToOKLab(xyz)) },
+	},
+	"lab": {percents: [3]float64{100, 125, 125}, toXYZ: labToXYZ, fromXYZ: xyzToLab},
+	"lch": {
+		percents: [3]float64{100, 150, 0},
+		toXYZ:    func(c [3]float64) [3]float64 { return labToXYZ(lchToLab(c)) },
+		fromXYZ:  func(xyz [3]float64) [3]float64 { return labToLCH(xyzToLab(xyz)) },
+	},
+	"srgb": {
+		percents: [3]float64{1, 1, 1},
+		toXYZ:    func(c [3]float64) [3]float64 { return mulMatrix(linearSRGBToXYZ, linearize(c)) },
+		fromXYZ:  func(xyz [3]float64) [3]float64 { return gammaEncode(mulMatrix(xyzToLinearSRGB, xyz)) },
+	},
+	"srgb-linear": {
+		percents: [3]float64{1, 1, 1},
+		toXYZ:    func(c [3]float64) [3]float64 { return mulMatrix(linearSRGBToXYZ, c) },
+		fromXYZ:  func(xyz [3]float64) [3]float64 { return mulMatrix(xyzToLinearSRGB, xyz) },
+	},
+	"display-p3": {
+		percents: [3]float64{1, 1, 1},
+		toXYZ:    func(c [3]float64) [3]float64 { return mulMatrix(linearP3ToXYZ, linearize(c)) },
+		fromXYZ:  func(xyz [3]float64) [3]float64 { return gammaEncode(mulMatrix(xyzToLinearP3, xyz)) },
+	},
+	"xyz-d65": {
+		percents: [3]float64{1, 1, 1},
+		toXYZ:    func(c [3]float64) [3]float64 { return c },
+		fromXYZ:  func(xyz [3]float64) [3]float64 { return xyz },
+	},
+	"xyz-d50": {
+		percents: [3]float64{1, 1, 1},
+		toXYZ:    func(c [3]float64) [3]float64 { return mulMatrix(d50ToD65, c) },
+		fromXYZ:  func(xyz [3]float64) [3]float64 { return mulMatrix(d65ToD50, xyz) },
+	},
+}
+
+func init() {
+	colorSpaces["xyz"] = colorSpaces["xyz-d65"]
+}
+
+// NewColorInSpace returns a Color written in a color space of CSS Color 4:
+// "oklch", "oklab", "lab", "lch", or one of color(), such as "display-p3",
+// "srgb", "srgb-linear", "xyz-d65" or "xyz-d50". coords are the three CSS
+// numbers of the color in that space. The color keeps them for its output, and
+// its RGB is the color mapped into the sRGB gamut, on which the color functions
+// work. It returns nil if the space is not known.
+func NewColorInSpace(space string, coords []float64, alpha float64) *Color {
+	cs, ok := colorSpaces[space]
+	if !ok || len(coords) != 3 {
+		return nil
+	}
+	c := NewColor(gamutMapSRGB(cs.toXYZ([3]float64{coords[0], coords[1], coords[2]})), alpha, "")
+	c.Space = space
+	c.Coords = append([]float64(nil), coords...)
+	return c
+}
+
+// spaceCSS returns the color in its color space, as oklch(0.7 0.1 200 / 0.5)
+func (c *Color) spaceCSS(context any) string {
+	parts := make([]string, 0, 5)
+	for _, v := range c.Coords {
+		parts = append(parts, formatNumberForCSS(c.Fround(context, v)))
+	}
+	if alpha := c.Fround(context, c.Alpha); alpha < 1 {
+		parts = append(parts, "/", formatNumberForCSS(clamp(alpha, 1)))
+	}
+	switch c.Space {
+	case "oklab", "oklch", "lab", "lch":
+		return c.Space + "(" + strings.Join(parts, " ") + ")"
+	}
+	return "color(" + c.Space + " " + strings.Join(parts, " ") + ")"
+}
+
+// xyz returns the color in CIE XYZ with a D65 white point, from its coordinates
+// if it has a color space, or from its sRGB channels
+func (c *Color) xyz() [3]float64 {
+	if cs, ok := colorSpaces[c.Space]; ok && len(c.Coords) == 3 {
+		return cs.toXYZ([3]float64{c.Coords[0], c.Coords[1], c.Coords[2]})
+	}
+	rgb := c.GetRGB()
+	if len(rgb) < 3 {
+		return [3]float64{}
+	}
+	return colorSpaces["srgb"].toXYZ([3]float64{rgb[0] / 255, rgb[1] / 255, rgb[2] / 255})
+}
+
+// modernColorArgs reads the channels of a color function of CSS Color 4,
+// written as a space-separated list with an optional "/ alpha", such as the
+// values of the Expression of oklch(70% 0.1 200 / 50%). percents are the values
+// of 100% for each channel, 0 for a hue, which may be an angle. "none" is read
+// as 0. ok is false if the channels are not constant numbers, e.g. with var()
+// or the relative color syntax, so that the function is output as written.
+func modernColorArgs(values []any, percents [3]float64) (channels [3]float64, alpha float64, ok bool) {
+	if len(values) != 3 {
+		return channels, 0, false
+	}
+	values = append([]any(nil), values...)
+	alpha = 1
+	if op, isOp := values[2].(*Operation); isOp {
+		if op.Op != "/" || len(op.Operands) != 2 {
+			return channels, 0, false
+		}
+		values[2] = op.Operands[0]
+		a, isNumber := colorChannel(op.Operands[1], 1, false)
+		if !isNumber {
+			return channels, 0, false
+		}
+		alpha = clampUnit(a)
+	}
+	for i, v := range values {
+		channel, isNumber := colorChannel(v, percents[i], percents[i] == 0)
+		if !isNumber {
+			return channels, 0, false
+		}
+		channels[i] = channel
+	}
+	return channels, alpha, true
+}
+
+// colorChannel reads a channel, a number, a percentage of hundred, an angle in
+// degrees if hue, or none
+func colorChannel(v any, hundred float64, hue bool) (float64, bool) {
+	if kw, ok := v.(*Keyword); ok && strings.EqualFold(kw.value, "none") {
+		return 0, true
+	}
+	dim, ok := v.(*Dimension)
+	if !ok {
+		return 0, false
+	}
+	unit := ""
+	if dim.Unit != nil {
+		unit = dim.Unit.ToString()
+	}
+	switch {
+	case unit == "":
+		return dim.Value, true
+	case unit == "%" && !hue:
+		return dim.Value * hundred / 100, true
+	case hue:
+		switch unit {
+		case "deg":
+			return dim.Value, true
+		case "rad":
+			return dim.Value * 180 / math.Pi, true
+		case "grad":
+			return dim.Value * 0.9, true
+		case "turn":
+			return dim.Value * 360, true
+		}
+	}
+	return 0, false
+}
+
+// gamutMapSRGB converts a color in XYZ to sRGB channels from 0 to 255. A color
+// out of the sRGB gamut is brought into it by lowering its OKLCH chroma until
+// clipping it is no longer noticeable, as CSS Color 4 specifies, so that it
+// keeps its lightness and hue.
+func gamutMapSRGB(xyz [3]float64) []float64 {
+	toSRGB := colorSpaces["srgb"].fromXYZ
+	rgb := toSRGB(xyz)
+	if inUnitGamut(rgb) {
+		return scaleRGB(rgb)
+	}
+
+	origin := labToLCH(xyzToOKLab(xyz))
+	if origin[0] >= 1 {
+		return []float64{255, 255, 255}
+	}
+	if origin[0] <= 0 {
+		return []float64{0, 0, 0}
+	}
+
+	const jnd, epsilon = 0.02, 0.0001
+	current := origin
+	clipped := clipUnit(rgb)
+	if deltaEOK(clipped, current) < jnd {
+		return scaleRGB(clipped)
+	}
+	low, high, lowInGamut := 0.0, origin[1], true
+	for high-low > epsilon {
+		current[1] = (low + high) / 2
+		candidate := toSRGB(oklabToXYZ(lchToLab(current)))
+		if lowInGamut && inUnitGamut(candidate) {
+			low = current[1]
+			continue
+		}
+		clipped = clipUnit(candidate)
+		e := deltaEOK(clipped, current)
+		if e < jnd {
+			if jnd-e < epsilon {
+				break
+			}
+			lowInGamut = false
+			low = current[1]
+		} else {
+			high = current[1]
+		}
+	}
+	return scaleRGB(clipped)
+}
+
+// deltaEOK is the distance between an sRGB color and an OKLCH one in OKLab
+func deltaEOK(rgb [3]float64, oklch [3]float64) float64 {
+	a := xyzToOKLab(colorSpaces["srgb"].toXYZ(rgb))
+	b := lchToLab(oklch)
+	return math.Sqrt((a[0]-b[0])*(a[0]-b[0]) + (a[1]-b[1])*(a[1]-b[1]) + (a[2]-b[2])*(a[2]-b[2]))
+}
+
+func inUnitGamut(rgb [3]float64) bool {
+	const e = 1e-6
+	for _, v := range rgb {
+		if v < -e || v > 1+e {
+			return false
+		}
+	}
+	return true
+}
+
+func clipUnit(rgb [3]float64) [3]float64 {
+	return [3]float64{clampUnit(rgb[0]), clampUnit(rgb[1]), clampUnit(rgb[2])}
+}
+
+// scaleRGB scales sRGB channels to 0 to 255, rounded to remove the noise of
+// the conversions, so that e.g. the white of oklch(100% 0 0) has no hue
+func scaleRGB(rgb [3]float64) []float64 {
+	rgb = clipUnit(rgb)
+	scaled := make([]float64, 3)
+	for i, v := range rgb {
+		scaled[i] = math.Round(v*255*1e6) / 1e6
+	}
+	return scaled
+}
+
+// The conversions below are those of the sample code of CSS Color 4
+
+var (
+	linearSRGBToXYZ = [3][3]float64{
+		{0.41239079926595934, 0.357584339383878, 0.1804807884018343},
+		{0.21263900587151027, 0.715168678767756, 0.07219231536073371},
+		{0.01933081871559182, 0.11919477979462598, 0.9505321522496607},
+	}
+	xyzToLinearSRGB = [3][3]float64{
+		{3.2409699419045226, -1.537383177570094, -0.4986107602930034},
+		{-0.9692436362808796, 1.8759675015077202, 0.04155505740717559},
+		{0.05563007969699366, -0.20397695888897652, 1.0569715142428786},
+	}
+	linearP3ToXYZ = [3][3]float64{
+		{0.4865709486482162, 0.26566769316909306, 0.1982172852343625},
+		{0.2289745640697488, 0.6917385218365064, 0.079286914093745},
+		{0, 0.04511338185890264, 1.043944368900976},
+	}
+	xyzToLinearP3 = [3][3]float64{
+		{2.493496911941425, -0.9313836179191239, -0.40271078445071684},
+		{-0.8294889695615747, 1.7626640603183463, 0.023624685841943577},
+		{0.03584583024378447, -0.07617238926804182, 0.9568845240076872},
+	}
+	d65ToD50 = [3][3]float64{
+		{1.0479297925449969, 0.022946870601609652, -0.05019226628920524},
+		{0.02962780877005599, 0.9904344267538799, -0.017073799063418826},
+		{-0.009243040646204504, 0.015055191490298152, 0.7518742814281371},
+	}
+	d50ToD65 = [3][3]float64{
+		{0.955473421488075, -0.02309845494876471, 0.06325924320057072},
+		{-0.0283697093338637, 1.0099953980813041, 0.021041441191917323},
+		{0.012314014864481998, -0.020507649298898964, 1.330365926242124},
+	}
+	xyzToLMS = [3][3]float64{
+		{0.8190224379967030, 0.3619062600528904, -0.1288737815209879},
+		{0.0329836539323885, 0.9292868615863434, 0.0361446663506424},
+		{0.0481771893596242, 0.2642395317527308, 0.6335478284694309},
+	}
+	lmsToOKLab = [3][3]float64{
+		{0.2104542683093140, 0.7936177747023054, -0.0040720430116193},
+		{1.9779985324311684, -2.4285922420485799, 0.4505937096174110},
+		{0.0259040424655478, 0.7827717124575296, -0.8086757549230774},
+	}
+	okLabToLMS = [3][3]float64{
+		{1, 0.3963377773761749, 0.2158037573099136},
+		{1, -0.1055613458156586, -0.0638541728258133},
+		{1, -0.0894841775298119, -1.2914855480194092},
+	}
+	lmsToXYZ = [3][3]float64{
+		{1.2268798758459243, -0.5578149944602171, 0.2813910456659647},
+		{-0.0405757452148008, 1.1122868032803170, -0.0717110580655164},
+		{-0.0763729366746601, -0.4214933324022432, 1.5869240198367816},
+	}
+	// d50White is the D50 white point of CIE Lab
+	d50White = [3]float64{0.3457 / 0.3585, 1, (1 - 0.3457 - 0.3585) / 0.3585}
+)
+
+func mulMatrix(m [3][3]float64, v [3]float64) [3]float64 {
+	var r [3]float64
+	for i := range m {
+		r[i] = m[i][0]*v[0] + m[i][1]*v[1] + m[i][2]*v[2]
+	}
+	return r
+}
+
+// linearize removes the sRGB transfer function, which display-p3 shares
+func linearize(c [3]float64) [3]float64 {
+	for i, v := range c {
+		abs := math.Abs(v)
+		if abs <= 0.04045 {
+			c[i] = v / 12.92
+		} else {
+			c[i] = math.Copysign(math.Pow((abs+0.055)/1.055, 2.4), v)
+		}
+	}
+	return c
+}
+
+// gammaEncode applies the sRGB transfer function
+func gammaEncode(c [3]float64) [3]float64 {
+	for i, v := range c {
+		abs := math.Abs(v)
+		if abs > 0.0031308 {
+			c[i] = math.Copysign(1.055*math.Pow(abs, 1/2.4)-0.055, v)
+		} else {
+			c[i] = 12.92 * v
+		}
+	}
+	return c
+}
+
+func oklabToXYZ(lab [3]float64) [3]float64 {
+	lms := mulMatrix(okLabToLMS, lab)
+	for i, v := range lms {
+		lms[i] = v * v * v
+	}
+	return mulMatrix(lmsToXYZ, lms)
+}
+
+func xyzToOKLab(xyz [3]float64) [3]float64 {
+	lms := mulMatrix(xyzToLMS, xyz)
+	for i, v := range lms {
+		lms[i] = math.Cbrt(v)
+	}
+	return mulMatrix(lmsToOKLab, lms)
+}
+
+const (
+	labKappa   = 24389.0 / 27
+	labEpsilon = 216.0 / 24389
+)
+
+func labToXYZ(lab [3]float64) [3]float64 {
+	f1 := (lab[0] + 16) / 116
+	f0 := lab[1]/500 + f1
+	f2 := f1 - lab[2]/200
+	var xyz [3]float64
+	if f0*f0*f0 > labEpsilon {
+		xyz[0] = f0 * f0 * f0
+	} else {
+		xyz[0] = (116*f0 - 16) / labKappa
+	}
+	if lab[0] > labKappa*labEpsilon {
+		xyz[1] = f1 * f1 * f1
+	} else {
+		xyz[1] = lab[0] / labKappa
+	}
+	if f2*f2*f2 > labEpsilon {
+		xyz[2] = f2 * f2 * f2
+	} else {
+		xyz[2] = (116*f2 - 16) / labKappa
+	}
+	for i := range xyz {
+		xyz[i] *= d50White[i]
+	}
+	return mulMatrix(d50ToD65, xyz)
+}
+
+func xyzToLab(xyz [3]float64) [3]float64 {
+	xyz = mulMatrix(d65ToD50, xyz)
+	var f [3]float64
+	for i, v := range xyz {
+		v /= d50White[i]
+		if v > labEpsilon {
+			f[i] = math.Cbrt(v)
+		} else {
+			f[i] = (labKappa*v + 16) / 116
+		}
+	}
+	return [3]float64{116*f[1] - 16, 500 * (f[0] - f[1]), 200 * (f[1] - f[2])}
+}
+
+// lchToLab converts the polar form of Lab or OKLab, with the hue in degrees
+func lchToLab(lch [3]float64) [3]float64 {
+	h := lch[2] * math.Pi / 180
+	return [3]float64{lch[0], lch[1] * math.Cos(h), lch[1] * math.Sin(h)}
+}
+
+func labToLCH(lab [3]float64) [3]float64 {
+	h := math.Atan2(lab[2], lab[1]) * 180 / math.Pi
+	if h < 0 {
+		h += 360
+	}
+	return [3]float64{lab[0], math.Hypot(lab[1], lab[2]), h}
+}
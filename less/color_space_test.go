@@ -0,0 +1,123 @@
+package less_go
+
+import (
+	"math"
+	"strings"
+	"testing"
+)
+
+func TestNewColorInSpace(t *testing.T) {
+	// The same red, #ff0000, in each color space
+	tests := []struct {
+		space  string
+		coords []float64
+	}{
+		{"oklch", []float64{0.62796, 0.25768, 29.2339}},
+		{"oklab", []float64{0.62796, 0.22486, 0.12585}},
+		{"lab", []float64{54.2905, 80.8049, 69.8910}},
+		{"lch", []float64{54.2905, 106.8390, 40.8526}},
+		{"display-p3", []float64{0.91749, 0.20029, 0.13856}},
+		{"srgb", []float64{1, 0, 0}},
+		{"srgb-linear", []float64{1, 0, 0}},
+		{"xyz", []float64{0.41239, 0.21264, 0.01933}},
+		{"xyz-d50", []float64{0.43607, 0.22249, 0.01392}},
+	}
+	for _, tt := range tests {
+		c := NewColorInSpace(tt.space, tt.coords, 1)
+		if c == nil {
+			t.Fatalf("%s: unknown color space", tt.space)
+		}
+		if got := c.ToRGB(); got != "#ff0000" {
+			t.Errorf("%s%v: got %s, want #ff0000", tt.space, tt.coords, got)
+		}
+		back := colorSpaces[tt.space].fromXYZ(c.xyz())
+		for i := range back {
+			if math.Abs(back[i]-tt.coords[i]) > 1e-6*math.Max(1, math.Abs(tt.coords[i])) {
+				t.Errorf("%s: round trip through XYZ gave %v, want %v", tt.space, back, tt.coords)
+				break
+			}
+		}
+	}
+	if NewColorInSpace("cmyk", []float64{0, 0, 0}, 1) != nil {
+		t.Error("expected nil for an unknown color space")
+	}
+}
+
+func TestGamutMapSRGB(t *testing.T) {
+	for _, coords := range [][3]float64{{0.9, 0.3, 140}, {0.5, 0.4, 270}, {0.7, 0.35, 30}} {
+		rgb := gamutMapSRGB(colorSpaces["oklch"].toXYZ(coords))
+		mapped := colorSpaces["oklch"].fromXYZ(colorSpaces["srgb"].toXYZ([3]float64{rgb[0] / 255, rgb[1] / 255, rgb[2] / 255}))
+		// Chroma is reduced, lightness and hue are kept within what clipping changes
+		if mapped[1] >= coords[1] || math.Abs(mapped[0]-coords[0]) > 0.02 || math.Abs(mapped[2]-coords[2]) > 3 {
+			t.Errorf("oklch%v: mapped to oklch%v", coords, mapped)
+		}
+	}
+
+	// Colors lighter than white or darker than black map to them
+	if got := gamutMapSRGB(colorSpaces["oklch"].toXYZ([3]float64{1.2, 0.1, 100})); got[0] != 255 || got[2] != 255 {
+		t.Errorf("expected white, got %v", got)
+	}
+	// Colors in gamut are not changed
+	if got := toHex(gamutMapSRGB(colorSpaces["display-p3"].toXYZ([3]float64{0.5, 0.5, 0.5}))); got != "#808080" {
+		t.Errorf("expected #808080, got %s", got)
+	}
+}
+
+func TestCompile_ModernColors(t *testing.T) {
+	input := `@brand: oklch(62.8% 0.2577 29.23);
+.a {
+  oklch: @brand;
+  alpha: oklch(70% 0.1 200 / 50%);
+  angle: oklch(0.7 0.1 0.5turn);
+  lab: lab(50% 40 -20);
+  lch: lch(50% 60 300deg);
+  oklab: oklab(0.7 -0.05 0.1);
+  p3: color(display-p3 1 0 0);
+  none: oklch(0.7 none 200);
+  hwb: hwb(120 0% 0%);
+  hwb-gray: lighten(hwb(0 60% 60%), 0%);
+  lighten: lighten(@brand, 10%);
+  darken: darken(oklch(100% 0 0), 20%);
+  spin: spin(@brand, 120);
+  mix: mix(@brand, #0000ff, 50%);
+  fade: fade(color(display-p3 0 1 0), 50%);
+  contrast: contrast(oklch(20% 0.05 250));
+  multiply: multiply(oklch(100% 0 0), #336699);
+  relative: oklch(from #0000FF calc(l - 0.1) c h);
+  var: oklch(var(--l) 0.1 200);
+  commas: lab(1, 2, 3);
+  p3-var: color(display-p3 var(--r) 0 0);
+}
+`
+	result, err := Compile(input, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{
+		"oklch: oklch(62.8% 0.2577 29.23);",
+		"alpha: oklch(70% 0.1 200 / 50%);",
+		"angle: oklch(0.7 0.1 0.5turn);",
+		"lab: lab(50% 40 -20);",
+		"lch: lch(50% 60 300deg);",
+		"oklab: oklab(0.7 -0.05 0.1);",
+		"p3: color(display-p3 1 0 0);",
+		"none: oklch(0.7 none 200);",
+		"hwb: hwb(120 0% 0%);",
+		"hwb-gray: #808080;",
+		"lighten: #ff3333;",
+		"darken: #cccccc;",
+		"spin: #00ff00;",
+		"mix: #800080;",
+		"fade: rgba(0, 251, 41, 0.5);",
+		"contrast: #ffffff;",
+		"multiply: #336699;",
+		"relative: oklch(from #0000FF calc(l - 0.1) c h);",
+		"var: oklch(var(--l) 0.1 200);",
+		"commas: lab(1, 2, 3);",
+		"p3-var: color(display-p3 var(--r) 0 0);",
+	} {
+		if !strings.Contains(result.CSS, want) {
+			t.Errorf("expected %q in:\n%s", want, result.CSS)
+		}
+	}
+}
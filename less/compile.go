@@ -1,17 +1,27 @@
 package less_go
 
 import (
+	"context"
 	"fmt"
+	"io"
+	"io/fs"
 	"os"
 	"path/filepath"
-	"runtime"
-	"strings"
+	"runtime/debug"
 )
 
 type CompileResult struct {
 	CSS     string   `json:"css"`
 	Map     string   `json:"map,omitempty"`
 	Imports []string `json:"imports,omitempty"`
+
+	// Dependencies is the full graph of files the compilation read: imports with
+	// their options, @plugin scripts and data-uri / image-size assets, each with
+	// the file that referenced it
+	Dependencies *DependencyGraph `json:"dependencies,omitempty"`
+
+	// Warnings are the diagnostics reported while compiling, in order
+	Warnings []Diagnostic `json:"warnings,omitempty"`
 }
 
 // PluginSpec specifies a plugin to load before compilation
@@ -76,6 +86,80 @@ type CompileOptions struct {
 
 	// SourceMapOptions contains detailed source map configuration
 	SourceMapOptions *SourceMapOptions
+
+	// FS is a virtual file system (e.g. an embed.FS or fstest.MapFS) used instead of
+	// the OS file system for @import, node_modules resolution, data-uri and image-size.
+	// Paths are resolved relative to the FS root; Filename and Paths are interpreted
+	// inside it. Remote (http/https) imports are unaffected.
+	FS fs.FS
+
+	// FileManagers are custom loaders for @import, data-uri and image-size, e.g. to
+	// resolve "db://theme/42" from a database. As in less.js, managers are tried from
+	// last to first, after those added by plugins and before the built-in file system
+	// manager; the first whose Supports (SupportsSync for synchronous loads) returns
+	// true loads the file. A manager without these methods supports every file.
+	FileManagers []FileManager
+
+	// OnDiagnostic, if set, is called with each warning as it is reported, including
+	// those of compilations that fail. Warnings are scoped to the compilation, unlike
+	// the listeners of DefaultLogger; those of imports that a Compiler reuses from
+	// its cache are only reported by the compilation that parsed them.
+	OnDiagnostic func(Diagnostic)
+
+	// Functions are custom Less functions, keyed by name, that are available to the
+	// compilation in addition to the built-in ones. A function with the name of a
+	// built-in replaces it. Use Function to write one as a plain Go func.
+	Functions map[string]FunctionDefinition
+
+	// GoPlugins are plugins written in Go, installed in order before compilation.
+	// They add visitors, pre- and post-processors and file managers like
+	// JavaScript plugins do, but run in-process without Node.js.
+	GoPlugins []GoPlugin
+
+	// CollectErrors makes a compilation go on after an error and return all of
+	// them at once as a *MultiError: the parser skips an invalid statement up to
+	// the next ';' or '}', and evaluation leaves out the rules that fail.
+	// Undefined variables and operations on invalid types, which are otherwise
+	// output as written, are reported too. No CSS is returned if there are errors.
+	CollectErrors bool
+
+	// PanicHandler, if set, is called with the value and Go stack of each panic
+	// of the compiler, or of a custom function or plugin, such as a nil pointer
+	// dereference. Such a panic fails the compilation with a *LessError of Type
+	// "Internal", located at the call or rule it happened in.
+	PanicHandler func(value any, stack []byte)
+
+	// ResolveModernColors, if not ModernColors.Off, adds a fallback before each
+	// declaration that uses color-mix() or relative colors, such as
+	// oklch(from #00f calc(l - 0.1) c h), for browsers that support neither: the
+	// same declaration with these colors computed at compile time. With
+	// ModernColors.SRGBHex, they are written as sRGB hex colors, or rgba() if
+	// translucent. Declarations whose colors are not constant, e.g. use var(),
+	// have no fallback.
+	ResolveModernColors ModernColorsType
+
+	// MinContrastRatio, if set, makes each ruleset whose color has a WCAG
+	// contrast ratio below it against its background-color, or a background of
+	// a single color, report a DiagnosticLowContrast warning, e.g. 4.5 for AA.
+	// An @accessibility directive at the root of the stylesheet, such as
+	// @accessibility AA, sets it for that stylesheet.
+	MinContrastRatio float64
+
+	// importCache is set by Compiler to share parsed imports between compilations
+	importCache *importCache
+
+	// dependencies is set by Compiler to collect the graph even if compilation fails
+	dependencies *DependencyGraph
+
+	// pluginRuntimes is set by Compiler to reuse Node.js processes between compilations
+	pluginRuntimes *pluginRuntimePool
+
+	// onEvaluated is set by ResolveVariables to read the evaluated tree
+	onEvaluated func(root *Ruleset, context *Eval, contents map[string]string)
+
+	// output and mapOutput are set by CompileTo to stream the CSS and source map
+	output    io.Writer
+	mapOutput io.Writer
 }
 
 // SourceMapOptions contains source map generation settings
@@ -124,6 +208,33 @@ type SourceMapOptions struct {
 //	}
 //	fmt.Println(result.CSS)
 func Compile(input string, options *CompileOptions) (*CompileResult, error) {
+	return CompileContext(context.Background(), input, options)
+}
+
+// CompileContext compiles LESS source code to CSS like Compile, but stops as soon
+// as ctx is cancelled or its deadline passes.
+//
+// Cancellation is checked while parsing, before each @import is loaded (including
+// remote imports already in flight), on every Ruleset and mixin call evaluation,
+// and while waiting on JavaScript plugin calls. When ctx is done, ctx.Err() is
+// returned so callers can test for context.Canceled or context.DeadlineExceeded.
+//
+// Example usage:
+//
+//	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+//	defer cancel()
+//	result, err := less_go.CompileContext(ctx, lessSource, nil)
+//	if errors.Is(err, context.DeadlineExceeded) {
+//	    log.Println("compilation took too long")
+//	}
+func CompileContext(ctx context.Context, input string, options *CompileOptions) (*CompileResult, error) {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
 	if options == nil {
 		options = &CompileOptions{}
 	}
@@ -134,6 +245,7 @@ func Compile(input string, options *CompileOptions) (*CompileResult, error) {
 	}
 
 	optionsMap := convertCompileOptionsToMap(options)
+	optionsMap["ctx"] = ctx
 
 	var lessContext *LessContext
 	var cleanup func() error
@@ -149,6 +261,10 @@ func Compile(input string, options *CompileOptions) (*CompileResult, error) {
 			}
 		}()
 
+		if lessContext.PluginBridge != nil {
+			lessContext.PluginBridge.SetCancel(ctx.Done())
+		}
+
 		// Preload plugins specified in options
 		if len(options.Plugins) > 0 && lessContext.PluginBridge != nil {
 			// Determine base directory for plugin resolution
@@ -162,12 +278,16 @@ func Compile(input string, options *CompileOptions) (*CompileResult, error) {
 			}
 
 			for _, plugin := range options.Plugins {
-				context := make(map[string]any)
+				if err := ctx.Err(); err != nil {
+					return nil, err
+				}
+
+				pluginContext := make(map[string]any)
 				if plugin.Options != "" {
-					context["options"] = map[string]any{"_args": plugin.Options}
+					pluginContext["options"] = map[string]any{"_args": plugin.Options}
 				}
 
-				result := lessContext.PluginBridge.LoadPluginSync(plugin.Name, baseDir, context, nil, nil)
+				result := lessContext.PluginBridge.LoadPluginSync(plugin.Name, baseDir, pluginContext, nil, nil)
 				if err, ok := result.(error); ok {
 					return nil, fmt.Errorf("failed to load plugin %q: %w", plugin.Name, err)
 				}
@@ -180,6 +300,11 @@ func Compile(input string, options *CompileOptions) (*CompileResult, error) {
 	lessContext.Functions = createFunctions(nil).(*DefaultFunctions)
 
 	result, err := compileWithContext(lessContext, input, optionsMap)
+	if ctxErr := ctx.Err(); ctxErr != nil {
+		// Cancellation surfaces through many layers as wrapped or stringified
+		// errors; report the context's own error instead.
+		return nil, ctxErr
+	}
 	if err != nil {
 		return nil, err
 	}
@@ -197,7 +322,19 @@ func Compile(input string, options *CompileOptions) (*CompileResult, error) {
 //	    EnableJavaScriptPlugins: true,
 //	})
 func CompileFile(filename string, options *CompileOptions) (*CompileResult, error) {
-	content, err := os.ReadFile(filename)
+	return CompileFileContext(context.Background(), filename, options)
+}
+
+// CompileFileContext compiles a LESS file to CSS like CompileFile, stopping as soon
+// as ctx is cancelled or its deadline passes. See CompileContext for details.
+func CompileFileContext(ctx context.Context, filename string, options *CompileOptions) (*CompileResult, error) {
+	var content []byte
+	var err error
+	if options != nil && options.FS != nil {
+		content, err = fs.ReadFile(options.FS, fsName(filename))
+	} else {
+		content, err = os.ReadFile(filename)
+	}
 	if err != nil {
 		return nil, fmt.Errorf("failed to read file %s: %w", filename, err)
 	}
@@ -207,7 +344,49 @@ func CompileFile(filename string, options *CompileOptions) (*CompileResult, erro
 	}
 	options.Filename = filename
 
-	return Compile(string(content), options)
+	return CompileContext(ctx, string(content), options)
+}
+
+// CompileTo compiles LESS source code like Compile, but writes the CSS to w as
+// it is generated instead of returning it, so that a large stylesheet is never
+// held in memory whole. With source maps enabled, the mappings of the source
+// map are written to mapWriter as the CSS is generated too, and its sources
+// once the CSS is done; if mapWriter is nil, the source map is returned in Map.
+// The result holds the imports, dependencies and warnings.
+//
+// The CSS and source map are buffered and written at the end when plugins add
+// post-processors, which need all of the CSS. An inline source map, which is
+// part of the CSS, is built in memory.
+//
+// Nothing is written if the compilation fails before the CSS is generated, or
+// if CollectErrors found errors. If generating the CSS fails, what was written
+// so far is left in w and mapWriter, which then hold a partial stylesheet and
+// source map: write to temporary files and rename them on success where that
+// matters. Errors writing to w or mapWriter fail the compilation.
+//
+// Example usage:
+//
+//	out, err := os.Create("utilities.css")
+//	if err != nil {
+//	    log.Fatal(err)
+//	}
+//	defer out.Close()
+//	_, err = less_go.CompileTo(out, nil, lessSource, &less_go.CompileOptions{
+//	    Filename: "utilities.less",
+//	})
+func CompileTo(w, mapWriter io.Writer, input string, options *CompileOptions) (*CompileResult, error) {
+	return CompileToContext(context.Background(), w, mapWriter, input, options)
+}
+
+// CompileToContext is like CompileTo but stops as soon as ctx is done. See
+// CompileContext for details.
+func CompileToContext(ctx context.Context, w, mapWriter io.Writer, input string, options *CompileOptions) (*CompileResult, error) {
+	var streamed CompileOptions
+	if options != nil {
+		streamed = *options
+	}
+	streamed.output, streamed.mapOutput = w, mapWriter
+	return CompileContext(ctx, input, &streamed)
 }
 
 func convertCompileOptionsToMap(options *CompileOptions) map[string]any {
@@ -243,6 +422,51 @@ func convertCompileOptionsToMap(options *CompileOptions) map[string]any {
 	if options.ModifyVars != nil {
 		result["modifyVars"] = options.ModifyVars
 	}
+	if options.FS != nil {
+		result["fs"] = options.FS
+	}
+	if len(options.FileManagers) > 0 {
+		result["fileManagers"] = options.FileManagers
+	}
+	if options.importCache != nil {
+		result["importCache"] = options.importCache
+	}
+	if options.dependencies != nil {
+		result["dependencies"] = options.dependencies
+	}
+	if options.OnDiagnostic != nil {
+		result["onDiagnostic"] = options.OnDiagnostic
+	}
+	if options.pluginRuntimes != nil {
+		result["pluginRuntimes"] = options.pluginRuntimes
+	}
+	if len(options.Functions) > 0 {
+		result["customFunctions"] = options.Functions
+	}
+	if len(options.GoPlugins) > 0 {
+		result["goPlugins"] = options.GoPlugins
+	}
+	if options.onEvaluated != nil {
+		result["onEvaluated"] = options.onEvaluated
+	}
+	if options.CollectErrors {
+		result["collectErrors"] = true
+	}
+	if options.PanicHandler != nil {
+		result["panicHandler"] = options.PanicHandler
+	}
+	if options.ResolveModernColors != ModernColors.Off {
+		result["resolveModernColors"] = options.ResolveModernColors
+	}
+	if options.MinContrastRatio > 0 {
+		result["minContrastRatio"] = options.MinContrastRatio
+	}
+	if options.output != nil {
+		result["output"] = options.output
+	}
+	if options.mapOutput != nil {
+		result["mapOutput"] = options.mapOutput
+	}
 	// When EnableJavaScriptPlugins is true, also enable JavascriptEnabled
 	// This ensures inline JavaScript expressions can be evaluated
 	if options.JavascriptEnabled || options.EnableJavaScriptPlugins {
@@ -285,9 +509,35 @@ func convertCompileOptionsToMap(options *CompileOptions) map[string]any {
 func compileWithContext(lessContext *LessContext, input string, options map[string]any) (*CompileResult, error) {
 	env := createEnvironment(nil, nil)
 	pluginManager := NewPluginManager(lessContext)
+	goPlugins, _ := options["goPlugins"].([]GoPlugin)
+	for _, plugin := range goPlugins {
+		if err := plugin.Install(pluginManager); err != nil {
+			return nil, fmt.Errorf("failed to install plugin %T: %w", plugin, err)
+		}
+	}
+
+	fsys, _ := options["fs"].(fs.FS)
+	fileManagers, _ := options["fileManagers"].([]FileManager)
+	dependencies, _ := options["dependencies"].(*DependencyGraph)
+	if dependencies == nil {
+		filename, _ := options["filename"].(string)
+		if filename == "" {
+			filename = "input"
+		}
+		dependencies = NewDependencyGraph(filename)
+	}
+	onDiagnostic, _ := options["onDiagnostic"].(func(Diagnostic))
+	diagnostics := newDiagnosticCollector(onDiagnostic)
+	var errs *errorCollector
+	if collectErrors, _ := options["collectErrors"].(bool); collectErrors {
+		errs = newErrorCollector()
+	}
+	panicHandler, _ := options["panicHandler"].(func(any, []byte))
+	output, _ := options["output"].(io.Writer)
+	mapOutput, _ := options["mapOutput"].(io.Writer)
 
 	parseFunc := CreateParse(env, nil, func(environment any, context *Parse, rootFileInfo map[string]any) *ImportManager {
-		factory := NewImportManager(&SimpleImportManagerEnvironment{})
+		factory := NewImportManager(&SimpleImportManagerEnvironment{FS: fsys, FileManagers: fileManagers})
 
 		fileInfo := &FileInfo{
 			Filename: "input",
@@ -320,6 +570,20 @@ func compileWithContext(lessContext *LessContext, input string, options map[stri
 			contextMap["strictImports"] = context.StrictImports
 			contextMap["insecure"] = context.Insecure
 		}
+		if ctx, ok := options["ctx"]; ok {
+			contextMap["ctx"] = ctx
+		}
+		if cache, ok := options["importCache"]; ok {
+			contextMap["importCache"] = cache
+		}
+		contextMap["dependencies"] = dependencies
+		contextMap["diagnostics"] = diagnostics
+		if errs != nil {
+			contextMap["errors"] = errs
+		}
+		if panicHandler != nil {
+			contextMap["panicHandler"] = panicHandler
+		}
 
 		return factory(environment, contextMap, fileInfo)
 	})
@@ -335,6 +599,10 @@ func compileWithContext(lessContext *LessContext, input string, options map[stri
 	}
 
 	mergedOptions["pluginManager"] = pluginManager
+	mergedOptions["diagnostics"] = diagnostics
+	if errs != nil {
+		mergedOptions["errors"] = errs
+	}
 
 	if lessContext.PluginBridge != nil {
 		mergedOptions["pluginBridge"] = lessContext.PluginBridge
@@ -342,8 +610,10 @@ func compileWithContext(lessContext *LessContext, input string, options map[stri
 
 	var compileResult *CompileResult
 	var compileErr error
+	var importManager *ImportManager
 
 	parseFunc(input, mergedOptions, func(err error, root any, imports *ImportManager, opts map[string]any) {
+		importManager = imports
 		if err != nil {
 			compileErr = err
 			return
@@ -352,22 +622,23 @@ func compileWithContext(lessContext *LessContext, input string, options map[stri
 		func() {
 			defer func() {
 				if r := recover(); r != nil {
-					buf := make([]byte, 4096)
-					n := runtime.Stack(buf, false)
-					stackTrace := string(buf[:n])
-
-					var errMsg string
-					if e, ok := r.(error); ok {
-						errMsg = e.Error()
-					} else {
-						errMsg = fmt.Sprintf("%v", r)
+					// Panics outside of the tree's evaluation and output have no location
+					if isInternalPanic(r) {
+						var contents map[string]string
+						var rootFilename string
+						if imports != nil {
+							contents, rootFilename = imports.Contents(), imports.RootFilename()
+						}
+						r = internalError(r, debug.Stack(), "", nil, contents, rootFilename, panicHandler)
 					}
-
-					if strings.Contains(errMsg, "index out of range") || strings.Contains(errMsg, "nil pointer") {
-						fmt.Fprintf(os.Stderr, "\n=== PANIC in compile ===\nError: %s\nStack:\n%s\n===\n", errMsg, stackTrace)
+					// Less errors are returned as they are, as when ToCSS returns them
+					if lessErr, ok := panicLessError(r, "", nil); ok {
+						compileErr = lessErr
+					} else if e, ok := r.(error); ok {
+						compileErr = fmt.Errorf("compilation failed: %w", e)
+					} else {
+						compileErr = fmt.Errorf("compilation failed: %v", r)
 					}
-
-					compileErr = fmt.Errorf("compilation failed: %s", errMsg)
 				}
 			}()
 
@@ -410,6 +681,12 @@ func compileWithContext(lessContext *LessContext, input string, options map[stri
 			parseTreeInstance := parseTreeFactory.NewParseTree(root, imports)
 
 			functionsObj := createFunctions(env)
+			if custom, ok := opts["customFunctions"].(map[string]FunctionDefinition); ok {
+				registry := functionsObj.(*DefaultFunctions).registry
+				for name, fn := range custom {
+					registry.Add(name, fn)
+				}
+			}
 
 			toCSSOptions := &ToCSSOptions{
 				Compress:       false,
@@ -449,16 +726,36 @@ func compileWithContext(lessContext *LessContext, input string, options map[stri
 				}
 				if pluginBridge := opts["pluginBridge"]; pluginBridge != nil {
 					toCSSOptions.PluginBridge = pluginBridge
-					toCSSOptions.PluginManager = opts["pluginManager"]
+				}
+				toCSSOptions.PluginManager = opts["pluginManager"]
+				if onEvaluated, ok := opts["onEvaluated"].(func(*Ruleset, *Eval, map[string]string)); ok {
+					toCSSOptions.OnEvaluated = onEvaluated
 				}
 				if javascriptEnabled, ok := opts["javascriptEnabled"].(bool); ok {
 					toCSSOptions.JavascriptEnabled = javascriptEnabled
 				}
+				if resolveModernColors, ok := opts["resolveModernColors"].(ModernColorsType); ok {
+					toCSSOptions.ResolveModernColors = resolveModernColors
+				}
+				if minContrastRatio, ok := opts["minContrastRatio"].(float64); ok {
+					toCSSOptions.MinContrastRatio = minContrastRatio
+				}
 				// Pass source map options
 				if sourceMapOpts := opts["sourceMap"]; sourceMapOpts != nil {
 					toCSSOptions.SourceMap = sourceMapOpts
 				}
+				if ctx, ok := opts["ctx"].(context.Context); ok {
+					toCSSOptions.Context = ctx
+				}
+				toCSSOptions.FS = fsys
+				toCSSOptions.FileManagers = fileManagers
 			}
+			toCSSOptions.Dependencies = dependencies
+			toCSSOptions.Diagnostics = diagnostics
+			toCSSOptions.Errors = errs
+			toCSSOptions.PanicHandler = panicHandler
+			toCSSOptions.Output = output
+			toCSSOptions.MapOutput = mapOutput
 
 			cssResult, err := parseTreeInstance.ToCSS(toCSSOptions)
 			if err != nil {
@@ -467,13 +764,25 @@ func compileWithContext(lessContext *LessContext, input string, options map[stri
 			}
 
 			compileResult = &CompileResult{
-				CSS:     cssResult.CSS,
-				Map:     cssResult.Map,
-				Imports: cssResult.Imports,
+				CSS:          cssResult.CSS,
+				Map:          cssResult.Map,
+				Imports:      cssResult.Imports,
+				Dependencies: dependencies,
+				Warnings:     diagnostics.list(),
 			}
 		}()
 	})
 
+	if errs != nil {
+		var contents map[string]string
+		var rootFilename string
+		if importManager != nil {
+			contents, rootFilename = importManager.Contents(), importManager.RootFilename()
+		}
+		if err := errs.result(compileErr, contents, rootFilename); err != nil {
+			return nil, err
+		}
+	}
 	if compileErr != nil {
 		return nil, compileErr
 	}
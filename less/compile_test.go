@@ -1,8 +1,15 @@
 package less_go
 
 import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
 	"strings"
 	"testing"
+	"testing/fstest"
+	"time"
 )
 
 func TestCompile_BasicInput(t *testing.T) {
@@ -151,3 +158,418 @@ func TestCompileOptions_EmptyConversion(t *testing.T) {
 		t.Errorf("Empty options should produce empty map, got %d entries", len(converted))
 	}
 }
+
+func TestCompileContext_AlreadyCancelled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	_, err := CompileContext(ctx, `.test { color: red; }`, nil)
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected context.Canceled, got %v", err)
+	}
+}
+
+func TestCompileContext_DeadlineStopsMixinRecursion(t *testing.T) {
+	input := `.loop(@i) when (@i > 0) { width: @i; .loop(@i - 1); } .a { .loop(100000); }`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+	_, err := CompileContext(ctx, input, nil)
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected context.DeadlineExceeded, got %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("compilation did not stop promptly after the deadline: %v", elapsed)
+	}
+}
+
+func TestCompileContext_DeadlineStopsRemoteImport(t *testing.T) {
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-release:
+		case <-r.Context().Done():
+		}
+	}))
+	defer server.Close()
+	defer close(release)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+	_, err := CompileContext(ctx, `@import "`+server.URL+`/slow.less"; .a { color: red; }`, nil)
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected context.DeadlineExceeded, got %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("remote import was not abandoned after the deadline: %v", elapsed)
+	}
+}
+
+func TestCompileFileContext_MissingFile(t *testing.T) {
+	_, err := CompileFileContext(context.Background(), "does-not-exist.less", nil)
+	if err == nil {
+		t.Fatal("expected an error for a missing file")
+	}
+}
+
+func TestCompileFile_WithFS(t *testing.T) {
+	fsys := fstest.MapFS{
+		"styles/main.less": &fstest.MapFile{Data: []byte(
+			`@import "partials/vars";
+@import "theme/base";
+.logo { background: data-uri("img/logo.svg"); size: image-size("img/logo.svg"); color: @brand; }`)},
+		"styles/partials/vars.less":    &fstest.MapFile{Data: []byte(`@brand: #336699;`)},
+		"node_modules/theme/base.less": &fstest.MapFile{Data: []byte(`.base { margin: 0; }`)},
+		"styles/img/logo.svg": &fstest.MapFile{Data: []byte(
+			`<svg xmlns="http://www.w3.org/2000/svg" width="40" height="20"></svg>`)},
+	}
+
+	result, err := CompileFile("styles/main.less", &CompileOptions{FS: fsys})
+	if err != nil {
+		t.Fatalf("CompileFile with FS failed: %v", err)
+	}
+
+	for _, want := range []string{"color: #336699", ".base", "data:image/svg+xml", "size: 40px 20px"} {
+		if !strings.Contains(result.CSS, want) {
+			t.Errorf("CSS should contain %q: %s", want, result.CSS)
+		}
+	}
+}
+
+func TestCompile_WithFSMissingImport(t *testing.T) {
+	fsys := fstest.MapFS{
+		"main.less": &fstest.MapFile{Data: []byte(`.a { color: red; }`)},
+	}
+
+	_, err := Compile(`@import "missing";`, &CompileOptions{FS: fsys})
+	if err == nil {
+		t.Fatal("expected an error for an import missing from the FS")
+	}
+}
+
+// prefixFileManager serves imports starting with prefix from an in-memory map
+type prefixFileManager struct {
+	*AbstractFileManager
+	prefix string
+	files  map[string]string
+}
+
+func newPrefixFileManager(prefix string, files map[string]string) *prefixFileManager {
+	return &prefixFileManager{AbstractFileManager: NewAbstractFileManager(), prefix: prefix, files: files}
+}
+
+func (m *prefixFileManager) Supports(filename, currentDirectory string, options map[string]any, environment map[string]any) bool {
+	return strings.HasPrefix(filename, m.prefix)
+}
+
+func (m *prefixFileManager) SupportsSync(filename, currentDirectory string, options map[string]any, environment map[string]any) bool {
+	return m.Supports(filename, currentDirectory, options, environment)
+}
+
+func (m *prefixFileManager) LoadFileSync(filename, currentDirectory string, context map[string]any, environment ImportManagerEnvironment) *LoadedFile {
+	contents, ok := m.files[filename]
+	if !ok {
+		return &LoadedFile{Message: "'" + filename + "' wasn't found"}
+	}
+	return &LoadedFile{Filename: filename, Contents: contents}
+}
+
+func (m *prefixFileManager) LoadFile(filename, currentDirectory string, context map[string]any, environment ImportManagerEnvironment, callback func(error, *LoadedFile)) any {
+	result := m.LoadFileSync(filename, currentDirectory, context, environment)
+	if result.Message != "" {
+		callback(&LessError{Message: result.Message}, nil)
+	} else {
+		callback(nil, result)
+	}
+	return nil
+}
+
+func TestCompile_WithFileManagers(t *testing.T) {
+	db := newPrefixFileManager("db://", map[string]string{
+		"db://theme/42":     `@brand: #112233; .theme { color: @brand; }`,
+		"db://img/logo.svg": `<svg xmlns="http://www.w3.org/2000/svg" width="40" height="20"></svg>`,
+	})
+	pkg := newPrefixFileManager("~", map[string]string{
+		"~pkg/x": `.pkg { margin: 0; }`,
+	})
+	fsys := fstest.MapFS{
+		"local.less": &fstest.MapFile{Data: []byte(`.local { padding: 0; }`)},
+	}
+
+	input := `@import "db://theme/42";
+@import "~pkg/x";
+@import "local";
+.a { border-color: @brand; background: data-uri("db://img/logo.svg"); }`
+	result, err := Compile(input, &CompileOptions{FS: fsys, FileManagers: []FileManager{db, pkg}})
+	if err != nil {
+		t.Fatalf("Compile with FileManagers failed: %v", err)
+	}
+
+	for _, want := range []string{".theme", "border-color: #112233", ".pkg", ".local", "data:image/svg+xml"} {
+		if !strings.Contains(result.CSS, want) {
+			t.Errorf("CSS should contain %q: %s", want, result.CSS)
+		}
+	}
+}
+
+func TestCompile_FileManagersPriority(t *testing.T) {
+	first := newPrefixFileManager("db://", map[string]string{"db://a": `.first { a: b; }`})
+	last := newPrefixFileManager("db://", map[string]string{"db://a": `.last { a: b; }`})
+
+	result, err := Compile(`@import "db://a";`, &CompileOptions{FileManagers: []FileManager{first, last}})
+	if err != nil {
+		t.Fatalf("Compile failed: %v", err)
+	}
+	if !strings.Contains(result.CSS, ".last") || strings.Contains(result.CSS, ".first") {
+		t.Errorf("later file managers should take priority: %s", result.CSS)
+	}
+
+	// A manager that supports the file but can't find it is not followed by a fallback
+	_, err = Compile(`@import "db://missing";`, &CompileOptions{FileManagers: []FileManager{first}})
+	if err == nil || !strings.Contains(err.Error(), "db://missing") {
+		t.Errorf("expected a not found error for db://missing, got %v", err)
+	}
+}
+
+func TestCompile_Warnings(t *testing.T) {
+	fsys := fstest.MapFS{
+		"main.less":   &fstest.MapFile{Data: []byte("@import \"mixins\";\n.b:extend(.missing) { x: y; }\n.c { .m; }\n")},
+		"mixins.less": &fstest.MapFile{Data: []byte(".m() { c: d; }\n.e { width: (4px ./ 2); }\n")},
+	}
+
+	var reported []Diagnostic
+	result, err := CompileFile("main.less", &CompileOptions{
+		FS:           fsys,
+		Compress:     true,
+		OnDiagnostic: func(d Diagnostic) { reported = append(reported, d) },
+	})
+	if err != nil {
+		t.Fatalf("CompileFile failed: %v", err)
+	}
+
+	want := map[string]Diagnostic{
+		DiagnosticDotSlashDeprecated: {Filename: "mixins.less", Line: 2, Column: 18},
+		DiagnosticMixinCallNoParens:  {Filename: "main.less", Line: 3, Column: 8},
+		DiagnosticExtendNoMatch:      {Filename: "main.less", Line: 2, Column: 3},
+		DiagnosticCompressDeprecated: {},
+	}
+	if len(result.Warnings) != len(want) {
+		t.Fatalf("expected %d warnings, got %v", len(want), result.Warnings)
+	}
+	for _, w := range result.Warnings {
+		expected, ok := want[w.Code]
+		if !ok {
+			t.Errorf("unexpected warning %v", w)
+			continue
+		}
+		if w.Filename != expected.Filename || w.Line != expected.Line || w.Column != expected.Column {
+			t.Errorf("%s: got %s:%d:%d, want %s:%d:%d", w.Code, w.Filename, w.Line, w.Column, expected.Filename, expected.Line, expected.Column)
+		}
+		if w.Message == "" {
+			t.Errorf("%s: empty message", w.Code)
+		}
+	}
+	if len(reported) != len(result.Warnings) {
+		t.Errorf("OnDiagnostic got %d warnings, result has %d", len(reported), len(result.Warnings))
+	}
+}
+
+func TestCompile_WarningsAreScopedToCompilation(t *testing.T) {
+	for i := 1; i <= 3; i++ {
+		calls := 0
+		input := strings.Repeat(".m() { a: b; }\n.c { .m; }\n", i)
+		result, err := Compile(input, &CompileOptions{OnDiagnostic: func(Diagnostic) { calls++ }})
+		if err != nil {
+			t.Fatalf("Compile failed: %v", err)
+		}
+		if len(result.Warnings) != i || calls != i {
+			t.Errorf("compilation %d: got %d warnings and %d callbacks, want %d", i, len(result.Warnings), calls, i)
+		}
+	}
+
+	result, err := Compile(".a { color: red; }", nil)
+	if err != nil {
+		t.Fatalf("Compile failed: %v", err)
+	}
+	if result.Warnings != nil {
+		t.Errorf("expected no warnings, got %v", result.Warnings)
+	}
+}
+
+func TestCompile_EvalErrorKeepsLocation(t *testing.T) {
+	tests := []struct {
+		input  string
+		typ    string
+		line   int
+		column int
+	}{
+		{".a {\n  .missing();\n}", "Name", 2, 2},
+		{".a {\n  b: @c;\n  d: percentage(\"x\");\n}", "Argument", 3, 5},
+	}
+	for _, tt := range tests {
+		_, err := Compile(tt.input, &CompileOptions{Filename: "main.less"})
+		var lessErr *LessError
+		if !errors.As(err, &lessErr) {
+			t.Fatalf("expected a LessError for %q, got %T: %v", tt.input, err, err)
+		}
+		if lessErr.Type != tt.typ || lessErr.LineNumber() != tt.line || lessErr.Column != tt.column {
+			t.Errorf("%q: got %s error at %d:%d, want %s at %d:%d", tt.input, lessErr.Type, lessErr.LineNumber(), lessErr.Column, tt.typ, tt.line, tt.column)
+		}
+		if lessErr.Filename != "main.less" || lessErr.Extract[1] == "" {
+			t.Errorf("%q: missing filename or extract: %+v", tt.input, lessErr)
+		}
+	}
+}
+
+func TestCompile_Functions(t *testing.T) {
+	tokens := map[string]string{"primary": "336699"}
+	var filename string
+	functions := map[string]FunctionDefinition{
+		"brand-token": Function(func(ctx *Context, args ...any) (any, error) {
+			filename, _ = ctx.Frames[0].CurrentFileInfo["filename"].(string)
+			name, ok := args[0].(*Quoted)
+			if !ok || tokens[name.GetValue()] == "" {
+				return nil, fmt.Errorf("unknown token %v", args[0])
+			}
+			return NewColor(tokens[name.GetValue()], 1, ""), nil
+		}),
+		"gutter": Function(func(ctx *Context, args ...any) (any, error) {
+			size := args[0].(*Dimension)
+			side := args[1].(*Keyword)
+			items := GetItemsFromNode(args[2])
+			return fmt.Sprintf("%s %gpx %d", side.GetValue(), size.GetValue()*2, len(items)), nil
+		}),
+		"unknown-yet": Function(func(ctx *Context, args ...any) (any, error) {
+			return nil, nil
+		}),
+	}
+	input := `.m() { border-color: darken(brand-token("primary"), 10%); }
+.a {
+  color: brand-token("primary");
+  margin: gutter(4px, left, a b c);
+  .m();
+  b: unknown-yet(1);
+}`
+	result, err := Compile(input, &CompileOptions{Filename: "theme.less", Functions: functions})
+	if err != nil {
+		t.Fatalf("Compile failed: %v", err)
+	}
+	for _, want := range []string{"color: #336699;", "margin: left 8px 3;", "border-color: #264c73;", "b: unknown-yet(1);"} {
+		if !strings.Contains(result.CSS, want) {
+			t.Errorf("expected %q in output:\n%s", want, result.CSS)
+		}
+	}
+	if filename != "theme.less" {
+		t.Errorf("expected the context to have the file info, got %q", filename)
+	}
+
+	_, err = Compile(`.a { color: brand-token("secondary"); }`, &CompileOptions{Functions: functions})
+	if err == nil || !strings.Contains(err.Error(), "unknown token") {
+		t.Errorf("expected the function's error, got %v", err)
+	}
+
+	// Functions are scoped to the compilation
+	result, err = Compile(`.a { color: brand-token("primary"); }`, nil)
+	if err != nil {
+		t.Fatalf("Compile failed: %v", err)
+	}
+	if !strings.Contains(result.CSS, `brand-token("primary")`) {
+		t.Errorf("expected the function to be unknown without the option:\n%s", result.CSS)
+	}
+}
+
+func TestCompile_FunctionsOverrideBuiltIns(t *testing.T) {
+	functions := map[string]FunctionDefinition{
+		"percentage": Function(func(ctx *Context, args ...any) (any, error) {
+			return NewKeyword("overridden"), nil
+		}),
+	}
+	fsys := fstest.MapFS{"lib.less": {Data: []byte(".lib { a: percentage(0.5); }")}}
+	result, err := Compile(`@import "lib.less"; .b { a: percentage(0.5); }`, &CompileOptions{FS: fsys, Functions: functions})
+	if err != nil {
+		t.Fatalf("Compile failed: %v", err)
+	}
+	if strings.Count(result.CSS, "a: overridden;") != 2 {
+		t.Errorf("expected both calls to be overridden:\n%s", result.CSS)
+	}
+	result, err = Compile(`.b { a: percentage(0.5); }`, nil)
+	if err != nil || !strings.Contains(result.CSS, "a: 50%;") {
+		t.Errorf("expected the built-in to be unchanged, got %v\n%v", err, result)
+	}
+}
+
+type preProcessorFunc func(string, map[string]any) string
+
+func (f preProcessorFunc) Process(src string, extra map[string]any) string { return f(src, extra) }
+
+type postProcessorFunc func(string, map[string]any) (string, error)
+
+func (f postProcessorFunc) Process(css string, extra map[string]any) (string, error) {
+	return f(css, extra)
+}
+
+type visitorFunc struct {
+	run     func(root any) any
+	preEval bool
+}
+
+func (v *visitorFunc) Run(root any) any       { return v.run(root) }
+func (v *visitorFunc) IsPreEvalVisitor() bool { return v.preEval }
+
+func TestCompile_GoPlugins(t *testing.T) {
+	var order []string
+	plugin := GoPluginFunc(func(pm *PluginManager) error {
+		pm.AddPreProcessor(preProcessorFunc(func(src string, extra map[string]any) string {
+			return strings.ReplaceAll(src, "$brand", "#336699")
+		}), 1)
+		pm.AddVisitor(&visitorFunc{preEval: true, run: func(root any) any {
+			order = append(order, "pre-eval")
+			Walk(root, func(v *Variable) {
+				if v.GetName() == "@old" {
+					v.name = "@new"
+				}
+			})
+			return root
+		}})
+		pm.AddVisitor(&visitorFunc{run: func(root any) any {
+			order = append(order, "post-eval")
+			Walk(root, func(d *Declaration) {
+				if d.GetName() == "color" {
+					d.important = " !important"
+				}
+			})
+			return root
+		}})
+		pm.AddPostProcessor(postProcessorFunc(func(css string, extra map[string]any) (string, error) {
+			return "/* processed */\n" + css, nil
+		}), 1)
+		pm.AddFileManager(newPrefixFileManager("db://", map[string]string{"db://a": ".db { a: b; }"}))
+		return nil
+	})
+
+	input := `@import "db://a";
+@new: 2px;
+.a { color: $brand; width: @old; }`
+	result, err := Compile(input, &CompileOptions{GoPlugins: []GoPlugin{plugin}})
+	if err != nil {
+		t.Fatalf("Compile failed: %v", err)
+	}
+	for _, want := range []string{"/* processed */\n", ".db {", "color: #336699 !important;", "width: 2px;"} {
+		if !strings.Contains(result.CSS, want) {
+			t.Errorf("expected %q in output:\n%s", want, result.CSS)
+		}
+	}
+	if strings.Join(order, ",") != "pre-eval,post-eval" {
+		t.Errorf("expected each visitor to run once, in order, got %v", order)
+	}
+
+	failing := GoPluginFunc(func(pm *PluginManager) error { return errors.New("bad config") })
+	_, err = Compile(".a { b: c; }", &CompileOptions{GoPlugins: []GoPlugin{failing}})
+	if err == nil || !strings.Contains(err.Error(), "bad config") {
+		t.Errorf("expected the install error, got %v", err)
+	}
+}
@@ -0,0 +1,227 @@
+package less_go
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// countingWriter counts the writes it gets, to tell streamed CSS from CSS
+// written at once
+type countingWriter struct {
+	buf    bytes.Buffer
+	writes int
+}
+
+func (w *countingWriter) Write(p []byte) (int, error) {
+	w.writes++
+	return w.buf.Write(p)
+}
+
+func (w *countingWriter) String() string {
+	return w.buf.String()
+}
+
+// failingWriter fails every write after the first n bytes
+type failingWriter struct {
+	n int
+}
+
+var errDiskFull = errors.New("disk full")
+
+func (w *failingWriter) Write(p []byte) (int, error) {
+	if len(p) > w.n {
+		n := w.n
+		w.n = 0
+		return n, errDiskFull
+	}
+	w.n -= len(p)
+	return len(p), nil
+}
+
+func TestCompileTo_MatchesCompile(t *testing.T) {
+	files, err := filepath.Glob("../testdata/less/_main/*.less")
+	if err != nil || len(files) == 0 {
+		t.Skip("test data not found")
+	}
+	for _, file := range files {
+		src, err := os.ReadFile(file)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if strings.Contains(string(src), "https:") || strings.Contains(string(src), "@plugin") {
+			continue // remote imports, or plugins needing Node.js
+		}
+		filename, _ := filepath.Abs(file)
+		for _, options := range []CompileOptions{
+			{Filename: filename, Math: Math.ParensDivision},
+			{Filename: filename, Math: Math.ParensDivision, Compress: true},
+			{Filename: filename, Math: Math.ParensDivision, SourceMap: true},
+		} {
+			want, wantErr := Compile(string(src), &options)
+			var out, sourceMap bytes.Buffer
+			got, err := CompileTo(&out, &sourceMap, string(src), &options)
+			if (err != nil) != (wantErr != nil) {
+				t.Errorf("%s: got error %v, want %v", filepath.Base(file), err, wantErr)
+				continue
+			}
+			if err != nil {
+				continue
+			}
+			// With source maps, some selectors are output with the address of a
+			// node, which differs between compilations
+			if out.String() != want.CSS && !options.SourceMap {
+				t.Errorf("%s (compress=%v): streamed CSS differs from Compile's", filepath.Base(file), options.Compress)
+			}
+			if sourceMap.String() != want.Map {
+				t.Errorf("%s: streamed source map differs from Compile's:\n%s\nwant:\n%s", filepath.Base(file), sourceMap.String(), want.Map)
+			}
+			if got.CSS != "" || got.Map != "" || len(got.Warnings) != len(want.Warnings) || len(got.Imports) != len(want.Imports) {
+				t.Errorf("%s: unexpected result %+v", filepath.Base(file), got)
+			}
+		}
+	}
+}
+
+func TestCompileTo_Streams(t *testing.T) {
+	var source strings.Builder
+	for i := 0; i < 5000; i++ {
+		fmt.Fprintf(&source, ".m-%d { margin: %dpx; }\n", i, i)
+	}
+	var out countingWriter
+	if _, err := CompileTo(&out, nil, source.String(), nil); err != nil {
+		t.Fatal(err)
+	}
+	want, err := Compile(source.String(), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if out.String() != want.CSS {
+		t.Error("streamed CSS differs from Compile's")
+	}
+	// 120KB or so of CSS goes through the 64KB buffer in several writes
+	if out.writes < 2 {
+		t.Errorf("expected the CSS to be written as it is generated, got %d writes", out.writes)
+	}
+}
+
+func TestCompileTo_StreamsSourceMap(t *testing.T) {
+	var source strings.Builder
+	for i := 0; i < 5000; i++ {
+		fmt.Fprintf(&source, ".m-%d { margin: %dpx; }\n", i, i)
+	}
+	options := &CompileOptions{Filename: "utilities.less", SourceMap: true}
+	var css bytes.Buffer
+	var sourceMap countingWriter
+	if _, err := CompileTo(&css, &sourceMap, source.String(), options); err != nil {
+		t.Fatal(err)
+	}
+	want, err := Compile(source.String(), options)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sourceMap.String() != want.Map {
+		t.Error("streamed source map differs from Compile's")
+	}
+	// The mappings go through the 64KB buffer as the CSS is generated, before
+	// the sources are written at the end
+	if sourceMap.writes < 2 {
+		t.Errorf("expected the source map to be written as it is generated, got %d writes", sourceMap.writes)
+	}
+}
+
+func TestCompileTo_SourceMap(t *testing.T) {
+	input := ".a {\n  color: red;\n  .b { margin: 1px; }\n}\n"
+	options := &CompileOptions{
+		Filename:         "main.less",
+		SourceMapOptions: &SourceMapOptions{SourceMapFilename: "main.css.map"},
+	}
+	want, err := Compile(input, options)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var css, sourceMap bytes.Buffer
+	result, err := CompileTo(&css, &sourceMap, input, options)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if css.String() != want.CSS || !strings.HasSuffix(css.String(), "/*# sourceMappingURL=main.css.map */") {
+		t.Errorf("got CSS\n%s\nwant\n%s", css.String(), want.CSS)
+	}
+	if sourceMap.String() != want.Map || !strings.Contains(sourceMap.String(), `"mappings"`) {
+		t.Errorf("got source map %s, want %s", sourceMap.String(), want.Map)
+	}
+	if result.Map != "" {
+		t.Errorf("expected the source map to be written only, got %s", result.Map)
+	}
+
+	// Without a writer for it, the source map is returned
+	css.Reset()
+	result, err = CompileTo(&css, nil, input, options)
+	if err != nil || result.Map != want.Map {
+		t.Errorf("expected the source map in the result, got %q, %v", result.Map, err)
+	}
+}
+
+func TestCompileTo_PostProcessors(t *testing.T) {
+	plugin := GoPluginFunc(func(pm *PluginManager) error {
+		pm.AddPostProcessor(postProcessorFunc(func(css string, extra map[string]any) (string, error) {
+			return "/* banner */\n" + css, nil
+		}), 1)
+		return nil
+	})
+	var out countingWriter
+	_, err := CompileTo(&out, nil, ".a { color: red; }", &CompileOptions{GoPlugins: []GoPlugin{plugin}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if out.String() != "/* banner */\n.a {\n  color: red;\n}\n" || out.writes != 1 {
+		t.Errorf("expected the post-processed CSS in one write, got %d writes of %q", out.writes, out.String())
+	}
+}
+
+func TestCompileTo_WriteError(t *testing.T) {
+	var source strings.Builder
+	for i := 0; i < 5000; i++ {
+		fmt.Fprintf(&source, ".m-%d { margin: %dpx; }\n", i, i)
+	}
+	_, err := CompileTo(&failingWriter{n: 1000}, nil, source.String(), nil)
+	if !errors.Is(err, errDiskFull) {
+		t.Errorf("expected the write error, got %v", err)
+	}
+
+	_, err = CompileTo(&bytes.Buffer{}, &failingWriter{}, ".a { b: c; }", &CompileOptions{SourceMap: true})
+	if !errors.Is(err, errDiskFull) {
+		t.Errorf("expected the source map write error, got %v", err)
+	}
+}
+
+func TestCompileTo_CollectedErrors(t *testing.T) {
+	var out bytes.Buffer
+	_, err := CompileTo(&out, nil, ".a { color: @missing; }\n.b { c: d; }", &CompileOptions{CollectErrors: true})
+	var multi *MultiError
+	if !errors.As(err, &multi) {
+		t.Fatalf("expected a *MultiError, got %v", err)
+	}
+	if out.Len() != 0 {
+		t.Errorf("expected no CSS to be written, got %q", out.String())
+	}
+}
+
+func TestCompiler_CompileTo(t *testing.T) {
+	compiler := NewCompiler(&CompileOptions{Compress: true})
+	var out bytes.Buffer
+	if _, err := compiler.CompileTo(&out, nil, "@c: red;\n.a { color: @c; }", "main.less"); err != nil {
+		t.Fatal(err)
+	}
+	if out.String() != ".a{color:red}" {
+		t.Errorf("unexpected CSS %q", out.String())
+	}
+	if compiler.Dependencies("main.less") == nil {
+		t.Error("expected the dependency graph of main.less")
+	}
+}
@@ -0,0 +1,170 @@
+package less_go
+
+import (
+	"context"
+	"io"
+	"sort"
+	"sync"
+)
+
+// Compiler compiles many stylesheets with the same options. Imported files are
+// parsed once and their trees reused by later compilations for as long as the
+// file's contents are unchanged, so entrypoints sharing a large library don't
+// each parse it again. Files are still read on every compilation to detect
+// changes; the cache is keyed on the resolved path and a hash of the contents.
+//
+// The Compiler remembers the dependency graph of the last compilation of each
+// entrypoint, so that Invalidate can tell which entrypoints a change affects.
+// With JavaScript plugins enabled, the Node.js process is kept running between
+// compilations and reset in between; call Close to stop it.
+//
+// A Compiler is safe for concurrent use by multiple goroutines.
+//
+// Example usage:
+//
+//	compiler := less_go.NewCompiler(&less_go.CompileOptions{
+//	    Paths: []string{"src/lib"},
+//	})
+//	for _, entry := range entrypoints {
+//	    result, err := compiler.CompileFile(entry)
+//	    ...
+//	}
+type Compiler struct {
+	options CompileOptions
+	*compilerState
+}
+
+// compilerState is shared by a Compiler and those derived from it with WithOptions
+type compilerState struct {
+	cache    *importCache
+	runtimes *pluginRuntimePool
+
+	mu          sync.Mutex
+	entrypoints map[string]*DependencyGraph
+}
+
+// NewCompiler creates a Compiler that compiles with a copy of options.
+// options.Filename is ignored; the filename is given to each compilation.
+func NewCompiler(options *CompileOptions) *Compiler {
+	c := &Compiler{compilerState: &compilerState{
+		cache:       newImportCache(),
+		runtimes:    newPluginRuntimePool(),
+		entrypoints: make(map[string]*DependencyGraph),
+	}}
+	if options != nil {
+		c.options = *options
+	}
+	return c
+}
+
+// WithOptions returns a Compiler that compiles with a copy of options but
+// shares c's parsed imports, Node.js processes and dependency graphs, e.g. for
+// entrypoints that need their own source map settings. Invalidate and Close
+// on either Compiler apply to both.
+func (c *Compiler) WithOptions(options *CompileOptions) *Compiler {
+	derived := &Compiler{compilerState: c.compilerState}
+	if options != nil {
+		derived.options = *options
+	}
+	return derived
+}
+
+// Compile compiles LESS source code to CSS. filename is used to resolve
+// relative imports and in error messages and source maps.
+func (c *Compiler) Compile(input, filename string) (*CompileResult, error) {
+	return c.CompileContext(context.Background(), input, filename)
+}
+
+// CompileContext is like Compile but stops as soon as ctx is done.
+// See the package-level CompileContext for details.
+func (c *Compiler) CompileContext(ctx context.Context, input, filename string) (*CompileResult, error) {
+	options := c.compileOptions(filename)
+	options.Filename = filename
+	return CompileContext(ctx, input, options)
+}
+
+// CompileTo is like Compile but writes the CSS to w, and the source map to
+// mapWriter, as they are generated. On error, they may hold a partial
+// stylesheet and source map. See the package-level CompileTo for details.
+func (c *Compiler) CompileTo(w, mapWriter io.Writer, input, filename string) (*CompileResult, error) {
+	return c.CompileToContext(context.Background(), w, mapWriter, input, filename)
+}
+
+// CompileToContext is like CompileTo but stops as soon as ctx is done.
+func (c *Compiler) CompileToContext(ctx context.Context, w, mapWriter io.Writer, input, filename string) (*CompileResult, error) {
+	options := c.compileOptions(filename)
+	options.Filename = filename
+	options.output, options.mapOutput = w, mapWriter
+	return CompileContext(ctx, input, options)
+}
+
+// CompileFile reads and compiles a LESS file to CSS.
+func (c *Compiler) CompileFile(filename string) (*CompileResult, error) {
+	return c.CompileFileContext(context.Background(), filename)
+}
+
+// CompileFileContext is like CompileFile but stops as soon as ctx is done.
+func (c *Compiler) CompileFileContext(ctx context.Context, filename string) (*CompileResult, error) {
+	return CompileFileContext(ctx, filename, c.compileOptions(filename))
+}
+
+// Dependencies returns the dependency graph of the last compilation of
+// entrypoint, or nil if it has not been compiled. The graph of a failed
+// compilation holds the files read before the error.
+func (c *Compiler) Dependencies(entrypoint string) *DependencyGraph {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.entrypoints[entrypoint]
+}
+
+// Invalidate forgets the cached trees of the given files, e.g. after a file
+// watcher reported them changed or removed, and returns the entrypoints whose
+// last compilation depended on any of them, sorted. An entrypoint depends on
+// itself, on everything it imports directly or transitively, on its @plugin
+// scripts and on the files read by data-uri and image-size.
+func (c *Compiler) Invalidate(paths ...string) []string {
+	keys := make(map[string]bool, len(paths))
+	for _, path := range paths {
+		keys[dependencyKey(path)] = true
+	}
+	c.cache.remove(func(filename string) bool {
+		return keys[dependencyKey(filename)]
+	})
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	affected := []string{}
+	for entrypoint, graph := range c.entrypoints {
+		for _, path := range paths {
+			if graph.DependsOn(path) {
+				affected = append(affected, entrypoint)
+				break
+			}
+		}
+	}
+	sort.Strings(affected)
+	return affected
+}
+
+// Close stops the Node.js processes kept for JavaScript plugins. Compilations
+// started after Close run their own Node.js process, as Compile does.
+func (c *Compiler) Close() error {
+	return c.runtimes.close()
+}
+
+// compileOptions returns a per-compilation copy of the options, since the
+// compile functions fill some of them in, and starts a new dependency graph
+// for filename
+func (c *Compiler) compileOptions(filename string) *CompileOptions {
+	options := c.options
+	options.importCache = c.cache
+	options.pluginRuntimes = c.runtimes
+	if filename == "" {
+		filename = "input"
+	}
+	options.dependencies = NewDependencyGraph(filename)
+	c.mu.Lock()
+	c.entrypoints[filename] = options.dependencies
+	c.mu.Unlock()
+	return &options
+}
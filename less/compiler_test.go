@@ -0,0 +1,410 @@
+package less_go
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"testing/fstest"
+)
+
+func TestCompiler_ReusesParsedImports(t *testing.T) {
+	fsys := fstest.MapFS{
+		"lib/base.less":  &fstest.MapFile{Data: []byte(`@gap: 4px; .m(@x) { margin: @x; } .base { .m(@gap); }`)},
+		"themes/a.less":  &fstest.MapFile{Data: []byte(`@import "../lib/base"; .a { padding: @gap; }`)},
+		"themes/b.less":  &fstest.MapFile{Data: []byte(`@import "../lib/base"; .b { padding: @gap * 2; }`)},
+		"themes/c.less":  &fstest.MapFile{Data: []byte(`@import (reference) "../lib/base"; .c { .m(1px); }`)},
+		"unrelated.less": &fstest.MapFile{Data: []byte(`.x { y: z; }`)},
+	}
+	compiler := NewCompiler(&CompileOptions{FS: fsys})
+
+	expected := map[string][]string{
+		"themes/a.less": {".base", "margin: 4px", "padding: 4px"},
+		"themes/b.less": {".base", "padding: 8px"},
+		"themes/c.less": {".c", "margin: 1px"},
+	}
+	for _, name := range []string{"themes/a.less", "themes/b.less", "themes/c.less", "themes/a.less"} {
+		result, err := compiler.CompileFile(name)
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		for _, want := range expected[name] {
+			if !strings.Contains(result.CSS, want) {
+				t.Errorf("%s: CSS should contain %q: %s", name, want, result.CSS)
+			}
+		}
+		if name == "themes/c.less" && strings.Contains(result.CSS, ".base") {
+			t.Errorf("reference import should not output .base: %s", result.CSS)
+		}
+	}
+
+	// One entry for the plain import and one for the reference import
+	if n := len(compiler.cache.entries); n != 2 {
+		t.Errorf("expected 2 cached trees, got %d", n)
+	}
+}
+
+func TestCompiler_ReplaysImportWarnings(t *testing.T) {
+	fsys := fstest.MapFS{
+		"main.less": &fstest.MapFile{Data: []byte("@import \"lib\";\n.a { b: c; }\n")},
+		"lib.less":  &fstest.MapFile{Data: []byte(".m() { c: d; }\n.e { width: (4px ./ 2); }\n.f { .m; }\n")},
+	}
+	compiler := NewCompiler(&CompileOptions{FS: fsys})
+
+	// The second compilation uses the cached tree of lib.less
+	for i := 0; i < 2; i++ {
+		result, err := compiler.CompileFile("main.less")
+		if err != nil {
+			t.Fatal(err)
+		}
+		var codes []string
+		for _, w := range result.Warnings {
+			if w.Filename != "lib.less" {
+				t.Errorf("compilation %d: expected the warning in lib.less, got %v", i+1, w)
+			}
+			codes = append(codes, w.Code)
+		}
+		if want := []string{DiagnosticDotSlashDeprecated, DiagnosticMixinCallNoParens}; strings.Join(codes, " ") != strings.Join(want, " ") {
+			t.Errorf("compilation %d: expected warnings %v, got %v", i+1, want, result.Warnings)
+		}
+	}
+	if n := len(compiler.cache.entries); n != 1 {
+		t.Errorf("expected 1 cached tree, got %d", n)
+	}
+}
+
+func TestCompiler_ChangedImportIsReparsed(t *testing.T) {
+	fsys := fstest.MapFS{
+		"main.less": &fstest.MapFile{Data: []byte(`@import "vars"; .a { color: @c; }`)},
+		"vars.less": &fstest.MapFile{Data: []byte(`@c: red;`)},
+	}
+	compiler := NewCompiler(&CompileOptions{FS: fsys})
+
+	result, err := compiler.CompileFile("main.less")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(result.CSS, "color: red") {
+		t.Fatalf("unexpected CSS: %s", result.CSS)
+	}
+
+	fsys["vars.less"] = &fstest.MapFile{Data: []byte(`@c: blue;`)}
+	result, err = compiler.CompileFile("main.less")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(result.CSS, "color: blue") {
+		t.Errorf("changed import should be reparsed: %s", result.CSS)
+	}
+}
+
+func TestCompiler_VariableImportsArePerCompilation(t *testing.T) {
+	// The import visitor resolves "@{theme}" inside the cached library; that
+	// must not leak into the next entrypoint
+	fsys := fstest.MapFS{
+		"base.less":  &fstest.MapFile{Data: []byte(`@import "@{theme}"; .base { color: @color; }`)},
+		"dark.less":  &fstest.MapFile{Data: []byte(`@color: black;`)},
+		"light.less": &fstest.MapFile{Data: []byte(`@color: white;`)},
+		"a.less":     &fstest.MapFile{Data: []byte(`@theme: "dark"; @import "base";`)},
+		"b.less":     &fstest.MapFile{Data: []byte(`@theme: "light"; @import "base";`)},
+	}
+	compiler := NewCompiler(&CompileOptions{FS: fsys})
+
+	for name, want := range map[string]string{"a.less": "color: black", "b.less": "color: white"} {
+		result, err := compiler.CompileFile(name)
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		if !strings.Contains(result.CSS, want) {
+			t.Errorf("%s: CSS should contain %q: %s", name, want, result.CSS)
+		}
+	}
+}
+
+func TestCompiler_Concurrent(t *testing.T) {
+	fsys := fstest.MapFS{
+		"lib.less": &fstest.MapFile{Data: []byte(`.mixin(@c) { color: @c; &:hover { color: darken(@c, 10%); } } .lib { .mixin(#888); }`)},
+	}
+	compiler := NewCompiler(&CompileOptions{FS: fsys})
+
+	var wg sync.WaitGroup
+	errs := make(chan error, 16)
+	for i := 0; i < 16; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			result, err := compiler.Compile(`@import "lib"; .a { .mixin(#336699); }`, "main.less")
+			if err != nil {
+				errs <- err
+				return
+			}
+			if !strings.Contains(result.CSS, ".a:hover") || !strings.Contains(result.CSS, ".lib") {
+				t.Errorf("unexpected CSS: %s", result.CSS)
+			}
+		}()
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		t.Error(err)
+	}
+}
+
+// TestCompiler_MatchesCompile compiles the main test corpus twice with one
+// Compiler and checks the output is identical to a fresh Compile each time
+func TestCompiler_MatchesCompile(t *testing.T) {
+	dir := "../testdata/less/_main"
+	files, err := filepath.Glob(filepath.Join(dir, "*.less"))
+	if err != nil || len(files) == 0 {
+		t.Skip("test corpus not available")
+	}
+
+	options := CompileOptions{RewriteUrls: RewriteUrlsAll, JavascriptEnabled: true}
+	compiler := NewCompiler(&options)
+	for _, file := range files {
+		if strings.Contains(file, "remote") {
+			continue
+		}
+		content, err := os.ReadFile(file)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		opts := options
+		opts.Filename = file
+		want, wantErr := Compile(string(content), &opts)
+		for pass := 1; pass <= 2; pass++ {
+			got, gotErr := compiler.Compile(string(content), file)
+			if (wantErr == nil) != (gotErr == nil) {
+				t.Errorf("%s pass %d: error mismatch: %v vs %v", filepath.Base(file), pass, wantErr, gotErr)
+				continue
+			}
+			if wantErr == nil && got.CSS != want.CSS {
+				t.Errorf("%s pass %d: CSS differs from Compile", filepath.Base(file), pass)
+			}
+		}
+	}
+}
+
+func TestCompiler_DependencyGraph(t *testing.T) {
+	fsys := fstest.MapFS{
+		"lib/base.less":   &fstest.MapFile{Data: []byte(`@import (reference) "mixins"; .base { background: data-uri("img/dot.svg"); }`)},
+		"lib/mixins.less": &fstest.MapFile{Data: []byte(`.m() { color: red; }`)},
+		"lib/print.css":   &fstest.MapFile{Data: []byte(`.print { display: none; }`)},
+		"img/dot.svg":     &fstest.MapFile{Data: []byte(`<svg xmlns="http://www.w3.org/2000/svg"/>`)},
+		"a.less":          &fstest.MapFile{Data: []byte(`@import "lib/base"; @import (inline) "lib/print.css"; @import (optional) "missing"; .a { .m(); }`)},
+	}
+	result, err := CompileFile("a.less", &CompileOptions{FS: fsys})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	graph := result.Dependencies
+	if graph == nil || graph.Root != "a.less" {
+		t.Fatalf("unexpected graph: %+v", graph)
+	}
+	expected := []Dependency{
+		{File: "lib/base.less", Importer: "a.less", Kind: DependencyImport},
+		{File: "lib/mixins.less", Importer: "lib/base.less", Kind: DependencyImport, Reference: true},
+		{File: "lib/print.css", Importer: "a.less", Kind: DependencyImport, Inline: true},
+		{File: "img/dot.svg", Importer: "lib/base.less", Kind: DependencyAsset},
+	}
+	for _, want := range expected {
+		importers := graph.Importers(want.File)
+		if len(importers) != 1 || importers[0] != want {
+			t.Errorf("expected %+v, got %+v", want, importers)
+		}
+	}
+	if got := graph.Files(); strings.Join(got, ",") != "a.less,img/dot.svg,lib/base.less,lib/mixins.less,lib/print.css" {
+		t.Errorf("unexpected files: %v", got)
+	}
+
+	data, err := json.Marshal(graph.Dependencies[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(data), `"kind":"import"`) {
+		t.Errorf("kind should marshal as a name: %s", data)
+	}
+}
+
+func TestCompiler_Invalidate(t *testing.T) {
+	fsys := fstest.MapFS{
+		"lib/vars.less":  &fstest.MapFile{Data: []byte(`@c: red;`)},
+		"lib/theme.less": &fstest.MapFile{Data: []byte(`@import "vars"; .icon { background: data-uri("img/icon.svg"); }`)},
+		"img/icon.svg":   &fstest.MapFile{Data: []byte(`<svg xmlns="http://www.w3.org/2000/svg"/>`)},
+		"a.less":         &fstest.MapFile{Data: []byte(`@import "lib/theme"; .a { color: @c; }`)},
+		"b.less":         &fstest.MapFile{Data: []byte(`@import "lib/vars"; .b { color: @c; }`)},
+		"c.less":         &fstest.MapFile{Data: []byte(`.c { color: blue; }`)},
+	}
+	compiler := NewCompiler(&CompileOptions{FS: fsys})
+	for _, name := range []string{"a.less", "b.less", "c.less"} {
+		if _, err := compiler.CompileFile(name); err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+	}
+
+	for _, tc := range []struct {
+		paths    []string
+		affected string
+	}{
+		{[]string{"lib/vars.less"}, "a.less,b.less"},
+		{[]string{"./lib/theme.less"}, "a.less"},
+		{[]string{"img/icon.svg"}, "a.less"},
+		{[]string{"c.less"}, "c.less"},
+		{[]string{"c.less", "lib/vars.less"}, "a.less,b.less,c.less"},
+		{[]string{"unrelated.less"}, ""},
+	} {
+		if got := strings.Join(compiler.Invalidate(tc.paths...), ","); got != tc.affected {
+			t.Errorf("Invalidate(%v) = %q, want %q", tc.paths, got, tc.affected)
+		}
+	}
+
+	for key := range compiler.cache.entries {
+		if key.filename == "lib/vars.less" || key.filename == "lib/theme.less" {
+			t.Errorf("invalidated file %s should be evicted from the cache", key.filename)
+		}
+	}
+}
+
+func TestCompiler_ReusesPluginRuntime(t *testing.T) {
+	dir := "../testdata/less/_main"
+	files, _ := filepath.Glob(filepath.Join(dir, "plugin*.less"))
+	if len(files) == 0 {
+		t.Skip("plugin tests not available")
+	}
+
+	options := CompileOptions{EnableJavaScriptPlugins: true, JavascriptEnabled: true}
+	compiler := NewCompiler(&options)
+	defer compiler.Close()
+
+	// Interleave entrypoints so each compilation follows a different one on the same runtime
+	for pass := 1; pass <= 2; pass++ {
+		for _, file := range files {
+			content, err := os.ReadFile(file)
+			if err != nil {
+				t.Fatal(err)
+			}
+			opts := options
+			opts.Filename = file
+			want, wantErr := Compile(string(content), &opts)
+			got, gotErr := compiler.Compile(string(content), file)
+			if (wantErr == nil) != (gotErr == nil) {
+				t.Fatalf("%s pass %d: error mismatch: %v vs %v", filepath.Base(file), pass, wantErr, gotErr)
+			}
+			if wantErr == nil && got.CSS != want.CSS {
+				t.Errorf("%s pass %d: CSS differs from Compile", filepath.Base(file), pass)
+			}
+		}
+	}
+
+	if n := len(compiler.runtimes.idle); n != 1 {
+		t.Errorf("expected one Node.js process to be kept, got %d", n)
+	}
+}
+
+func TestCompiler_ReloadsEditedPlugin(t *testing.T) {
+	dir := t.TempDir()
+	writeFile := func(name, content string) {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	writeFile("main.less", `@plugin "answer"; .a { width: answer(); }`)
+	writeFile("answer.js", `functions.add('answer', function() { return less.dimension(1, 'px'); });`)
+
+	compiler := NewCompiler(&CompileOptions{EnableJavaScriptPlugins: true})
+	defer compiler.Close()
+
+	main := filepath.Join(dir, "main.less")
+	for _, want := range []string{"width: 1px", "width: 2px"} {
+		result, err := compiler.CompileFile(main)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !strings.Contains(result.CSS, want) {
+			t.Errorf("CSS should contain %q: %s", want, result.CSS)
+		}
+		if got := compiler.Invalidate(filepath.Join(dir, "answer.js")); len(got) != 1 || got[0] != main {
+			t.Errorf("editing the plugin should affect %s, got %v", main, got)
+		}
+		writeFile("answer.js", `functions.add('answer', function() { return less.dimension(2, 'px'); });`)
+	}
+}
+
+func TestCompiler_WithOptionsSharesCache(t *testing.T) {
+	fsys := fstest.MapFS{
+		"lib.less":  &fstest.MapFile{Data: []byte(`@w: 10px; .lib { width: @w; }`)},
+		"main.less": &fstest.MapFile{Data: []byte(`@import "lib";`)},
+	}
+	compiler := NewCompiler(&CompileOptions{FS: fsys})
+	compressed := compiler.WithOptions(&CompileOptions{FS: fsys, Compress: true, ModifyVars: map[string]any{"w": "5px"}})
+
+	result, err := compiler.CompileFile("main.less")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(result.CSS, "width: 10px") {
+		t.Errorf("unexpected CSS: %s", result.CSS)
+	}
+	result, err = compressed.CompileFile("main.less")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.CSS != ".lib{width:5px}" {
+		t.Errorf("derived Compiler should use its own options: %s", result.CSS)
+	}
+
+	if n := len(compiler.cache.entries); n != 1 {
+		t.Errorf("expected the parsed import to be shared, got %d cache entries", n)
+	}
+	if got := compiler.Invalidate("lib.less"); len(got) != 1 || got[0] != "main.less" {
+		t.Errorf("Invalidate should see compilations of the derived Compiler, got %v", got)
+	}
+}
+
+func TestCompiler_CacheKeepsParseOptionsApart(t *testing.T) {
+	// Compilers derived with WithOptions share the cache, so a tree parsed with
+	// line numbers must not be served to a compilation without them
+	cache := newImportCache()
+	parse := func(dumpLineNumbers any) *Ruleset {
+		context := map[string]any{
+			"paths":       []string{},
+			"syncImport":  true,
+			"importCache": cache,
+			"parserFactory": func(context map[string]any, imports map[string]any, fileInfo map[string]any, index int) ParserInterface {
+				return NewParser(context, imports, fileInfo, index)
+			},
+		}
+		if dumpLineNumbers != nil {
+			context["dumpLineNumbers"] = dumpLineNumbers
+		}
+		im := NewImportManager(createMockEnvironment())(map[string]any{}, context, createMockRootFileInfo())
+		var root *Ruleset
+		im.Push("/lib.less", true, &FileInfo{CurrentDirectory: "/"}, &ImportOptions{}, func(err error, r any, _ bool, _ string) {
+			if err != nil {
+				t.Fatal(err)
+			}
+			root, _ = r.(*Ruleset)
+		})
+		if root == nil || len(root.Rules) != 1 {
+			t.Fatalf("expected the parsed file, got %v", root)
+		}
+		return root.Rules[0].(*Ruleset)
+	}
+
+	if ruleset := parse("comments"); ruleset.DebugInfo == nil {
+		t.Error("expected debug info with dumpLineNumbers")
+	}
+	if ruleset := parse(nil); ruleset.DebugInfo != nil {
+		t.Error("a tree parsed with dumpLineNumbers was served to a compilation without it")
+	}
+	if ruleset := parse("comments"); ruleset.DebugInfo == nil {
+		t.Error("expected debug info from the cached tree")
+	}
+	if n := len(cache.entries); n != 2 {
+		t.Errorf("expected one cached tree per option set, got %d", n)
+	}
+}
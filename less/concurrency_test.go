@@ -0,0 +1,295 @@
+package less_go
+
+import (
+	"fmt"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+)
+
+// These tests are meant to be run with the race detector, as `make test-race`
+// does: concurrent compilations must neither race nor see each other's
+// variables, functions, warnings or errors.
+
+// corpusJob is a compilation of a stylesheet of the test suite with one of the
+// option sets, alone or through a Compiler shared by the jobs of its options
+type corpusJob struct {
+	name     string
+	source   string
+	options  CompileOptions
+	compiler *Compiler
+}
+
+// corpusResult is the CSS, number of warnings and error of a compilation
+type corpusResult struct {
+	css      string
+	warnings int
+	err      string
+}
+
+func (j *corpusJob) compile() corpusResult {
+	var result *CompileResult
+	var err error
+	if j.compiler != nil {
+		result, err = j.compiler.Compile(j.source, j.options.Filename)
+	} else {
+		options := j.options
+		result, err = Compile(j.source, &options)
+	}
+	if err != nil {
+		return corpusResult{err: err.Error()}
+	}
+	return corpusResult{result.CSS, len(result.Warnings), ""}
+}
+
+func TestCompile_ConcurrentCorpus(t *testing.T) {
+	files, err := filepath.Glob("../testdata/less/_main/*.less")
+	if err != nil || len(files) == 0 {
+		t.Skip("test data not found")
+	}
+	variants := []CompileOptions{
+		{Math: Math.ParensDivision},
+		{Math: Math.Always, RewriteUrls: RewriteUrlsAll, UrlArgs: "v=2", StrictUnits: true},
+		{Math: Math.ParensDivision, CollectErrors: true},
+	}
+	compilers := make([]*Compiler, len(variants))
+	for i := range variants {
+		compilers[i] = NewCompiler(&variants[i])
+	}
+	var jobs []*corpusJob
+	for _, file := range files {
+		src, err := os.ReadFile(file)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if strings.Contains(string(src), "https:") || strings.Contains(string(src), "@plugin") {
+			continue // remote imports, or plugins needing Node.js
+		}
+		filename, _ := filepath.Abs(file)
+		for i, options := range variants {
+			options.Filename = filename
+			name := fmt.Sprintf("%s/%d", filepath.Base(file), i)
+			jobs = append(jobs,
+				&corpusJob{name: name, source: string(src), options: options},
+				&corpusJob{name: name + "/compiler", source: string(src), options: options, compiler: compilers[i]})
+		}
+	}
+
+	rounds := 3
+	if testing.Short() {
+		rounds = 1
+	}
+	// The jobs run concurrently first, so that lazily initialized state is
+	// initialized concurrently too, and are compared to sequential runs after
+	results := make([][]corpusResult, rounds)
+	for round := range results {
+		results[round] = make([]corpusResult, len(jobs))
+	}
+	type task struct{ round, job int }
+	work := make(chan task)
+	var wg sync.WaitGroup
+	for w := 0; w < max(4, runtime.GOMAXPROCS(0)); w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for task := range work {
+				results[task.round][task.job] = jobs[task.job].compile()
+			}
+		}()
+	}
+	for round := 0; round < rounds; round++ {
+		// Interleave the stylesheets differently each round
+		for _, i := range rand.New(rand.NewSource(int64(round))).Perm(len(jobs)) {
+			work <- task{round, i}
+		}
+	}
+	close(work)
+	wg.Wait()
+
+	for i, job := range jobs {
+		want := job.compile()
+		for round := range results {
+			got := results[round][i]
+			if got.err != want.err {
+				t.Errorf("%s: got error %q, want %q", job.name, got.err, want.err)
+			} else if got.css != want.css {
+				t.Errorf("%s: compiles differently when run concurrently", job.name)
+			} else if got.warnings != want.warnings {
+				t.Errorf("%s: got %d warnings, want %d", job.name, got.warnings, want.warnings)
+			}
+		}
+	}
+}
+
+func TestCompile_ConcurrentTenants(t *testing.T) {
+	theme := `@brand: black;
+.button {
+  color: @brand;
+  border-color: darken(@brand, 10%);
+  content: tenant-name();
+  &:extend(.base all);
+  &:extend(.missing);
+}
+.base { margin: 0; }
+`
+	tenants := []string{"#336699", "#993366", "#669933", "#aa5500", "#0055aa", "#5500aa", "#00aa55", "#aa0055"}
+	var wg sync.WaitGroup
+	for round := 0; round < 8; round++ {
+		for i, color := range tenants {
+			wg.Add(1)
+			go func(name, color string) {
+				defer wg.Done()
+				var warnings []Diagnostic
+				result, err := Compile(theme, &CompileOptions{
+					Filename:   name + ".less",
+					ModifyVars: map[string]any{"brand": color},
+					Functions: map[string]FunctionDefinition{
+						"tenant-name": Function(func(ctx *Context, args ...any) (any, error) {
+							return NewQuoted(`"`, name, false, 0, nil), nil
+						}),
+					},
+					OnDiagnostic: func(d Diagnostic) { warnings = append(warnings, d) },
+				})
+				if err != nil {
+					t.Errorf("%s: %v", name, err)
+					return
+				}
+				want := fmt.Sprintf("color: %s;", color)
+				if !strings.Contains(result.CSS, want) || !strings.Contains(result.CSS, `content: "`+name+`";`) {
+					t.Errorf("%s: expected its own color and name:\n%s", name, result.CSS)
+				}
+				for _, other := range tenants {
+					if other != color && strings.Contains(result.CSS, other) {
+						t.Errorf("%s: got the color of another tenant, %s:\n%s", name, other, result.CSS)
+					}
+				}
+				if len(warnings) == 0 {
+					t.Errorf("%s: expected the warning of the unmatched extend", name)
+				}
+				for _, d := range append(warnings, result.Warnings...) {
+					if d.Filename != name+".less" {
+						t.Errorf("%s: got a warning of %s: %s", name, d.Filename, d.Message)
+					}
+				}
+			}(fmt.Sprintf("tenant%d", i), color)
+		}
+	}
+	wg.Wait()
+}
+
+// countingListener counts the warnings logged to DefaultLogger, from any goroutine
+type countingListener struct {
+	warnings atomic.Int32
+}
+
+func (l *countingListener) Error(msg any) {}
+func (l *countingListener) Warn(msg any)  { l.warnings.Add(1) }
+func (l *countingListener) Info(msg any)  {}
+func (l *countingListener) Debug(msg any) {}
+
+func TestCompile_ConcurrentAPIs(t *testing.T) {
+	source := `@brand: black;
+@space: 4px;
+.a { color: @brand; margin: @space * 2; }
+`
+	var wg sync.WaitGroup
+	for i := 0; i < 16; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			color := fmt.Sprintf("#0000%02x", i)
+			tenant := fmt.Sprintf("tenant%d", i)
+
+			sheet, err := ParseStylesheet(source, &CompileOptions{Filename: tenant + ".less"})
+			if err != nil || sheet.Filename != tenant+".less" || !strings.Contains(sheet.String(), "@space * 2") {
+				t.Errorf("%s: unexpected stylesheet %v, %v", tenant, sheet, err)
+			}
+
+			variables, err := ResolveVariables(source, &CompileOptions{ModifyVars: map[string]any{"brand": color}})
+			var brand string
+			for _, v := range variables {
+				if v.Name == "@brand" {
+					brand = v.CSS
+				}
+			}
+			if err != nil || brand != color {
+				t.Errorf("%s: expected @brand to be %s, got %q, %v", tenant, color, brand, err)
+			}
+
+			// A listener of DefaultLogger, which the deprecated compress option warns to
+			listener := &countingListener{}
+			AddListener(listener)
+			defer RemoveListener(listener)
+
+			plugin := GoPluginFunc(func(pm *PluginManager) error {
+				pm.AddPostProcessor(postProcessorFunc(func(css string, extra map[string]any) (string, error) {
+					return "/* " + tenant + " */\n" + css, nil
+				}), 1)
+				return nil
+			})
+			result, err := Compile(source, &CompileOptions{
+				ModifyVars: map[string]any{"brand": color},
+				GoPlugins:  []GoPlugin{plugin},
+				Compress:   i%2 == 0,
+			})
+			if err != nil {
+				t.Errorf("%s: %v", tenant, err)
+				return
+			}
+			if !strings.HasPrefix(result.CSS, "/* "+tenant+" */\n") || !strings.Contains(result.CSS, color) {
+				t.Errorf("%s: unexpected CSS:\n%s", tenant, result.CSS)
+			}
+			if i%2 == 0 && listener.warnings.Load() == 0 {
+				t.Errorf("%s: expected the listener to get the compress warning", tenant)
+			}
+		}(i)
+	}
+	wg.Wait()
+}
+
+func TestCompiler_ConcurrentPlugins(t *testing.T) {
+	files, _ := filepath.Glob("../testdata/less/_main/plugin*.less")
+	if len(files) == 0 {
+		t.Skip("plugin tests not available")
+	}
+	options := CompileOptions{EnableJavaScriptPlugins: true, JavascriptEnabled: true}
+	compiler := NewCompiler(&options)
+	defer compiler.Close()
+
+	// Each stylesheet loads its own plugins on the runtimes the Compiler shares
+	jobs := make([]*corpusJob, 0, len(files)*3)
+	for round := 0; round < 3; round++ {
+		for _, file := range files {
+			src, err := os.ReadFile(file)
+			if err != nil {
+				t.Fatal(err)
+			}
+			opts := options
+			opts.Filename = file
+			jobs = append(jobs, &corpusJob{name: filepath.Base(file), source: string(src), options: opts, compiler: compiler})
+		}
+	}
+	results := make([]corpusResult, len(jobs))
+	var wg sync.WaitGroup
+	for i, job := range jobs {
+		wg.Add(1)
+		go func(i int, job *corpusJob) {
+			defer wg.Done()
+			results[i] = job.compile()
+		}(i, job)
+	}
+	wg.Wait()
+
+	for i, job := range jobs {
+		alone := *job
+		alone.compiler = nil
+		if want := alone.compile(); results[i] != want {
+			t.Errorf("%s: compiles differently when run concurrently: got error %q, want %q", job.name, results[i].err, want.err)
+		}
+	}
+}
@@ -16,7 +16,15 @@ const (
 	RewriteUrlsAll
 )
 
+type ModernColorsType int
+
+const (
+	ModernColorsOff ModernColorsType = iota
+	ModernColorsSRGBHex
+)
+
 var (
-	Math       = struct{ Always, ParensDivision, Parens MathType }{MathAlways, MathParensDivision, MathParens}
-	RewriteUrls = struct{ Off, Local, All RewriteUrlsType }{RewriteUrlsOff, RewriteUrlsLocal, RewriteUrlsAll}
-) 
\ No newline at end of file
+	Math         = struct{ Always, ParensDivision, Parens MathType }{MathAlways, MathParensDivision, MathParens}
+	RewriteUrls  = struct{ Off, Local, All RewriteUrlsType }{RewriteUrlsOff, RewriteUrlsLocal, RewriteUrlsAll}
+	ModernColors = struct{ Off, SRGBHex ModernColorsType }{ModernColorsOff, ModernColorsSRGBHex}
+)
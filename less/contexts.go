@@ -1,7 +1,9 @@
 package less_go
 
 import (
+	"context"
 	"fmt"
+	"io/fs"
 	"os"
 	"strings"
 	"sync"
@@ -45,6 +47,13 @@ func GetEvalFromPool(source *Eval, frames []any) *Eval {
 	e.MediaPath = nil
 	e.PluginBridge = source.PluginBridge
 	e.LazyPluginBridge = source.LazyPluginBridge
+	e.Context = source.Context
+	e.FS = source.FS
+	e.FileManagers = source.FileManagers
+	e.Dependencies = source.Dependencies
+	e.diagnostics = source.diagnostics
+	e.errors = source.errors
+	e.calls = source.calls
 	e.cachedInParenthesis = nil
 	e.cachedOutOfParenthesis = nil
 	e.cachedIsMathOn = nil
@@ -72,6 +81,13 @@ func PutEvalToPool(e *Eval) {
 	e.PluginManager = nil
 	e.PluginBridge = nil
 	e.LazyPluginBridge = nil
+	e.Context = nil
+	e.FS = nil
+	e.FileManagers = nil
+	e.Dependencies = nil
+	e.diagnostics = nil
+	e.errors = nil
+	e.calls = nil
 	e.cachedInParenthesis = nil
 	e.cachedOutOfParenthesis = nil
 	e.cachedIsMathOn = nil
@@ -186,6 +202,21 @@ type Eval struct {
 	PluginBridge     *NodeJSPluginBridge
 	LazyPluginBridge *LazyNodeJSPluginBridge // Lazy bridge for deferred initialization
 
+	// Context carries cancellation and deadlines for the compilation (nil means never cancelled)
+	Context context.Context
+	// FS is the virtual file system used by data-uri and image-size instead of disk (nil means disk)
+	FS fs.FS
+	// FileManagers are custom file managers consulted by data-uri and image-size before the file system
+	FileManagers []FileManager
+	// Dependencies collects the files read by data-uri and image-size
+	Dependencies *DependencyGraph
+	// diagnostics collects the warnings of the compilation
+	diagnostics *diagnosticCollector
+	// errors collects the errors of the compilation when CollectErrors is set
+	errors *errorCollector
+	// calls is the stack of calls being evaluated, for the Frames of errors
+	calls *callStack
+
 	// Cached closures to avoid allocations in CopyEvalToMap
 	cachedInParenthesis    func()
 	cachedOutOfParenthesis func()
@@ -247,6 +278,13 @@ func NewEvalFromEval(parent *Eval, frames []any) *Eval {
 		MediaPath:         parent.MediaPath,
 		PluginBridge:      parent.PluginBridge,
 		LazyPluginBridge:  parent.LazyPluginBridge,
+		Context:           parent.Context,
+		FS:                parent.FS,
+		FileManagers:      parent.FileManagers,
+		Dependencies:      parent.Dependencies,
+		diagnostics:       parent.diagnostics,
+		errors:            parent.errors,
+		calls:             parent.calls,
 	}
 }
 
@@ -318,6 +356,13 @@ func (e *Eval) ToMap() map[string]any {
 		"outOfParenthesis":  e.cachedOutOfParenthesis,
 		"isMathOn":          e.cachedIsMathOn,
 		"inCalc":            e.InCalc,
+		"ctx":               e.Context,
+		"fs":                e.FS,
+		"fileManagers":      e.FileManagers,
+		"dependencies":      e.Dependencies,
+		"diagnostics":       e.diagnostics,
+		"errors":            e.errors,
+		"callStack":         e.calls,
 	}
 }
 
@@ -338,6 +383,27 @@ func (e *Eval) CopyEvalToMap(target map[string]any, includeMediaContext bool) {
 	target["inCalc"] = e.InCalc
 	target["mathOn"] = e.MathOn
 	target["_evalContext"] = e
+	if e.Context != nil {
+		target["ctx"] = e.Context
+	}
+	if e.FS != nil {
+		target["fs"] = e.FS
+	}
+	if e.FileManagers != nil {
+		target["fileManagers"] = e.FileManagers
+	}
+	if e.Dependencies != nil {
+		target["dependencies"] = e.Dependencies
+	}
+	if e.diagnostics != nil {
+		target["diagnostics"] = e.diagnostics
+	}
+	if e.errors != nil {
+		target["errors"] = e.errors
+	}
+	if e.calls != nil {
+		target["callStack"] = e.calls
+	}
 
 	// Use cached closures to avoid allocations
 	if e.cachedInParenthesis == nil {
@@ -714,9 +780,71 @@ func copyFromOriginal(original map[string]any, destination any) {
 		} else if lazyBridge, ok := original["pluginBridge"].(*LazyNodeJSPluginBridge); ok {
 			d.LazyPluginBridge = lazyBridge
 		}
+		if ctx, ok := original["ctx"].(context.Context); ok {
+			d.Context = ctx
+		}
+		if fsys, ok := original["fs"].(fs.FS); ok {
+			d.FS = fsys
+		}
+		if fileManagers, ok := original["fileManagers"].([]FileManager); ok {
+			d.FileManagers = fileManagers
+		}
+		if dependencies, ok := original["dependencies"].(*DependencyGraph); ok {
+			d.Dependencies = dependencies
+		}
+		if diagnostics, ok := original["diagnostics"].(*diagnosticCollector); ok {
+			d.diagnostics = diagnostics
+		}
+		if errs, ok := original["errors"].(*errorCollector); ok {
+			d.errors = errs
+		}
+		if calls, ok := original["callStack"].(*callStack); ok {
+			d.calls = calls
+		}
 	}
 }
 
+// Err returns the cancellation error of the compilation this context belongs to,
+// or nil if it is still running.
+func (e *Eval) Err() error {
+	if e == nil || e.Context == nil {
+		return nil
+	}
+	return e.Context.Err()
+}
+
+// evalContextErr returns the cancellation error for either an *Eval or a map context.
+// Map contexts carry the cancellation context directly or via their _evalContext.
+func evalContextErr(ctx any) error {
+	switch c := ctx.(type) {
+	case *Eval:
+		return c.Err()
+	case map[string]any:
+		if evalCtx, ok := c["_evalContext"].(*Eval); ok {
+			return evalCtx.Err()
+		}
+		return optionsContextErr(c)
+	}
+	return nil
+}
+
+// optionsContext returns the cancellation context stored under "ctx" in an options map,
+// or nil if the compilation was started without one.
+func optionsContext(options map[string]any) context.Context {
+	if ctx, ok := options["ctx"].(context.Context); ok {
+		return ctx
+	}
+	return nil
+}
+
+// optionsContextErr returns the cancellation error stored under "ctx" in an options map.
+func optionsContextErr(options map[string]any) error {
+	if ctx := optionsContext(options); ctx != nil {
+		return ctx.Err()
+	}
+	return nil
+}
+
 func (e *Eval) EnterPluginScope() any {
 	if e.PluginBridge != nil {
 		return e.PluginBridge.EnterScope()
@@ -804,6 +932,13 @@ func (e *Eval) NewMixinEvalContext(frames []any) *Eval {
 		// where mixin body evaluation gets a fresh media context
 		PluginBridge:     e.PluginBridge,
 		LazyPluginBridge: e.LazyPluginBridge,
+		Context:          e.Context,
+		FS:               e.FS,
+		FileManagers:     e.FileManagers,
+		Dependencies:     e.Dependencies,
+		diagnostics:      e.diagnostics,
+		errors:           e.errors,
+		calls:            e.calls,
 	}
 }
 
@@ -834,6 +969,13 @@ func (e *Eval) CopyWithFrames(frames []any) *Eval {
 		MediaPath:         e.MediaPath,
 		PluginBridge:      e.PluginBridge,
 		LazyPluginBridge:  e.LazyPluginBridge,
+		Context:           e.Context,
+		FS:                e.FS,
+		FileManagers:      e.FileManagers,
+		Dependencies:      e.Dependencies,
+		diagnostics:       e.diagnostics,
+		errors:            e.errors,
+		calls:             e.calls,
 	}
 }
 
@@ -0,0 +1,60 @@
+package less_go
+
+import (
+	"bufio"
+	"io"
+)
+
+// cssSink is what the generated CSS is written to: a strings.Builder, or a
+// cssWriter when the CSS is streamed. Len is the number of bytes written so far.
+type cssSink interface {
+	io.Writer
+	io.StringWriter
+	Len() int
+}
+
+// cssWriter streams the chunks of generated CSS to w through a buffer, so that
+// the many small chunks don't each make a write. After a write fails, the
+// chunks are dropped and Flush returns the error.
+type cssWriter struct {
+	w   *bufio.Writer
+	n   int
+	err error
+}
+
+func newCSSWriter(w io.Writer) *cssWriter {
+	return &cssWriter{w: bufio.NewWriterSize(w, 64*1024)}
+}
+
+func (cw *cssWriter) WriteString(s string) (int, error) {
+	if cw.err != nil {
+		return 0, cw.err
+	}
+	n, err := cw.w.WriteString(s)
+	cw.n += n
+	cw.err = err
+	return n, err
+}
+
+func (cw *cssWriter) Write(p []byte) (int, error) {
+	if cw.err != nil {
+		return 0, cw.err
+	}
+	n, err := cw.w.Write(p)
+	cw.n += n
+	cw.err = err
+	return n, err
+}
+
+func (cw *cssWriter) Len() int {
+	return cw.n
+}
+
+// Flush writes the buffered CSS to w and returns the first write error
+func (cw *cssWriter) Flush() error {
+	if cw.err != nil {
+		return cw.err
+	}
+	cw.err = cw.w.Flush()
+	return cw.err
+}
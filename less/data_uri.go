@@ -8,6 +8,7 @@ import (
 	_ "image/gif"
 	_ "image/jpeg"
 	_ "image/png"
+	"io/fs"
 	"net/url"
 	"path/filepath"
 	"strings"
@@ -318,6 +319,10 @@ func (w *ImageHeightFunctionWrapper) NeedsEvalArgs() bool {
 func buildContextMap(ctx *Context) map[string]any {
 	contextMap := make(map[string]any)
 	var paths []string
+	var fsys fs.FS
+	var fileManagers []FileManager
+	var dependencies *DependencyGraph
+	var diagnostics *diagnosticCollector
 	var currentFileInfo map[string]any
 	var currentDirectory string
 
@@ -325,6 +330,10 @@ func buildContextMap(ctx *Context) map[string]any {
 		frame := ctx.Frames[0]
 		if evalCtx, ok := frame.EvalContext.(*Eval); ok {
 			paths = evalCtx.Paths
+			fsys = evalCtx.FS
+			fileManagers = evalCtx.FileManagers
+			dependencies = evalCtx.Dependencies
+			diagnostics = evalCtx.diagnostics
 		}
 		if frame.CurrentFileInfo != nil {
 			currentFileInfo = frame.CurrentFileInfo
@@ -345,20 +354,38 @@ func buildContextMap(ctx *Context) map[string]any {
 	contextMap["paths"] = paths
 	contextMap["currentFileInfo"] = currentFileInfo
 	contextMap["index"] = 0
-	contextMap["environment"] = createGoEnvironment()
+	importer, _ := currentFileInfo["filename"].(string)
+	contextMap["environment"] = createGoEnvironment(fsys, fileManagers, dependencies, importer)
+	if diagnostics != nil {
+		contextMap["logger"] = map[string]any{
+			"warn": func(msg string) {
+				diagnostics.warn(Diagnostic{Code: DiagnosticDataURINotFound, Message: msg, Filename: importer})
+			},
+		}
+	}
 	return contextMap
 }
 
-func createGoEnvironment() map[string]any {
-	fileManager := NewFileSystemFileManager()
+// createGoEnvironment builds the environment used by data-uri and image-size.
+// Files are read from fsys when it is non-nil, otherwise from disk, unless one
+// of fileManagers supports the file. Files read are recorded in dependencies
+// as assets of importer.
+func createGoEnvironment(fsys fs.FS, fileManagers []FileManager, dependencies *DependencyGraph, importer string) map[string]any {
+	fsManager := NewFileSystemFileManager()
+	fsManager.FS = fsys
 	return map[string]any{
-		"getFileManager": func(filename, currentDirectory string, context, environment map[string]any, isReference bool) any {
+		"getFileManager": func(filename, currentDirectory string, context, environment map[string]any, isSync bool) any {
+			fileManager := getFileManager(append([]FileManager{fsManager}, fileManagers...), filename, currentDirectory, context, isSync)
+			if fileManager == nil {
+				return nil
+			}
 			return map[string]any{
 				"loadFileSync": func(filename, currentDirectory string, context, environment map[string]any) map[string]any {
 					result := fileManager.LoadFileSync(filename, currentDirectory, context, nil)
-					if result.Message != "" {
+					if result == nil || result.Message != "" {
 						return nil
 					}
+					dependencies.add(Dependency{File: result.Filename, Importer: importer, Kind: DependencyAsset})
 					return map[string]any{
 						"contents": result.Contents,
 						"filename": result.Filename,
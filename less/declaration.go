@@ -310,7 +310,10 @@ func (d *Declaration) Eval(context any) (any, error) {
 	// Evaluate value
 	var evaldValue any
 	var err error
+	errs := errorCollectorOf(context)
+	recorded := errs.count()
 	evaldValue, err = d.Value.Eval(context)
+	errs.locate(recorded, d.GetIndex(), getFilename(d.FileInfo()))
 	if err != nil {
 		return nil, err
 	}
@@ -443,13 +446,9 @@ func (d *Declaration) GenCSS(context any, output *CSSOutput) {
 	// Use defer/recover to catch panics and convert to proper errors
 	defer func() {
 		if r := recover(); r != nil {
-			// Convert panic to error with proper index and filename information
-			var errMsg string
-			switch e := r.(type) {
-			case error:
-				errMsg = e.Error()
-			default:
-				errMsg = fmt.Sprintf("%v", e)
+			// Internal errors are located where they are recovered
+			if isInternalPanic(r) {
+				panic(r)
 			}
 
 			// Create an error with index and filename similar to JavaScript
@@ -460,6 +459,23 @@ func (d *Declaration) GenCSS(context any, output *CSSOutput) {
 				}
 			}
 
+			// Less errors keep their type, at the declaration unless located
+			if lessErr, ok := panicLessError(r, filename, d.GetIndex()); ok {
+				if lessErr.Index == nil {
+					lessErr.Filename, lessErr.Index = filename, d.GetIndex()
+				}
+				panic(lessErr)
+			}
+
+			// Convert panic to error with proper index and filename information
+			var errMsg string
+			switch e := r.(type) {
+			case error:
+				errMsg = e.Error()
+			default:
+				errMsg = fmt.Sprintf("%v", e)
+			}
+
 			// Re-panic with enhanced error message
 			panic(fmt.Errorf("%s (index: %d, filename: %s)", errMsg, d.GetIndex(), filename))
 		}
@@ -0,0 +1,135 @@
+package less_go
+
+import (
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// DependencyKind says how a file is used by the file that depends on it
+type DependencyKind int
+
+const (
+	// DependencyImport is a Less or CSS file pulled in with @import
+	DependencyImport DependencyKind = iota
+	// DependencyAsset is a file read by data-uri() or image-size()
+	DependencyAsset
+	// DependencyPlugin is a script loaded with @plugin
+	DependencyPlugin
+)
+
+func (k DependencyKind) String() string {
+	switch k {
+	case DependencyAsset:
+		return "asset"
+	case DependencyPlugin:
+		return "plugin"
+	default:
+		return "import"
+	}
+}
+
+// MarshalText encodes the kind as "import", "asset" or "plugin"
+func (k DependencyKind) MarshalText() ([]byte, error) {
+	return []byte(k.String()), nil
+}
+
+// Dependency is an edge of the dependency graph: Importer uses File
+type Dependency struct {
+	// File is the resolved path of the imported file or asset
+	File string `json:"file"`
+	// Importer is the file containing the @import, @plugin or data-uri() call
+	Importer string         `json:"importer"`
+	Kind     DependencyKind `json:"kind"`
+
+	// Import options, as in @import (reference, inline, optional, multiple)
+	Reference bool `json:"reference,omitempty"`
+	Inline    bool `json:"inline,omitempty"`
+	Optional  bool `json:"optional,omitempty"`
+	Multiple  bool `json:"multiple,omitempty"`
+}
+
+// DependencyGraph records every file a compilation read and which file pulled
+// it in. All files in the graph are transitive dependencies of Root.
+type DependencyGraph struct {
+	// Root is the filename of the compiled entrypoint
+	Root         string       `json:"root"`
+	Dependencies []Dependency `json:"dependencies"`
+
+	mu sync.Mutex
+}
+
+// NewDependencyGraph creates an empty graph for the entrypoint root
+func NewDependencyGraph(root string) *DependencyGraph {
+	return &DependencyGraph{Root: root, Dependencies: []Dependency{}}
+}
+
+// add records dep unless the same edge is already known
+func (g *DependencyGraph) add(dep Dependency) {
+	if g == nil || dep.File == "" {
+		return
+	}
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	for _, existing := range g.Dependencies {
+		if existing.File == dep.File && existing.Importer == dep.Importer && existing.Kind == dep.Kind {
+			return
+		}
+	}
+	g.Dependencies = append(g.Dependencies, dep)
+}
+
+// Files returns the entrypoint and every file it depends on, sorted
+func (g *DependencyGraph) Files() []string {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	seen := map[string]bool{}
+	files := []string{}
+	if g.Root != "" {
+		seen[g.Root] = true
+		files = append(files, g.Root)
+	}
+	for _, dep := range g.Dependencies {
+		if !seen[dep.File] {
+			seen[dep.File] = true
+			files = append(files, dep.File)
+		}
+	}
+	sort.Strings(files)
+	return files
+}
+
+// Importers returns the files that directly import, load or read file
+func (g *DependencyGraph) Importers(file string) []Dependency {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	key := dependencyKey(file)
+	var importers []Dependency
+	for _, dep := range g.Dependencies {
+		if dependencyKey(dep.File) == key {
+			importers = append(importers, dep)
+		}
+	}
+	return importers
+}
+
+// DependsOn reports whether the entrypoint is file or depends on it, directly or transitively
+func (g *DependencyGraph) DependsOn(file string) bool {
+	key := dependencyKey(file)
+	if g.Root != "" && dependencyKey(g.Root) == key {
+		return true
+	}
+	return len(g.Importers(file)) > 0
+}
+
+// dependencyKey normalizes a path so that the same file is matched however it was spelled
+func dependencyKey(path string) string {
+	if strings.Contains(path, "://") {
+		return path
+	}
+	if abs, err := filepath.Abs(path); err == nil {
+		return abs
+	}
+	return filepath.Clean(path)
+}
@@ -146,6 +146,8 @@ func (dr *DetachedRuleset) CallEval(context any) any {
 				DefaultFunc:       ctx.DefaultFunc,
 				PluginBridge:      ctx.PluginBridge,
 				LazyPluginBridge:  ctx.LazyPluginBridge,
+				errors:            ctx.errors,
+				calls:             ctx.calls,
 				// MediaBlocks: nil - intentionally not copied, see comment above
 				// MediaPath: nil - intentionally not copied, see comment above
 			}
@@ -0,0 +1,166 @@
+package less_go
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+)
+
+// Warning codes of Diagnostic
+const (
+	// DiagnosticCompressDeprecated is reported when the compress option is used
+	DiagnosticCompressDeprecated = "compress-deprecated"
+	// DiagnosticExtendComplexSelector is reported for :extend targeting a complex selector
+	DiagnosticExtendComplexSelector = "extend-complex-selector"
+	// DiagnosticExtendNoMatch is reported for an :extend that matched nothing
+	DiagnosticExtendNoMatch = "extend-no-match"
+	// DiagnosticDotSlashDeprecated is reported for the ./ division operator
+	DiagnosticDotSlashDeprecated = "dot-slash-deprecated"
+	// DiagnosticMixinCallWhitespace is reported for whitespace between a mixin name and its parentheses
+	DiagnosticMixinCallWhitespace = "mixin-call-whitespace-deprecated"
+	// DiagnosticMixinCallNoParens is reported for a mixin call without parentheses
+	DiagnosticMixinCallNoParens = "mixin-call-no-parens-deprecated"
+	// DiagnosticDataURINotFound is reported when data-uri falls back to url() because the file is missing
+	DiagnosticDataURINotFound = "data-uri-not-found"
+	// DiagnosticLowContrast is reported for a ruleset whose color and background
+	// have a contrast ratio below MinContrastRatio or that of @accessibility
+	DiagnosticLowContrast = "low-contrast"
+	// DiagnosticUndefinedVariable is reported for an undefined variable, which
+	// is output as written unless CollectErrors is set
+	DiagnosticUndefinedVariable = "undefined-variable"
+)
+
+// Diagnostic is a warning reported by a compilation
+type Diagnostic struct {
+	// Code identifies the kind of warning, one of the Diagnostic* constants
+	Code string `json:"code"`
+	// Message describes the problem
+	Message string `json:"message"`
+	// Filename is the file the warning is about, empty if it concerns the whole compilation
+	Filename string `json:"filename,omitempty"`
+	// Line and Column are 1-based, or 0 when the position is not known
+	Line   int `json:"line,omitempty"`
+	Column int `json:"column,omitempty"`
+}
+
+// String formats the diagnostic as "file:line:column: warning: message [code]"
+func (d Diagnostic) String() string {
+	location := d.Filename
+	if location != "" && d.Line > 0 {
+		location = fmt.Sprintf("%s:%d:%d", location, d.Line, d.Column)
+	}
+	if location != "" {
+		location += ": "
+	}
+	return fmt.Sprintf("%swarning: %s [%s]", location, d.Message, d.Code)
+}
+
+// diagnosticCollector gathers the warnings of one compilation and passes each
+// to the OnDiagnostic callback as it is reported. A nil collector drops them.
+type diagnosticCollector struct {
+	mu           sync.Mutex
+	diagnostics  []Diagnostic
+	onDiagnostic func(Diagnostic)
+	// contents are the sources of the compilation, to locate the warnings of
+	// evaluation
+	contents map[string]string
+	// undefined holds the positions of the undefined names reported so far
+	undefined map[string]bool
+}
+
+func newDiagnosticCollector(onDiagnostic func(Diagnostic)) *diagnosticCollector {
+	return &diagnosticCollector{onDiagnostic: onDiagnostic}
+}
+
+// warn records d; it is safe to call on a nil collector
+func (c *diagnosticCollector) warn(d Diagnostic) {
+	if c == nil {
+		return
+	}
+	c.mu.Lock()
+	c.diagnostics = append(c.diagnostics, d)
+	onDiagnostic := c.onDiagnostic
+	c.mu.Unlock()
+	if onDiagnostic != nil {
+		onDiagnostic(d)
+	}
+}
+
+// warnAt records a warning at index in filename, working out the line and
+// column from the file's contents
+func (c *diagnosticCollector) warnAt(code, message, filename string, index int, contents map[string]string) {
+	if c == nil {
+		return
+	}
+	d := Diagnostic{Code: code, Message: message, Filename: filename}
+	if input, ok := contents[filename]; ok && index >= 0 {
+		loc := GetLocation(index, input)
+		if loc.Line != nil {
+			d.Line = *loc.Line + 1
+			d.Column = loc.Column + 1
+		}
+	}
+	c.warn(d)
+}
+
+// warnUndefined reports err, an undefined name output as written, once for
+// each place it is used at, however often that is evaluated
+func (c *diagnosticCollector) warnUndefined(err *LessError) {
+	if c == nil {
+		return
+	}
+	index, ok := err.Index.(int)
+	if !ok {
+		index = -1
+	}
+	position := fmt.Sprintf("%s:%d", err.Filename, index)
+	c.mu.Lock()
+	reported := c.undefined[position]
+	if c.undefined == nil {
+		c.undefined = make(map[string]bool)
+	}
+	c.undefined[position] = true
+	contents := c.contents
+	c.mu.Unlock()
+	if reported {
+		return
+	}
+	message := err.Message
+	if n := len(err.Suggestions); n > 0 {
+		names := err.Suggestions[n-1]
+		if n > 1 {
+			names = strings.Join(err.Suggestions[:n-1], ", ") + " or " + names
+		}
+		message += "; did you mean " + names + "?"
+	}
+	c.warnAt(DiagnosticUndefinedVariable, message, err.Filename, index, contents)
+}
+
+// diagnosticsOf returns the collector of an *Eval or map context, or nil
+func diagnosticsOf(context any) *diagnosticCollector {
+	switch c := context.(type) {
+	case *Eval:
+		return c.diagnostics
+	case map[string]any:
+		if diagnostics, ok := c["diagnostics"].(*diagnosticCollector); ok {
+			return diagnostics
+		}
+		if evalCtx, ok := c["_evalContext"].(*Eval); ok {
+			return evalCtx.diagnostics
+		}
+	}
+	return nil
+}
+
+// list returns the warnings recorded so far
+func (c *diagnosticCollector) list() []Diagnostic {
+	if c == nil {
+		return nil
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if len(c.diagnostics) == 0 {
+		return nil
+	}
+	return append([]Diagnostic(nil), c.diagnostics...)
+}
@@ -317,6 +317,9 @@ type ProcessExtendsVisitor struct {
 	extendIndices     map[string]bool
 	allExtendsStack   [][]*Extend
 	extendChainCount  int
+	// diagnostics receives the warnings for extends without matches, located using contents
+	diagnostics *diagnosticCollector
+	contents    map[string]string
 	// Track Media/AtRule containers we're currently inside for visibility propagation
 	mediaAtRuleStack []any
 }
@@ -353,6 +356,8 @@ func (pev *ProcessExtendsVisitor) Reset() {
 		pev.mediaAtRuleStack[i] = nil
 	}
 	pev.mediaAtRuleStack = pev.mediaAtRuleStack[:0]
+	pev.diagnostics = nil
+	pev.contents = nil
 	// Note: pev.visitor is preserved - its methodLookup is reused
 }
 
@@ -460,7 +465,7 @@ func (pev *ProcessExtendsVisitor) checkExtendsForNonMatched(extendList []*Extend
 		if !extend.HasFoundMatches && len(extend.ParentIds) == 1 {
 			selector := "_unknown_"
 			if extend.Selector != nil {
-				if selectorWithCSS, ok := extend.Selector.(interface{ ToCSS(map[string]any) string }); ok {
+				if selectorWithCSS, ok := extend.Selector.(interface{ ToCSS(any) string }); ok {
 					// Try to generate CSS, but catch any errors (equivalent to JS try/catch)
 					func() {
 						defer func() {
@@ -477,6 +482,8 @@ func (pev *ProcessExtendsVisitor) checkExtendsForNonMatched(extendList []*Extend
 			if !indices[key] {
 				indices[key] = true
 				Warn(fmt.Sprintf("WARNING: extend '%s' has no matches", selector))
+				filename, _ := extend.FileInfo()["filename"].(string)
+				pev.diagnostics.warnAt(DiagnosticExtendNoMatch, fmt.Sprintf("extend '%s' has no matches", selector), filename, extend.GetIndex(), pev.contents)
 			}
 		}
 	}
@@ -642,7 +649,10 @@ func (pev *ProcessExtendsVisitor) doExtendChaining(extendsList []*Extend, extend
 				}
 			}
 			
-			panic(fmt.Sprintf("extend circular reference detected. One of the circular extends is currently:%s:extend(%s)", selectorOne, selectorTwo))
+			panic(&LessError{
+				Type:    "Syntax",
+				Message: fmt.Sprintf("extend circular reference detected. One of the circular extends is currently:%s:extend(%s)", selectorOne, selectorTwo),
+			})
 		}
 
 		// now process the new extends on the existing rules so that we can handle a extending b extending c extending d extending e...
@@ -1,10 +1,13 @@
 package less_go
 
 import (
+	"context"
 	"fmt"
+	"io/fs"
 	"io/ioutil"
 	"net/http"
 	"os"
+	"path"
 	"path/filepath"
 	"strings"
 	"time"
@@ -13,6 +16,10 @@ import (
 // FileSystemFileManager implements a real file manager that loads files from disk
 type FileSystemFileManager struct {
 	AbstractFileManager
+
+	// FS, when set, is read instead of the OS file system (e.g. an embed.FS or fstest.MapFS).
+	// Paths are resolved relative to the FS root.
+	FS fs.FS
 }
 
 // NewFileSystemFileManager creates a new FileSystemFileManager
@@ -20,6 +27,25 @@ func NewFileSystemFileManager() *FileSystemFileManager {
 	return &FileSystemFileManager{}
 }
 
+// fsName converts an OS-style path into a name valid for fs.FS:
+// slash-separated, cleaned and relative to the FS root.
+func fsName(name string) string {
+	name = path.Clean(filepath.ToSlash(name))
+	name = strings.TrimPrefix(name, "/")
+	if name == "" {
+		return "."
+	}
+	return name
+}
+
+// readFile reads a file from FS when set, otherwise from disk
+func (fm *FileSystemFileManager) readFile(name string) ([]byte, error) {
+	if fm.FS != nil {
+		return fs.ReadFile(fm.FS, fsName(name))
+	}
+	return ioutil.ReadFile(name)
+}
+
 // Supports returns true for all files (this manager handles any file)
 func (fm *FileSystemFileManager) Supports(filename, currentDirectory string, options map[string]any, environment map[string]any) bool {
 	return true
@@ -35,8 +61,13 @@ func isRemoteURL(filename string) bool {
 	return strings.HasPrefix(filename, "http://") || strings.HasPrefix(filename, "https://")
 }
 
-// fetchRemoteFile fetches a file from a remote URL with retry logic
-func fetchRemoteFile(url string) *LoadedFile {
+// fetchRemoteFile fetches a file from a remote URL with retry logic.
+// The request and the backoff between retries are abandoned as soon as ctx is done.
+func fetchRemoteFile(ctx context.Context, url string) *LoadedFile {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
 	// Create HTTP client with timeout
 	client := &http.Client{
 		Timeout: 30 * time.Second,
@@ -51,13 +82,31 @@ func fetchRemoteFile(url string) *LoadedFile {
 	for attempt := 0; attempt < maxRetries; attempt++ {
 		if attempt > 0 {
 			// Exponential backoff: 1s, 2s, 4s
-			time.Sleep(time.Duration(1<<uint(attempt-1)) * time.Second)
+			timer := time.NewTimer(time.Duration(1<<uint(attempt-1)) * time.Second)
+			select {
+			case <-timer.C:
+			case <-ctx.Done():
+				timer.Stop()
+				return &LoadedFile{
+					Message: fmt.Sprintf("Failed to fetch remote file: %v", ctx.Err()),
+				}
+			}
+		}
+
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+		if err != nil {
+			return &LoadedFile{
+				Message: fmt.Sprintf("Failed to fetch remote file: %v", err),
+			}
 		}
 
 		// Make GET request
-		resp, err := client.Get(url)
+		resp, err := client.Do(req)
 		if err != nil {
 			lastErr = err
+			if ctx.Err() != nil {
+				break
+			}
 			continue
 		}
 
@@ -104,26 +153,40 @@ func fetchRemoteFile(url string) *LoadedFile {
 
 // resolveNodeModule attempts to resolve a module path using Node.js module resolution algorithm
 // This mimics Node's require.resolve() behavior
-func resolveNodeModule(modulePath, startDir string) (string, error) {
-	// Clean the start directory
-	startDir, err := filepath.Abs(startDir)
-	if err != nil {
-		return "", err
-	}
+// When fsys is non-nil the lookup walks up from startDir inside fsys instead of the OS file system.
+func resolveNodeModule(fsys fs.FS, modulePath, startDir string) (string, error) {
+	stat := os.Stat
+	var searchDirs []string
+
+	if fsys != nil {
+		// There is no working directory inside a virtual file system, so only
+		// walk up from the importing file's directory to the FS root.
+		stat = func(name string) (fs.FileInfo, error) {
+			return fs.Stat(fsys, fsName(name))
+		}
+		searchDirs = []string{"/" + fsName(startDir)}
+	} else {
+		// Clean the start directory
+		var err error
+		startDir, err = filepath.Abs(startDir)
+		if err != nil {
+			return "", err
+		}
 
-	// Get current working directory
-	cwd, err := os.Getwd()
-	if err != nil {
-		cwd = startDir // Fallback to startDir if we can't get CWD
-	}
+		// Get current working directory
+		cwd, err := os.Getwd()
+		if err != nil {
+			cwd = startDir // Fallback to startDir if we can't get CWD
+		}
 
-	// Try to resolve from multiple starting points:
-	// 1. Current working directory (where the process is running)
-	// 2. Start directory (where the file being compiled is located)
-	// This mimics Node's behavior where CWD node_modules is checked first
-	searchDirs := []string{cwd}
-	if startDir != cwd {
-		searchDirs = append(searchDirs, startDir)
+		// Try to resolve from multiple starting points:
+		// 1. Current working directory (where the process is running)
+		// 2. Start directory (where the file being compiled is located)
+		// This mimics Node's behavior where CWD node_modules is checked first
+		searchDirs = []string{cwd}
+		if startDir != cwd {
+			searchDirs = append(searchDirs, startDir)
+		}
 	}
 
 	for _, searchStart := range searchDirs {
@@ -134,14 +197,14 @@ func resolveNodeModule(modulePath, startDir string) (string, error) {
 			tryPath := filepath.Join(currentDir, "node_modules", modulePath)
 
 			// Check if file exists
-			if _, err := os.Stat(tryPath); err == nil {
+			if _, err := stat(tryPath); err == nil {
 				return tryPath, nil
 			}
 
 			// Try with .less extension if no extension present
 			if !strings.Contains(filepath.Base(modulePath), ".") {
 				tryPathWithExt := tryPath + ".less"
-				if _, err := os.Stat(tryPathWithExt); err == nil {
+				if _, err := stat(tryPathWithExt); err == nil {
 					return tryPathWithExt, nil
 				}
 			}
@@ -174,7 +237,7 @@ func isExplicitPath(path string) bool {
 func (fm *FileSystemFileManager) LoadFileSync(filename, currentDirectory string, context map[string]any, environment ImportManagerEnvironment) *LoadedFile {
 	// Check if this is a remote URL
 	if isRemoteURL(filename) {
-		return fetchRemoteFile(filename)
+		return fetchRemoteFile(optionsContext(context), filename)
 	}
 
 	// Debug logging
@@ -250,10 +313,10 @@ func (fm *FileSystemFileManager) LoadFileSync(filename, currentDirectory string,
 		// 1. Path is not explicit (doesn't start with . or /)
 		// 2. We're searching in the current directory (.)
 		if !explicit && dir == "." {
-			resolvedPath, resolveErr := resolveNodeModule(filename, currentDirectory)
+			resolvedPath, resolveErr := resolveNodeModule(fm.FS, filename, currentDirectory)
 			if resolveErr == nil {
 				// Successfully resolved as Node module
-				contents, err = ioutil.ReadFile(resolvedPath)
+				contents, err = fm.readFile(resolvedPath)
 				if err == nil {
 					fullPath = resolvedPath
 					if os.Getenv("LESS_GO_DEBUG") == "1" {
@@ -271,7 +334,7 @@ func (fm *FileSystemFileManager) LoadFileSync(filename, currentDirectory string,
 			if os.Getenv("LESS_GO_DEBUG") == "1" {
 				fmt.Printf("[DEBUG FileManager] Trying with .less extension: %s\n", tryPath)
 			}
-			contents, err = ioutil.ReadFile(tryPath)
+			contents, err = fm.readFile(tryPath)
 			if err == nil {
 				fullPath = tryPath
 				if os.Getenv("LESS_GO_DEBUG") == "1" {
@@ -285,7 +348,7 @@ func (fm *FileSystemFileManager) LoadFileSync(filename, currentDirectory string,
 		if os.Getenv("LESS_GO_DEBUG") == "1" {
 			fmt.Printf("[DEBUG FileManager] Trying as-is: %s\n", fullPath)
 		}
-		contents, err = ioutil.ReadFile(fullPath)
+		contents, err = fm.readFile(fullPath)
 		if err == nil {
 			if os.Getenv("LESS_GO_DEBUG") == "1" {
 				fmt.Printf("[DEBUG FileManager] ✓ Found: %s\n", fullPath)
@@ -127,6 +127,29 @@ func (f *FlexibleFunctionDef) NeedsEvalArgs() bool {
 	return f.needsEval
 }
 
+// Function adapts a Go func to a FunctionDefinition, for CompileOptions.Functions.
+// Arguments are evaluated before fn is called, so they are nodes such as
+// *Dimension, *Color, *Quoted or *Keyword, or *Value and *Expression for comma
+// and space separated lists (GetItemsFromNode returns their items). ctx gives
+// access to the evaluation context in ctx.Frames[0].EvalContext.
+//
+// A result that is not a node is output as text; a nil result outputs the call
+// unchanged, as CSS. An error fails the compilation at the call; it is reported
+// as an Argument error unless it is a *LessError.
+type Function func(ctx *Context, args ...any) (any, error)
+
+func (f Function) Call(args ...any) (any, error) {
+	return f(&Context{}, args...)
+}
+
+func (f Function) CallCtx(ctx *Context, args ...any) (any, error) {
+	return f(ctx, args...)
+}
+
+func (f Function) NeedsEvalArgs() bool {
+	return true
+}
+
 type RegistryFunctionAdapter struct {
 	registry *Registry
 }
@@ -666,6 +666,7 @@ func (i *Import) DoEval(context any) (any, error) {
 	}
 
 	if i.root != nil {
+		callStackOf(context).imported(i, i.importedFilename)
 		// Get rules directly - NewRuleset will copy them internally
 		var rules []any
 		if rootWithRules, ok := i.root.(interface{ GetRules() []any }); ok {
@@ -0,0 +1,400 @@
+package less_go
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"reflect"
+	"sync"
+	"unsafe"
+)
+
+// importCacheKey identifies a parsed import. Besides the resolved filename it
+// includes everything from the import manager's file info that ends up baked
+// into the parsed nodes, and the parser options that change the tree, as
+// Compilers derived with WithOptions share the cache. rootFilename is left out
+// on purpose: it is only forwarded to nested imports, so entrypoints sharing a
+// library can share the library's parse.
+type importCacheKey struct {
+	filename         string
+	rootpath         string
+	currentDirectory string
+	entryPath        string
+	reference        bool
+
+	dumpLineNumbers string // empty when off
+	strictImports   bool
+	processImports  bool
+	chunkInput      bool
+}
+
+// newImportCacheKey returns the key of the file described by fileInfo, parsed
+// with the parser context
+func newImportCacheKey(fileInfo *FileInfo, context map[string]any) importCacheKey {
+	key := importCacheKey{
+		filename:         fileInfo.Filename,
+		rootpath:         fileInfo.Rootpath,
+		currentDirectory: fileInfo.CurrentDirectory,
+		entryPath:        fileInfo.EntryPath,
+		reference:        fileInfo.Reference,
+		processImports:   true,
+	}
+	if dumpLineNumbers, ok := normalizeDumpLineNumbersOption(context["dumpLineNumbers"]); ok {
+		key.dumpLineNumbers = fmt.Sprint(dumpLineNumbers)
+	}
+	key.strictImports, _ = context["strictImports"].(bool)
+	if processImports, ok := context["processImports"].(bool); ok {
+		key.processImports = processImports
+	}
+	key.chunkInput, _ = context["chunkInput"].(bool)
+	return key
+}
+
+type importCacheEntry struct {
+	sum    [sha256.Size]byte
+	root   *Ruleset
+	counts map[reflect.Type]int // nodes per type in root, to preallocate copies
+	// warnings reported while parsing the file, replayed on every hit
+	warnings []Diagnostic
+}
+
+// importCache keeps pristine parse trees of imported files across compilations.
+// Entries are validated against a hash of the file contents, and every hit
+// returns a private deep copy, because the import visitor and evaluation
+// modify the trees they are handed. It is safe for concurrent use.
+type importCache struct {
+	mu      sync.RWMutex
+	entries map[importCacheKey]*importCacheEntry
+}
+
+func newImportCache() *importCache {
+	return &importCache{entries: make(map[importCacheKey]*importCacheEntry)}
+}
+
+// get returns a copy of the tree cached for key if it was parsed from contents,
+// with the warnings of parsing it
+func (c *importCache) get(key importCacheKey, contents string) (*Ruleset, []Diagnostic) {
+	c.mu.RLock()
+	entry := c.entries[key]
+	c.mu.RUnlock()
+	if entry == nil || entry.sum != sha256.Sum256([]byte(contents)) {
+		return nil, nil
+	}
+	return newTreeCloner(entry.counts).cloneAny(entry.root).(*Ruleset), entry.warnings
+}
+
+// put stores a copy of root, parsed from contents with warnings, replacing
+// older versions of the file
+func (c *importCache) put(key importCacheKey, contents string, root *Ruleset, warnings []Diagnostic) {
+	cloner := newTreeCloner(nil)
+	cloner.counts = make(map[reflect.Type]int)
+	entry := &importCacheEntry{
+		sum:      sha256.Sum256([]byte(contents)),
+		root:     cloner.cloneAny(root).(*Ruleset),
+		counts:   cloner.counts,
+		warnings: warnings,
+	}
+	c.mu.Lock()
+	c.entries[key] = entry
+	c.mu.Unlock()
+}
+
+// remove drops the trees of every file for which match returns true
+func (c *importCache) remove(match func(filename string) bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for key := range c.entries {
+		if match(key.filename) {
+			delete(c.entries, key)
+		}
+	}
+}
+
+// newTreeCloner returns a cloner with room for counts nodes of each type
+func newTreeCloner(counts map[reflect.Type]int) *treeCloner {
+	c := &treeCloner{
+		nodes: make(map[treeClonerKey]unsafe.Pointer),
+		maps:  make(map[uintptr]reflect.Value),
+		slabs: make(map[reflect.Type]*nodeSlab, len(counts)),
+	}
+	for t, n := range counts {
+		slab := reflect.MakeSlice(reflect.SliceOf(t), n, n)
+		c.slabs[t] = &nodeSlab{base: slab.UnsafePointer(), size: t.Size(), free: n}
+	}
+	return c
+}
+
+type treeClonerKey struct {
+	addr unsafe.Pointer
+	typ  reflect.Type
+}
+
+// treeCloner deep-copies parse trees. AST nodes (structs embedding Node), the
+// slices, maps and interfaces that hold them and pointers to plain values such
+// as visibility flags are copied; pointers to anything else (registries,
+// managers, contexts) and functions are shared with the original. Node
+// identity is preserved, including pointers into the middle of a node such
+// as Ruleset.Node pointing at its nodeStorage.
+//
+// A tree is copied on every cache hit, so nodes are copied as raw memory using
+// precomputed per-type plans; copying must stay well below the cost of parsing.
+type treeCloner struct {
+	nodes map[treeClonerKey]unsafe.Pointer
+	maps  map[uintptr]reflect.Value
+	// slabs hold preallocated nodes per type, so a copy of a known tree
+	// needs one allocation per node type instead of one per node
+	slabs  map[reflect.Type]*nodeSlab
+	counts map[reflect.Type]int
+}
+
+type nodeSlab struct {
+	base       unsafe.Pointer
+	size       uintptr
+	used, free int
+}
+
+// eface is the memory layout of an empty interface
+type eface struct {
+	typ  unsafe.Pointer
+	data unsafe.Pointer
+}
+
+// clonePlan describes how to copy a struct type: a shallow copy followed by
+// fixing up fields, plus the nested AST structs other nodes may point into
+type clonePlan struct {
+	ast      bool
+	fields   []cloneField
+	embedded []cloneField
+}
+
+type cloneField struct {
+	offset uintptr
+	typ    reflect.Type
+	op     cloneOp
+}
+
+type cloneOp int
+
+const (
+	cloneNode    cloneOp = iota // pointer to a struct, copied if it is an AST node
+	cloneAnyOp                  // empty interface
+	cloneAnys                   // []any
+	cloneGeneric                // anything else that may hold AST nodes
+)
+
+// clonePlans caches a *clonePlan per struct type
+var clonePlans sync.Map
+
+var (
+	nodeType = reflect.TypeOf(Node{})
+	anyType  = reflect.TypeOf((*any)(nil)).Elem()
+	anysType = reflect.TypeOf([]any(nil))
+)
+
+func planFor(t reflect.Type) *clonePlan {
+	if cached, ok := clonePlans.Load(t); ok {
+		return cached.(*clonePlan)
+	}
+	plan := &clonePlan{ast: t == nodeType}
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if f.Anonymous && !plan.ast {
+			ft := f.Type
+			if ft.Kind() == reflect.Pointer {
+				ft = ft.Elem()
+			}
+			plan.ast = ft != t && ft.Kind() == reflect.Struct && planFor(ft).ast
+		}
+		plan.addField(f.Offset, f.Type)
+	}
+	clonePlans.Store(t, plan)
+	return plan
+}
+
+func (p *clonePlan) addField(offset uintptr, t reflect.Type) {
+	switch t.Kind() {
+	case reflect.Pointer:
+		if t.Elem().Kind() == reflect.Struct {
+			p.fields = append(p.fields, cloneField{offset, t, cloneNode})
+		} else {
+			p.fields = append(p.fields, cloneField{offset, t, cloneGeneric})
+		}
+	case reflect.Interface:
+		if t == anyType {
+			p.fields = append(p.fields, cloneField{offset, t, cloneAnyOp})
+		} else {
+			p.fields = append(p.fields, cloneField{offset, t, cloneGeneric})
+		}
+	case reflect.Slice:
+		if t == anysType {
+			p.fields = append(p.fields, cloneField{offset, t, cloneAnys})
+		} else {
+			p.fields = append(p.fields, cloneField{offset, t, cloneGeneric})
+		}
+	case reflect.Map, reflect.Array:
+		p.fields = append(p.fields, cloneField{offset, t, cloneGeneric})
+	case reflect.Struct:
+		if planFor(t).ast {
+			p.embedded = append(p.embedded, cloneField{offset, t, cloneGeneric})
+		}
+		for i := 0; i < t.NumField(); i++ {
+			f := t.Field(i)
+			p.addField(offset+f.Offset, f.Type)
+		}
+	}
+}
+
+// cloneNode copies the AST struct of type t at src, once per tree
+func (c *treeCloner) cloneNode(src unsafe.Pointer, t reflect.Type) unsafe.Pointer {
+	key := treeClonerKey{src, t}
+	if dst, ok := c.nodes[key]; ok {
+		return dst
+	}
+	dst := c.alloc(t)
+	c.nodes[key] = dst
+	reflect.NewAt(t, dst).Elem().Set(reflect.NewAt(t, src).Elem())
+
+	plan := planFor(t)
+	for _, f := range plan.embedded {
+		c.nodes[treeClonerKey{unsafe.Add(src, f.offset), f.typ}] = unsafe.Add(dst, f.offset)
+	}
+	for _, f := range plan.fields {
+		field := unsafe.Add(dst, f.offset)
+		switch f.op {
+		case cloneNode:
+			if p := *(*unsafe.Pointer)(field); p != nil && planFor(f.typ.Elem()).ast {
+				*(*unsafe.Pointer)(field) = c.cloneNode(p, f.typ.Elem())
+			}
+		case cloneAnyOp:
+			if v := *(*any)(field); v != nil {
+				*(*any)(field) = c.cloneAny(v)
+			}
+		case cloneAnys:
+			if v := *(*[]any)(field); v != nil {
+				*(*[]any)(field) = c.cloneAnys(v)
+			}
+		default:
+			v := reflect.NewAt(f.typ, field).Elem()
+			if f.typ.Kind() == reflect.Array || !v.IsNil() {
+				v.Set(c.clone(v))
+			}
+		}
+	}
+	return dst
+}
+
+func (c *treeCloner) alloc(t reflect.Type) unsafe.Pointer {
+	if c.counts != nil {
+		c.counts[t]++
+	}
+	if slab := c.slabs[t]; slab != nil && slab.free > 0 {
+		p := unsafe.Add(slab.base, uintptr(slab.used)*slab.size)
+		slab.used++
+		slab.free--
+		return p
+	}
+	return reflect.New(t).UnsafePointer()
+}
+
+func (c *treeCloner) cloneAnys(items []any) []any {
+	out := make([]any, len(items))
+	for i, item := range items {
+		if item != nil {
+			out[i] = c.cloneAny(item)
+		}
+	}
+	return out
+}
+
+func (c *treeCloner) cloneAny(v any) any {
+	t := reflect.TypeOf(v)
+	switch t.Kind() {
+	case reflect.Pointer:
+		elem := t.Elem()
+		if elem.Kind() != reflect.Struct {
+			return c.clone(reflect.ValueOf(v)).Interface()
+		}
+		if !planFor(elem).ast {
+			return v
+		}
+		e := (*eface)(unsafe.Pointer(&v))
+		copied := eface{typ: e.typ, data: c.cloneNode(e.data, elem)}
+		return *(*any)(unsafe.Pointer(&copied))
+	case reflect.Slice:
+		if items, ok := v.([]any); ok {
+			if items == nil {
+				return v
+			}
+			return c.cloneAnys(items)
+		}
+		return c.clone(reflect.ValueOf(v)).Interface()
+	case reflect.Map, reflect.Interface, reflect.Array, reflect.Struct:
+		return c.clone(reflect.ValueOf(v)).Interface()
+	default:
+		return v
+	}
+}
+
+// clone is the generic path for the less common kinds of values in a tree
+func (c *treeCloner) clone(v reflect.Value) reflect.Value {
+	switch v.Kind() {
+	case reflect.Pointer:
+		if v.IsNil() {
+			return v
+		}
+		elem := v.Type().Elem()
+		if elem.Kind() == reflect.Struct {
+			if !planFor(elem).ast {
+				return v
+			}
+			return reflect.NewAt(elem, c.cloneNode(v.UnsafePointer(), elem))
+		}
+		copied := reflect.New(elem)
+		copied.Elem().Set(c.clone(v.Elem()))
+		return copied
+	case reflect.Interface:
+		if v.IsNil() {
+			return v
+		}
+		out := reflect.New(v.Type()).Elem()
+		out.Set(c.clone(v.Elem()))
+		return out
+	case reflect.Slice:
+		if v.IsNil() {
+			return v
+		}
+		out := reflect.MakeSlice(v.Type(), v.Len(), v.Len())
+		for i := 0; i < v.Len(); i++ {
+			out.Index(i).Set(c.clone(v.Index(i)))
+		}
+		return out
+	case reflect.Map:
+		if v.IsNil() {
+			return v
+		}
+		if copied, ok := c.maps[v.Pointer()]; ok {
+			return copied
+		}
+		out := reflect.MakeMapWithSize(v.Type(), v.Len())
+		c.maps[v.Pointer()] = out
+		iter := v.MapRange()
+		for iter.Next() {
+			out.SetMapIndex(iter.Key(), c.clone(iter.Value()))
+		}
+		return out
+	case reflect.Struct:
+		if !planFor(v.Type()).ast {
+			return v
+		}
+		src := reflect.New(v.Type())
+		src.Elem().Set(v)
+		return reflect.NewAt(v.Type(), c.cloneNode(src.UnsafePointer(), v.Type())).Elem()
+	case reflect.Array:
+		out := reflect.New(v.Type()).Elem()
+		for i := 0; i < v.Len(); i++ {
+			out.Index(i).Set(c.clone(v.Index(i)))
+		}
+		return out
+	default:
+		return v
+	}
+}
@@ -3,6 +3,7 @@ package less_go
 import (
 	"fmt"
 	"os"
+	"path/filepath"
 	"reflect"
 	"strings"
 )
@@ -116,6 +117,34 @@ type FileManager interface {
 	AlwaysMakePathsAbsolute() bool
 }
 
+// getFileManager picks the file manager for filename the way less.js does:
+// managers are tried from last to first and the first one whose supports
+// (or supportsSync) method returns true wins. Managers that implement
+// neither method are assumed to support every file.
+func getFileManager(fileManagers []FileManager, filename, currentDirectory string, options map[string]any, isSync bool) FileManager {
+	for i := len(fileManagers) - 1; i >= 0; i-- {
+		fileManager := fileManagers[i]
+		if fileManager == nil {
+			continue
+		}
+		if isSync {
+			if s, ok := fileManager.(interface {
+				SupportsSync(filename, currentDirectory string, options map[string]any, environment map[string]any) bool
+			}); ok && !s.SupportsSync(filename, currentDirectory, options, nil) {
+				continue
+			}
+		} else {
+			if s, ok := fileManager.(interface {
+				Supports(filename, currentDirectory string, options map[string]any, environment map[string]any) bool
+			}); ok && !s.Supports(filename, currentDirectory, options, nil) {
+				continue
+			}
+		}
+		return fileManager
+	}
+	return nil
+}
+
 type LoadedFile struct {
 	Filename string `json:"filename"`
 	Contents string `json:"contents"`
@@ -231,6 +260,9 @@ func (im *ImportManager) Push(path string, tryAppendExtension bool, currentFileI
 			if e != nil && im.error == nil {
 				im.error = e
 			}
+			if e == nil {
+				im.recordDependency(currentFileInfo.Filename, fullPath, importOptions)
+			}
 
 			callback(e, root, importedEqualsRoot, fullPath)
 		}
@@ -264,6 +296,12 @@ func (im *ImportManager) Push(path string, tryAppendExtension bool, currentFileI
 		return
 	}
 
+	// Don't start loading new files once the compilation has been cancelled
+	if err := optionsContextErr(im.context); err != nil {
+		fileParsedFunc(err, nil, "")
+		return
+	}
+
 	loadFileCallback := func(loadedFile *LoadedFile) {
 		if loadedFile == nil {
 			fileParsedFunc(fmt.Errorf("LoadedFile is nil for path: %s", path), nil, "")
@@ -361,13 +399,46 @@ func (im *ImportManager) Push(path string, tryAppendExtension bool, currentFileI
 				"reference":        newFileInfo.Reference,
 			}
 
+			// Reuse the tree parsed by an earlier compilation of the same Compiler
+			cache := im.importCache()
+			cacheKey := newImportCacheKey(newFileInfo, parserContext)
+			// The warnings of parsing a cached file are replayed, as it is not parsed
+			// again. A quiet compilation neither reports nor caches them.
+			diagnostics, _ := parserContext["diagnostics"].(*diagnosticCollector)
+			quiet, _ := parserContext["quiet"].(bool)
+			var fileDiagnostics *diagnosticCollector
+			if cache != nil {
+				if root, warnings := cache.get(cacheKey, contents); root != nil {
+					if funcRegistry, ok := parserContext["functionRegistry"].(*Registry); ok && funcRegistry != nil {
+						root.FunctionRegistry = funcRegistry.Inherit()
+					} else {
+						root.FunctionRegistry = DefaultRegistry.Inherit()
+					}
+					if !quiet {
+						for _, d := range warnings {
+							diagnostics.warn(d)
+						}
+					}
+					fileParsedFunc(nil, root, resolvedFilename)
+					return
+				}
+				fileDiagnostics = newDiagnosticCollector(nil)
+				parserContext["diagnostics"] = fileDiagnostics
+			}
+
 			if parserFactory, exists := im.context["parserFactory"]; exists {
 				if pf, ok := parserFactory.(func(map[string]any, map[string]any, map[string]any, int) ParserInterface); ok {
 					parser := pf(parserContext, parserImports, parserFileInfo, 0)
 					parser.Parse(contents, func(e *LessError, root *Ruleset) {
+						warnings := fileDiagnostics.list()
+						for _, d := range warnings {
+							diagnostics.warn(d)
+						}
 						var err error
 						if e != nil {
 							err = e
+						} else if cache != nil && root != nil && !quiet {
+							cache.put(cacheKey, contents, root, warnings)
 						}
 						fileParsedFunc(err, root, resolvedFilename)
 					}, nil)
@@ -448,6 +519,48 @@ func (im *ImportManager) Push(path string, tryAppendExtension bool, currentFileI
 	}
 }
 
+// recordDependency adds the file importer pulled in to the compilation's dependency graph
+func (im *ImportManager) recordDependency(importer, fullPath string, importOptions *ImportOptions) {
+	dependencies, _ := im.context["dependencies"].(*DependencyGraph)
+	if dependencies == nil {
+		return
+	}
+	kind := DependencyImport
+	if importOptions.IsPlugin {
+		kind = DependencyPlugin
+		// Plugins are resolved by Node.js, which appends .js like @plugin does
+		if filepath.Ext(fullPath) == "" {
+			fullPath += ".js"
+		}
+	}
+	dependencies.add(Dependency{
+		File:      fullPath,
+		Importer:  importer,
+		Kind:      kind,
+		Reference: importOptions.Reference,
+		Inline:    importOptions.Inline,
+		Optional:  importOptions.Optional,
+		Multiple:  importOptions.Multiple,
+	})
+}
+
+// importCache returns the cache shared between compilations, if any. Trees are
+// not cached when pre-processors may rewrite the file contents before parsing,
+// nor when errors are collected, as a cached tree would not report its errors.
+func (im *ImportManager) importCache() *importCache {
+	cache, _ := im.context["importCache"].(*importCache)
+	if cache == nil {
+		return nil
+	}
+	if _, ok := im.context["errors"].(*errorCollector); ok {
+		return nil
+	}
+	if pluginManager, ok := im.context["pluginManager"].(*PluginManager); ok && len(pluginManager.GetPreProcessors()) > 0 {
+		return nil
+	}
+	return cache
+}
+
 func (im *ImportManager) cloneContext() map[string]any {
 	return Clone(im.context)
 }
@@ -2,8 +2,8 @@ package less_go
 
 import (
 	"fmt"
+	"io/fs"
 	"os"
-	"runtime"
 	"strconv"
 	"strings"
 	"sync"
@@ -207,10 +207,6 @@ func createRender(env any, parseTree any, importManager any) func(string, ...any
 	return func(input string, args ...any) (result any) {
 		defer func() {
 			if r := recover(); r != nil {
-				buf := make([]byte, 4096)
-				n := runtime.Stack(buf, false)
-				stackTrace := string(buf[:n])
-
 				var errMsg string
 				if err, ok := r.(error); ok {
 					errMsg = err.Error()
@@ -227,10 +223,6 @@ func createRender(env any, parseTree any, importManager any) func(string, ...any
 					}
 				}
 
-				if strings.Contains(errMsg, "nil pointer dereference") || strings.Contains(errMsg, "invalid memory address") || strings.Contains(errMsg, "index out of range") {
-					fmt.Fprintf(os.Stderr, "\n=== DEBUG: Runtime error in render ===\nError: %s\nFile: %s\nStack trace:\n%s\n===\n", errMsg, filename, stackTrace)
-				}
-
 				result = map[string]any{
 					"error": fmt.Sprintf("Syntax: %s in %s", errMsg, filename),
 				}
@@ -366,23 +358,11 @@ func createRender(env any, parseTree any, importManager any) func(string, ...any
 				func() {
 					defer func() {
 						if r := recover(); r != nil {
-							buf := make([]byte, 8192)
-							n := runtime.Stack(buf, false)
-							stackTrace := string(buf[:n])
-
-							var errMsg string
 							if err, ok := r.(error); ok {
-								errMsg = err.Error()
+								renderErr = err
 							} else {
-								errMsg = fmt.Sprintf("%v", r)
+								renderErr = fmt.Errorf("%v", r)
 							}
-
-							if strings.Contains(errMsg, "index out of range") {
-								fmt.Fprintf(os.Stderr, "\n=== PANIC in ToCSS ===\nError: %s\nStack:\n%s\n===\n", errMsg, stackTrace)
-								fmt.Printf("\n=== PANIC in ToCSS ===\nError: %s\nStack:\n%s\n===\n", errMsg, stackTrace)
-							}
-
-							renderErr = fmt.Errorf("%s", errMsg)
 						}
 					}()
 
@@ -715,10 +695,32 @@ func mergeDefaults(target, source map[string]any) map[string]any {
 	return result
 }
 
-type SimpleImportManagerEnvironment struct{}
+type SimpleImportManagerEnvironment struct {
+	// FS, when set, is used by the returned file managers instead of the OS file system
+	FS fs.FS
+	// FileManagers take priority over the file system manager, later entries first
+	FileManagers []FileManager
+}
 
 func (s *SimpleImportManagerEnvironment) GetFileManager(path, currentDirectory string, context map[string]any, environment ImportManagerEnvironment) FileManager {
-	return NewFileSystemFileManager()
+	fm := NewFileSystemFileManager()
+	fm.FS = s.FS
+	if len(s.FileManagers) == 0 && context["pluginManager"] == nil {
+		return fm
+	}
+
+	// Match JavaScript: fileManagers = [].concat(fileManagers).concat(options.pluginManager.getFileManagers());
+	fileManagers := append([]FileManager{fm}, s.FileManagers...)
+	if pluginManager, ok := context["pluginManager"].(*PluginManager); ok {
+		for _, manager := range pluginManager.GetFileManagers() {
+			if fileManager, ok := manager.(FileManager); ok {
+				fileManagers = append(fileManagers, fileManager)
+			}
+		}
+	}
+
+	isSync, _ := context["syncImport"].(bool)
+	return getFileManager(fileManagers, path, currentDirectory, context, isSync)
 }
 
 type SimpleFileManager struct {
@@ -0,0 +1,92 @@
+package less_go
+
+// Inspect traverses the tree rooted at node in depth-first order, calling fn
+// for each node before its children. If fn returns false, the children of the
+// node are skipped. It works on parsed trees (see ParseStylesheet) as well as
+// evaluated ones, and reaches every node: selectors, values and mixin
+// arguments included.
+func Inspect(node any, fn func(node any) bool) {
+	i := &inspector{fn: fn}
+	i.visitor = NewVisitor(i)
+	i.visitor.visitUnindexed = true
+	i.visitor.Visit(node)
+}
+
+// Walk calls fn for each node of type T in the tree rooted at node, in
+// depth-first order, e.g.
+//
+//	less_go.Walk(sheet.Root, func(call *less_go.MixinCall) { ... })
+func Walk[T any](node any, fn func(T)) {
+	Inspect(node, func(n any) bool {
+		if t, ok := n.(T); ok {
+			fn(t)
+		}
+		return true
+	})
+}
+
+// inspector is the visitor implementation behind Inspect. It never replaces
+// nodes.
+type inspector struct {
+	fn      func(node any) bool
+	visitor *Visitor
+}
+
+func (i *inspector) IsReplacing() bool {
+	return false
+}
+
+func (i *inspector) VisitNode(node any, visitArgs *VisitArgs) (any, bool) {
+	visitArgs.VisitDeeper = i.fn(node)
+	if visitArgs.VisitDeeper {
+		for _, child := range unvisitedChildren(node) {
+			i.visitor.Visit(child)
+		}
+	}
+	return node, true
+}
+
+func (i *inspector) VisitNodeOut(node any) bool {
+	return false
+}
+
+// unvisitedChildren returns the children of node that its Accept method does
+// not pass to a Visitor, either because they are not nodes, like mixin
+// arguments, or because the compiler's visitors never need them
+func unvisitedChildren(node any) []any {
+	var children []any
+	switch n := node.(type) {
+	case *MixinCall:
+		children = argumentValues(n.Arguments)
+	case *MixinDefinition:
+		children = argumentValues(n.Params)
+	case *Declaration:
+		if name, ok := n.name.([]any); ok {
+			children = name
+		}
+	case *Operation:
+		children = n.Operands
+	case *Call:
+		children = n.Args
+	case *Paren:
+		children = []any{n.Value}
+	case *Negative:
+		children = []any{n.Value}
+	case *NamespaceValue:
+		children = []any{n.value}
+	case *Attribute:
+		children = []any{n.Key, n.Value}
+	}
+	return children
+}
+
+// argumentValues returns the values of mixin arguments or parameters
+func argumentValues(args []any) []any {
+	var values []any
+	for _, arg := range args {
+		if m, ok := arg.(map[string]any); ok && m["value"] != nil {
+			values = append(values, m["value"])
+		}
+	}
+	return values
+}
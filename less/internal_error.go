@@ -0,0 +1,78 @@
+package less_go
+
+import (
+	"errors"
+	"fmt"
+	"runtime"
+)
+
+// isInternalPanic reports whether a value recovered from a panic is a bug, in
+// the compiler or in a custom function or plugin, rather than an error in the
+// stylesheet: a runtime error, such as a nil pointer dereference or an index out
+// of range, or a value that is neither an error, a message nor a map-shaped
+// Less error
+func isInternalPanic(r any) bool {
+	switch r.(type) {
+	case *LessError, *MixinCallError, string, map[string]any, map[string]string:
+		return false
+	}
+	if err, ok := r.(error); ok {
+		var runtimeErr runtime.Error
+		return errors.As(err, &runtimeErr)
+	}
+	return true
+}
+
+// panicLessError returns a value recovered from a panic as a *LessError if it
+// is one, or if it is a map-shaped Less error such as
+// {"type": "Syntax", "message": "..."}, which default() and Keyword raise as
+// less.js does. Such an error has no location; filename and index, those of
+// where it is recovered, are used unless it has its own.
+func panicLessError(r any, filename string, index any) (*LessError, bool) {
+	var fields map[string]any
+	switch e := r.(type) {
+	case *LessError:
+		return e, true
+	case map[string]any:
+		fields = e
+	case map[string]string:
+		fields = make(map[string]any, len(e))
+		for k, v := range e {
+			fields[k] = v
+		}
+	default:
+		return nil, false
+	}
+	message, ok := fields["message"].(string)
+	if !ok {
+		return nil, false
+	}
+	lessErr := &LessError{Type: "Syntax", Message: message, Filename: filename, Index: index}
+	if errorType, ok := fields["type"].(string); ok && errorType != "" {
+		lessErr.Type = errorType
+	}
+	if f, ok := fields["filename"].(string); ok && f != "" {
+		lessErr.Filename = f
+	}
+	if i, ok := fields["index"].(int); ok {
+		lessErr.Index = i
+	}
+	return lessErr, true
+}
+
+// internalError converts a value recovered from an internal panic to a
+// *LessError of Type "Internal" at index in filename, with the Go stack of the
+// panic as its Stack. The panic is passed to handler, the compilation's
+// PanicHandler, if set.
+func internalError(r any, stack []byte, filename string, index any, contents map[string]string, rootFilename string, handler func(any, []byte)) *LessError {
+	if handler != nil {
+		handler(r, stack)
+	}
+	return NewLessError(ErrorDetails{
+		Type:     "Internal",
+		Message:  fmt.Sprint(r),
+		Stack:    string(stack),
+		Filename: filename,
+		Index:    index,
+	}, contents, rootFilename)
+}
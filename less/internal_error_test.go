@@ -0,0 +1,124 @@
+package less_go
+
+import (
+	"errors"
+	"fmt"
+	"runtime"
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+// panickingFunctions has a custom function with a bug, a nil pointer dereference
+var panickingFunctions = map[string]FunctionDefinition{
+	"broken": Function(func(ctx *Context, args ...any) (any, error) {
+		var d *Dimension
+		return d.Value, nil
+	}),
+}
+
+func TestCompile_InternalError(t *testing.T) {
+	input := ".m() {\n  width: broken(1);\n}\n.a {\n  color: red;\n  .m();\n}\n"
+	for _, collect := range []bool{false, true} {
+		var values []any
+		var stacks [][]byte
+		_, err := Compile(input, &CompileOptions{
+			Filename:      "main.less",
+			Functions:     panickingFunctions,
+			CollectErrors: collect,
+			PanicHandler: func(value any, stack []byte) {
+				values = append(values, value)
+				stacks = append(stacks, stack)
+			},
+		})
+		var lessErr *LessError
+		if !errors.As(err, &lessErr) {
+			t.Fatalf("collect=%v: expected a *LessError, got %T: %v", collect, err, err)
+		}
+		if lessErr.Type != "Internal" || lessErr.Message != "runtime error: invalid memory address or nil pointer dereference" {
+			t.Errorf("collect=%v: unexpected error %s: %s", collect, lessErr.Type, lessErr.Message)
+		}
+		if lessErr.LineNumber() != 2 || lessErr.Column != 9 || lessErr.Extract[1] != "  width: broken(1);" {
+			t.Errorf("collect=%v: expected the error at the call, got %d:%d %q", collect, lessErr.LineNumber(), lessErr.Column, lessErr.Extract[1])
+		}
+		if got := frameList(t, err); len(got) != 1 || got[0] != "mixin .m() main.less:6:3" {
+			t.Errorf("collect=%v: unexpected frames %s", collect, got)
+		}
+		if !strings.Contains(lessErr.Stack, "goroutine") {
+			t.Errorf("collect=%v: expected the Go stack in Stack, got %q", collect, lessErr.Stack)
+		}
+		if len(values) != 1 {
+			t.Fatalf("collect=%v: expected the handler to be called once, got %d", collect, len(values))
+		}
+		if _, ok := values[0].(runtime.Error); !ok || !strings.Contains(string(stacks[0]), "goroutine") {
+			t.Errorf("collect=%v: unexpected handler arguments %v, %q", collect, values[0], stacks[0])
+		}
+	}
+}
+
+func TestCompile_InternalErrorInImport(t *testing.T) {
+	_, err := Compile("@import \"lib.less\";\n.a { b: broken(1); }", &CompileOptions{
+		Filename:  "main.less",
+		FS:        fstest.MapFS{"lib.less": {Data: []byte(".l {\n  x: y;\n  z: broken(2);\n}\n")}},
+		Functions: panickingFunctions,
+	})
+	var lessErr *LessError
+	if !errors.As(err, &lessErr) || lessErr.Type != "Internal" {
+		t.Fatalf("expected an Internal error, got %v", err)
+	}
+	if lessErr.Filename != "lib.less" || lessErr.LineNumber() != 3 {
+		t.Errorf("expected the error in lib.less:3, got %s:%d", lessErr.Filename, lessErr.LineNumber())
+	}
+}
+
+func TestIsInternalPanic(t *testing.T) {
+	var runtimeErr error
+	func() {
+		defer func() { runtimeErr = recover().(error) }()
+		var s []int
+		_ = s[len(s)]
+	}()
+	tests := []struct {
+		value any
+		want  bool
+	}{
+		{runtimeErr, true},
+		{fmt.Errorf("in mixin: %w", runtimeErr), true},
+		{"unexpected state", false},
+		{map[string]any{"type": "Syntax", "message": "it is currently only allowed in parametric mixin guards,"}, false},
+		{map[string]string{"type": "Syntax", "message": "Invalid % without number"}, false},
+		{42, true},
+		{&LessError{Type: "Name", Message: "variable @a is undefined"}, false},
+		{&MixinCallError{Message: "No matching definition was found for `.m()`"}, false},
+		{errors.New("file not found"), false},
+	}
+	for _, tt := range tests {
+		if got := isInternalPanic(tt.value); got != tt.want {
+			t.Errorf("isInternalPanic(%v) = %v, want %v", tt.value, got, tt.want)
+		}
+	}
+}
+
+func TestCompile_MapShapedError(t *testing.T) {
+	_, err := Compile(".a {\n  color: red;\n}\n.b when (default()) {\n  color: blue;\n}\n", &CompileOptions{Filename: "main.less"})
+	lessErr, ok := err.(*LessError)
+	if !ok {
+		t.Fatalf("expected a *LessError, got %T: %v", err, err)
+	}
+	if lessErr.Type != "Syntax" || lessErr.Message != "it is currently only allowed in parametric mixin guards," {
+		t.Errorf("unexpected error %s: %s", lessErr.Type, lessErr.Message)
+	}
+	if lessErr.Filename != "main.less" || lessErr.LineNumber() != 4 {
+		t.Errorf("expected the error in main.less:4, got %s:%d", lessErr.Filename, lessErr.LineNumber())
+	}
+}
+
+func TestPanicLessError(t *testing.T) {
+	lessErr, ok := panicLessError(map[string]string{"type": "Syntax", "message": "Invalid % without number"}, "main.less", 7)
+	if !ok || lessErr.Type != "Syntax" || lessErr.Message != "Invalid % without number" || lessErr.Filename != "main.less" || lessErr.Index != 7 {
+		t.Errorf("unexpected conversion %+v, %v", lessErr, ok)
+	}
+	if _, ok := panicLessError("unexpected state", "", nil); ok {
+		t.Error("expected a string not to be converted")
+	}
+}
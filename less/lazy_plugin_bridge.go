@@ -17,12 +17,22 @@ type LazyNodeJSPluginBridge struct {
 	initErr       error
 	closed        bool
 	pendingScopes int
+	cancel        <-chan struct{}
+	// runtimes, when set, provides the Node.js process instead of spawning one,
+	// and gets it back on Close
+	runtimes *pluginRuntimePool
 }
 
 func NewLazyNodeJSPluginBridge() *LazyNodeJSPluginBridge {
 	return &LazyNodeJSPluginBridge{}
 }
 
+// newPooledLazyNodeJSPluginBridge creates a lazy bridge that borrows its
+// Node.js process from runtimes
+func newPooledLazyNodeJSPluginBridge(runtimes *pluginRuntimePool) *LazyNodeJSPluginBridge {
+	return &LazyNodeJSPluginBridge{runtimes: runtimes}
+}
+
 func (lb *LazyNodeJSPluginBridge) ensureInitialized() error {
 	lb.initOnce.Do(func() {
 		if os.Getenv("LESS_GO_DEBUG") == "1" {
@@ -36,7 +46,16 @@ func (lb *LazyNodeJSPluginBridge) ensureInitialized() error {
 			return
 		}
 
-		bridge, err := NewNodeJSPluginBridge()
+		var bridge *NodeJSPluginBridge
+		var err error
+		if lb.runtimes != nil {
+			var rt *runtime.NodeJSRuntime
+			if rt, err = lb.runtimes.get(); err == nil {
+				bridge = NewNodeJSPluginBridgeWithRuntime(rt)
+			}
+		} else {
+			bridge, err = NewNodeJSPluginBridge()
+		}
 		if err != nil {
 			lb.initErr = err
 			if os.Getenv("LESS_GO_DEBUG") == "1" {
@@ -46,6 +65,11 @@ func (lb *LazyNodeJSPluginBridge) ensureInitialized() error {
 		}
 		lb.bridge = bridge
 
+		// Apply a cancellation channel set before initialization
+		if lb.cancel != nil {
+			bridge.SetCancel(lb.cancel)
+		}
+
 		// Apply pending scopes entered before initialization
 		if lb.pendingScopes > 0 {
 			if os.Getenv("LESS_GO_DEBUG") == "1" {
@@ -237,6 +261,18 @@ func (lb *LazyNodeJSPluginBridge) RunPostProcessors(css string, options map[stri
 	return lb.bridge.RunPostProcessors(css, options)
 }
 
+// SetCancel aborts in-flight plugin calls when done is closed. If Node.js has
+// not been started yet, the channel is applied once it is.
+func (lb *LazyNodeJSPluginBridge) SetCancel(done <-chan struct{}) {
+	lb.mu.Lock()
+	defer lb.mu.Unlock()
+
+	lb.cancel = done
+	if lb.bridge != nil {
+		lb.bridge.SetCancel(done)
+	}
+}
+
 func (lb *LazyNodeJSPluginBridge) Close() error {
 	lb.mu.Lock()
 	defer lb.mu.Unlock()
@@ -244,6 +280,9 @@ func (lb *LazyNodeJSPluginBridge) Close() error {
 	lb.closed = true
 
 	if lb.bridge != nil {
+		if lb.runtimes != nil {
+			return lb.runtimes.put(lb.bridge.GetRuntime())
+		}
 		return lb.bridge.Close()
 	}
 	return nil
@@ -25,6 +25,16 @@ type LessError struct {
 	CallLine    *int
 	CallExtract string
 	Extract     []string
+	// Suggestions are the names closest to an undefined variable, mixin or
+	// function, as @brand for @brnad
+	Suggestions []string
+	// Candidates are the signatures of the mixins found for a call when none
+	// of them matched its arguments or guard
+	Candidates []string
+	// Frames are the mixin, function and detached ruleset calls the error
+	// happened in, innermost first, followed by the imports of the file of
+	// the outermost one
+	Frames         []ErrorFrame
 	fileContentMap map[string]string
 }
 
@@ -2,6 +2,8 @@ package less_go
 
 import (
 	"fmt"
+	"math"
+	"sort"
 	"strconv"
 )
 
@@ -418,6 +420,8 @@ func EachWithContext(list any, rs any, ctx *Context) any {
 					DefaultFunc:       evalCtx.DefaultFunc,
 					PluginBridge:      evalCtx.PluginBridge,
 					LazyPluginBridge:  evalCtx.LazyPluginBridge,
+					errors:            evalCtx.errors,
+					calls:             evalCtx.calls,
 				}
 				finalEvalContext = newEvalCtx
 			}
@@ -529,4 +533,306 @@ func init() {
 			}
 		}
 	}
-}
\ No newline at end of file
+
+	// List manipulation, for comma and space separated lists
+	for _, def := range []*FlexibleFunctionDef{
+		{name: "append", minArgs: 2, maxArgs: 3, fn: Append},
+		{name: "prepend", minArgs: 2, maxArgs: 3, fn: Prepend},
+		{name: "join", minArgs: 2, maxArgs: 3, fn: Join},
+		{name: "slice", minArgs: 2, maxArgs: 3, fn: Slice},
+		{name: "index", minArgs: 2, maxArgs: 2, fn: Index},
+		{name: "reverse", minArgs: 1, maxArgs: 1, fn: Reverse},
+		{name: "sort", minArgs: 1, maxArgs: 1, fn: Sort},
+		{name: "unique", minArgs: 1, maxArgs: 1, fn: Unique},
+		{name: "set-nth", minArgs: 3, maxArgs: 3, fn: SetNth},
+		{name: "zip", minArgs: 1, variadic: true, fn: Zip},
+	} {
+		def.needsEval = true
+		DefaultRegistry.Add(def.name, def)
+	}
+}
+
+// listItems returns the items of a list and whether it is comma separated. A
+// single value is a list of one item
+func listItems(list any) ([]any, bool) {
+	switch l := list.(type) {
+	case *Value:
+		if len(l.Value) == 1 {
+			return listItems(l.Value[0])
+		}
+		return l.Value, true
+	case *Expression:
+		return l.Value, false
+	}
+	return []any{list}, false
+}
+
+// newList returns a comma separated list (a Value) or a space separated list
+// (an Expression) of items
+func newList(items []any, comma bool) any {
+	if comma {
+		list, _ := NewValue(items)
+		return list
+	}
+	list, _ := NewExpression(items, false)
+	return list
+}
+
+// listSeparator returns whether a separator argument (comma, space or auto)
+// asks for a comma separated list. auto keeps that of the list
+func listSeparator(name string, separator any, comma bool) (bool, *LessError) {
+	if separator == nil {
+		return comma, nil
+	}
+	var value string
+	switch s := separator.(type) {
+	case *Keyword:
+		value = s.value
+	case *Quoted:
+		value = s.value
+	}
+	switch value {
+	case "comma":
+		return true, nil
+	case "space":
+		return false, nil
+	case "auto":
+		return comma, nil
+	}
+	return false, &LessError{Type: "Argument", Message: name + ": separator must be comma, space or auto"}
+}
+
+// listPosition returns the 0-based position of a 1-based index argument; a
+// negative index counts from the end
+func listPosition(name string, indexNode any, length int) (int, *LessError) {
+	dim, ok := indexNode.(*Dimension)
+	if !ok || dim.Value != float64(int(dim.Value)) || dim.Value == 0 {
+		return 0, &LessError{Type: "Argument", Message: name + ": index must be a non-zero integer"}
+	}
+	position := int(dim.Value) - 1
+	if dim.Value < 0 {
+		position = length + int(dim.Value)
+	}
+	if position < 0 || position >= length {
+		return 0, &LessError{Type: "Argument", Message: fmt.Sprintf("%s: index %d is out of bounds for a list of %d items", name, int(dim.Value), length)}
+	}
+	return position, nil
+}
+
+// listItemText returns the text of an item, unquoted, to compare it to others
+func listItemText(item any) string {
+	switch i := item.(type) {
+	case *Quoted:
+		return i.value
+	case interface{ ToCSS(any) string }:
+		return i.ToCSS(nil)
+	}
+	return fmt.Sprint(item)
+}
+
+// listNumber returns the value of a number converted to px, s or rad when it
+// is a length, a duration or an angle, and the unit of that value
+func listNumber(d *Dimension) (float64, string) {
+	unified := d.Unify()
+	return unified.Value, unified.Unit.ToString()
+}
+
+// listItemsEqual returns whether two items are equal. Numbers are equal in
+// compatible units, such as 1in and 96px, and colors with the same channels,
+// such as red and #f00; other items are compared by text
+func listItemsEqual(a, b any) bool {
+	if colorA, ok := a.(*Color); ok {
+		if colorB, ok := b.(*Color); ok {
+			return toHex(colorA.RGB) == toHex(colorB.RGB) && colorA.Alpha == colorB.Alpha
+		}
+	}
+	if dimA, ok := a.(*Dimension); ok {
+		if dimB, ok := b.(*Dimension); ok {
+			valueA, unitA := listNumber(dimA)
+			valueB, unitB := listNumber(dimB)
+			return unitA == unitB && math.Abs(valueA-valueB) <= 1e-9*math.Max(1, math.Abs(valueA))
+		}
+	}
+	return listItemText(a) == listItemText(b)
+}
+
+// Append returns a list with a value added at its end
+func Append(args ...any) any {
+	items, comma := listItems(args[0])
+	comma, err := listSeparator("append", optionalArg(args, 2), comma)
+	if err != nil {
+		return err
+	}
+	return newList(append(append([]any{}, items...), args[1]), comma)
+}
+
+// Prepend returns a list with a value added at its start
+func Prepend(args ...any) any {
+	items, comma := listItems(args[0])
+	comma, err := listSeparator("prepend", optionalArg(args, 2), comma)
+	if err != nil {
+		return err
+	}
+	return newList(append([]any{args[1]}, items...), comma)
+}
+
+// Join returns the items of two lists as one list. Its separator is that of
+// the first list, or of the second if the first has a single item, unless a
+// separator is given
+func Join(args ...any) any {
+	first, comma := listItems(args[0])
+	second, secondComma := listItems(args[1])
+	if len(first) < 2 {
+		comma = secondComma
+	}
+	comma, err := listSeparator("join", optionalArg(args, 2), comma)
+	if err != nil {
+		return err
+	}
+	return newList(append(append([]any{}, first...), second...), comma)
+}
+
+// Slice returns the items of a list from a start index to an end index, both
+// included. The end defaults to the last item and must not be before the start
+func Slice(args ...any) any {
+	items, comma := listItems(args[0])
+	start, err := listPosition("slice", args[1], len(items))
+	if err != nil {
+		return err
+	}
+	end := len(items) - 1
+	if len(args) > 2 {
+		if end, err = listPosition("slice", args[2], len(items)); err != nil {
+			return err
+		}
+	}
+	if end < start {
+		return &LessError{Type: "Argument", Message: fmt.Sprintf("slice: end index %d is before start index %d", end+1, start+1)}
+	}
+	return newList(append([]any{}, items[start:end+1]...), comma)
+}
+
+// Index returns the 1-based index of the first item of a list equal to a
+// value, or false
+func Index(list, value any) any {
+	items, _ := listItems(list)
+	for i, item := range items {
+		if listItemsEqual(item, value) {
+			dim, _ := NewDimension(float64(i+1), nil)
+			return dim
+		}
+	}
+	return KeywordFalse
+}
+
+// Reverse returns the items of a list in reverse order
+func Reverse(list any) any {
+	items, comma := listItems(list)
+	reversed := make([]any, len(items))
+	for i, item := range items {
+		reversed[len(items)-1-i] = item
+	}
+	return newList(reversed, comma)
+}
+
+// Sort returns the items of a list in ascending order. A list of numbers is
+// sorted by value, converting compatible units (1in is after 90px), any other
+// list by text
+func Sort(list any) any {
+	items, comma := listItems(list)
+	sorted := append([]any{}, items...)
+
+	numbers := true
+	for _, item := range sorted {
+		if _, ok := item.(*Dimension); !ok {
+			numbers = false
+			break
+		}
+	}
+	if !numbers {
+		sort.SliceStable(sorted, func(i, j int) bool {
+			return listItemText(sorted[i]) < listItemText(sorted[j])
+		})
+		return newList(sorted, comma)
+	}
+
+	// Unitless numbers are compatible with any unit
+	values := make(map[any]float64, len(sorted))
+	unit := ""
+	for _, item := range sorted {
+		value, itemUnit := listNumber(item.(*Dimension))
+		if itemUnit != "" && unit != "" && itemUnit != unit {
+			return &LessError{Type: "Argument", Message: fmt.Sprintf("sort: incompatible units %s and %s", unit, itemUnit)}
+		}
+		if itemUnit != "" {
+			unit = itemUnit
+		}
+		values[item] = value
+	}
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return values[sorted[i]] < values[sorted[j]]
+	})
+	return newList(sorted, comma)
+}
+
+// Unique returns the items of a list without those equal to an earlier one
+func Unique(list any) any {
+	items, comma := listItems(list)
+	var unique []any
+	for _, item := range items {
+		duplicate := false
+		for _, kept := range unique {
+			if listItemsEqual(item, kept) {
+				duplicate = true
+				break
+			}
+		}
+		if !duplicate {
+			unique = append(unique, item)
+		}
+	}
+	return newList(unique, comma)
+}
+
+// SetNth returns a list with the item at an index replaced by a value
+func SetNth(list, indexNode, value any) any {
+	items, comma := listItems(list)
+	position, err := listPosition("set-nth", indexNode, len(items))
+	if err != nil {
+		return err
+	}
+	replaced := append([]any{}, items...)
+	replaced[position] = value
+	return newList(replaced, comma)
+}
+
+// Zip returns a comma separated list of space separated lists of the items
+// at the same index of lists, as long as the shortest list
+func Zip(lists ...any) any {
+	var columns [][]any
+	length := -1
+	for _, list := range lists {
+		items, _ := listItems(list)
+		columns = append(columns, items)
+		if length < 0 || len(items) < length {
+			length = len(items)
+		}
+	}
+	zipped := make([]any, length)
+	for i := range zipped {
+		row := make([]any, len(columns))
+		for j, items := range columns {
+			row[j] = items[i]
+		}
+		zipped[i] = newList(row, false)
+	}
+	return newList(zipped, true)
+}
+
+// optionalArg returns the argument at index i, or nil
+func optionalArg(args []any, i int) any {
+	if i < len(args) {
+		return args[i]
+	}
+	return nil
+}
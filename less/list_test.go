@@ -1,6 +1,7 @@
 package less_go
 
 import (
+	"strings"
 	"testing"
 )
 
@@ -884,4 +885,88 @@ func TestComplexIntegrationScenarios(t *testing.T) {
 			t.Errorf("Expected final Dimension, got %T", finalExtract)
 		}
 	})
-}
\ No newline at end of file
+}
+
+func TestCompile_ListManipulation(t *testing.T) {
+	input := `@comma: a, b, c;
+@space: 4px 1cm 2px;
+@one: 1px;
+.x {
+  append: append(@comma, d);
+  append-space: append(@space, 3px);
+  append-comma: append(@space, 3px, comma);
+  append-one: append(@one, 2px);
+  prepend: prepend(@comma, z);
+  join: join(@comma, @space);
+  join-one: join(@one, @comma);
+  join-space: join(@comma, @space, space);
+  slice: slice(@comma, 2);
+  slice-negative: slice(@comma, 1, -2);
+  index: index(@comma, b);
+  index-units: index(1in 2px, 96px);
+  index-missing: index(@comma, q);
+  reverse: reverse(@comma);
+  sort: sort(@space);
+  sort-unitless: sort(10px 1in 90px 2);
+  sort-text: sort(~"b" "a" c);
+  unique: unique(1px 2px 1px a a "a");
+  unique-colors: unique(red #f00 rgb(255, 0, 0) fade(red, 50%));
+  index-color: index(blue red, #f00);
+  set-nth: set-nth(@comma, -1, x);
+  zip: zip(1px 2px 3px, solid dashed, red blue);
+  each(sort(3 1 2), { item-@{index}: @value; });
+}
+`
+	result, err := Compile(input, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{
+		"append: a, b, c, d;",
+		"append-space: 4px 1cm 2px 3px;",
+		"append-comma: 4px, 1cm, 2px, 3px;",
+		"append-one: 1px 2px;",
+		"prepend: z, a, b, c;",
+		"join: a, b, c, 4px, 1cm, 2px;",
+		"join-one: 1px, a, b, c;",
+		"join-space: a b c 4px 1cm 2px;",
+		"slice: b, c;",
+		"slice-negative: a, b;",
+		"index: 2;",
+		"index-units: 1;",
+		"index-missing: false;",
+		"reverse: c, b, a;",
+		"sort: 2px 4px 1cm;",
+		"sort-unitless: 2 10px 90px 1in;",
+		"sort-text: \"a\" b c;",
+		"unique: 1px 2px a;",
+		"unique-colors: red rgba(255, 0, 0, 0.5);",
+		"index-color: 2;",
+		"set-nth: a, b, x;",
+		"zip: 1px solid red, 2px dashed blue;",
+		"item-1: 1;\n  item-2: 2;\n  item-3: 3;",
+	} {
+		if !strings.Contains(result.CSS, want) {
+			t.Errorf("expected %q in:\n%s", want, result.CSS)
+		}
+	}
+}
+
+func TestCompile_ListManipulationErrors(t *testing.T) {
+	tests := []struct {
+		source string
+		want   string
+	}{
+		{".x { a: sort(1px 1em); }", "sort: incompatible units px and em"},
+		{".x { a: slice(1 2, 5); }", "slice: index 5 is out of bounds for a list of 2 items"},
+		{".x { a: slice(a b c, 3, 1); }", "slice: end index 1 is before start index 3"},
+		{".x { a: set-nth(1 2, 0, 1); }", "set-nth: index must be a non-zero integer"},
+		{".x { a: append(1 2, 3, dots); }", "append: separator must be comma, space or auto"},
+	}
+	for _, tt := range tests {
+		_, err := Compile(tt.source, nil)
+		if err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("%q: expected an error with %q, got %v", tt.source, tt.want, err)
+		}
+	}
+}
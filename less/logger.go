@@ -1,5 +1,7 @@
 package less_go
 
+import "sync"
+
 type LogListener interface {
 	Error(msg any)
 	Warn(msg any)
@@ -9,7 +11,10 @@ type LogListener interface {
 
 type LogListenerPartial map[string]func(msg any)
 
+// Logger dispatches messages to its listeners. It is safe for concurrent use:
+// listeners can be added and removed while compilations log to it.
 type Logger struct {
+	mu        sync.RWMutex
 	listeners []any
 }
 
@@ -36,21 +41,30 @@ func (l *Logger) Debug(msg any) {
 }
 
 func (l *Logger) AddListener(listener any) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
 	l.listeners = append(l.listeners, listener)
 }
 
 func (l *Logger) RemoveListener(listener any) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
 	for i := 0; i < len(l.listeners); i++ {
 		if l.listeners[i] == listener {
-			l.listeners = append(l.listeners[:i], l.listeners[i+1:]...)
+			// Copy, as fireEvent may be iterating over the current slice
+			l.listeners = append(l.listeners[:i:i], l.listeners[i+1:]...)
 			return
 		}
 	}
 }
 
 func (l *Logger) fireEvent(eventType string, msg any) {
-	for i := 0; i < len(l.listeners); i++ {
-		listener := l.listeners[i]
+	// Listeners are called without the lock, so that they may remove themselves
+	l.mu.RLock()
+	listeners := l.listeners
+	l.mu.RUnlock()
+	for i := 0; i < len(listeners); i++ {
+		listener := listeners[i]
 
 		switch v := listener.(type) {
 		case LogListener:
@@ -77,6 +91,8 @@ func (l *Logger) fireEvent(eventType string, msg any) {
 }
 
 func (l *Logger) GetListeners() []any {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
 	result := make([]any, len(l.listeners))
 	copy(result, l.listeners)
 	return result
@@ -0,0 +1,312 @@
+package less_go
+
+import (
+	"fmt"
+	"strings"
+)
+
+// MapFunctionDef is a function on maps: detached rulesets such as
+// @config: { primary: blue; } and mixin namespaces such as #theme(). Like
+// each(), it evaluates its own arguments, because a mixin call is only
+// evaluated with the caller's context.
+type MapFunctionDef struct {
+	name    string
+	minArgs int
+	maxArgs int // -1 for any number
+	fn      func(evalContext any, args []any) any
+}
+
+func (m *MapFunctionDef) Call(args ...any) (any, error) {
+	return nil, fmt.Errorf("%s() function requires context", m.name)
+}
+
+func (m *MapFunctionDef) CallCtx(ctx *Context, args ...any) (any, error) {
+	if m.maxArgs < 0 && len(args) < m.minArgs {
+		return &LessError{Type: "Argument", Message: fmt.Sprintf("function %s expects at least %d arguments, got %d", m.name, m.minArgs, len(args))}, nil
+	}
+	if m.maxArgs >= 0 && (len(args) < m.minArgs || len(args) > m.maxArgs) {
+		return &LessError{Type: "Argument", Message: fmt.Sprintf("function %s expects %d-%d arguments, got %d", m.name, m.minArgs, m.maxArgs, len(args))}, nil
+	}
+
+	var evalContext any
+	if ctx != nil && len(ctx.Frames) > 0 {
+		evalContext = ctx.Frames[0].EvalContext
+		if mapCtx, ok := evalContext.(*MapEvalContext); ok {
+			evalContext = mapCtx.ctx
+		}
+	}
+	if evalContext == nil {
+		return nil, fmt.Errorf("%s() function requires context", m.name)
+	}
+
+	evaluated := make([]any, len(args))
+	for i, arg := range args {
+		value, err := evalMapArg(arg, evalContext)
+		if err != nil {
+			return nil, err
+		}
+		evaluated[i] = value
+	}
+	return m.fn(evalContext, evaluated), nil
+}
+
+func (m *MapFunctionDef) NeedsEvalArgs() bool {
+	return false
+}
+
+// evalMapArg evaluates an argument of a map function. A mixin call evaluates
+// to a ruleset of its rules
+func evalMapArg(arg any, evalContext any) (any, error) {
+	switch a := arg.(type) {
+	case *MixinCall:
+		rules, err := a.Eval(evalContext)
+		if err != nil {
+			return nil, err
+		}
+		return NewRuleset(nil, rules, false, nil), nil
+	case interface{ Eval(any) (any, error) }:
+		result, err := a.Eval(evalContext)
+		if err != nil {
+			return nil, err
+		}
+		if paren, ok := result.(*Paren); ok {
+			return paren.Value, nil
+		}
+		return result, nil
+	case interface{ Eval(any) any }:
+		return a.Eval(evalContext), nil
+	}
+	return arg, nil
+}
+
+// mapEntries returns the declarations of a map, evaluated, in the order of
+// their first occurrence. A repeated key has the value of its last
+// declaration, as a repeated property does
+func mapEntries(name string, m any, evalContext any) ([]*Declaration, error) {
+	// An entry whose value is a map holds it in a list of one item
+	for {
+		if list, ok := m.(*Value); ok && len(list.Value) == 1 {
+			m = list.Value[0]
+		} else if expr, ok := m.(*Expression); ok && len(expr.Value) == 1 {
+			m = expr.Value[0]
+		} else {
+			break
+		}
+	}
+
+	var rules []any
+	switch v := m.(type) {
+	case *DetachedRuleset:
+		ruleset, ok := v.CallEval(evalContext).(*Ruleset)
+		if !ok {
+			return nil, nil
+		}
+		rules = ruleset.Rules
+	case *Ruleset:
+		rules = v.Rules
+	default:
+		return nil, &LessError{Type: "Argument", Message: fmt.Sprintf("%s expects a detached ruleset or a mixin call such as #ns() as a map", name)}
+	}
+
+	var entries []*Declaration
+	positions := make(map[string]int)
+	for _, rule := range rules {
+		d, ok := rule.(*Declaration)
+		if !ok {
+			continue
+		}
+		key := mapKey(d)
+		if i, exists := positions[key]; exists {
+			entries[i] = d
+			continue
+		}
+		positions[key] = len(entries)
+		entries = append(entries, d)
+	}
+	return entries, nil
+}
+
+// mapKey returns the name of a map entry, with the @ of a variable
+func mapKey(d *Declaration) string {
+	switch name := d.name.(type) {
+	case string:
+		return name
+	case []any:
+		if len(name) > 0 {
+			if keyword, ok := name[0].(*Keyword); ok {
+				return keyword.value
+			}
+		}
+	}
+	return d.GetName()
+}
+
+// mapKeyArg returns the key named by an argument, such as primary, @primary
+// or "@primary"
+func mapKeyArg(name string, arg any) (string, error) {
+	switch a := arg.(type) {
+	case *Keyword:
+		return a.value, nil
+	case *Quoted:
+		return a.value, nil
+	case *Anonymous:
+		if s, ok := a.Value.(string); ok {
+			return s, nil
+		}
+	}
+	return "", &LessError{Type: "Argument", Message: fmt.Sprintf("%s expects keys such as primary or \"@primary\"", name)}
+}
+
+// findMapEntry returns the index of the entry named by key, or -1. A key
+// without @ names a property, or a variable if there is no such property
+func findMapEntry(entries []*Declaration, key string) int {
+	for i, d := range entries {
+		if mapKey(d) == key {
+			return i
+		}
+	}
+	if !strings.HasPrefix(key, "@") {
+		for i, d := range entries {
+			if mapKey(d) == "@"+key {
+				return i
+			}
+		}
+	}
+	return -1
+}
+
+// newMap returns a detached ruleset of entries, which each() iterates
+func newMap(entries []*Declaration) *DetachedRuleset {
+	rules := make([]any, len(entries))
+	for i, d := range entries {
+		rules[i] = d
+	}
+	return NewDetachedRuleset(NewRuleset(nil, rules, false, nil), nil)
+}
+
+// newMapList returns a comma separated list of items
+func newMapList(items []any) any {
+	if len(items) == 0 {
+		return NewAnonymous("", 0, nil, false, false, nil)
+	}
+	list, _ := NewValue(items)
+	return list
+}
+
+// MapGet returns the value of a key of a map. Further keys look up nested
+// maps: map-get(@config, colors, primary)
+func MapGet(evalContext any, args []any) any {
+	m := args[0]
+	for _, arg := range args[1:] {
+		entries, err := mapEntries("map-get", m, evalContext)
+		if err != nil {
+			return err
+		}
+		key, err := mapKeyArg("map-get", arg)
+		if err != nil {
+			return err
+		}
+		i := findMapEntry(entries, key)
+		if i < 0 {
+			return &LessError{Type: "Argument", Message: fmt.Sprintf("map-get: key %s is not in the map", key)}
+		}
+		m = entries[i].Value
+	}
+	return m
+}
+
+// MapKeys returns a comma separated list of the keys of a map
+func MapKeys(evalContext any, args []any) any {
+	entries, err := mapEntries("map-keys", args[0], evalContext)
+	if err != nil {
+		return err
+	}
+	keys := make([]any, len(entries))
+	for i, d := range entries {
+		keys[i] = NewKeyword(mapKey(d))
+	}
+	return newMapList(keys)
+}
+
+// MapValues returns a comma separated list of the values of a map
+func MapValues(evalContext any, args []any) any {
+	entries, err := mapEntries("map-values", args[0], evalContext)
+	if err != nil {
+		return err
+	}
+	values := make([]any, len(entries))
+	for i, d := range entries {
+		values[i] = d.Value
+	}
+	return newMapList(values)
+}
+
+// MapHasKey returns true if a map has a key
+func MapHasKey(evalContext any, args []any) any {
+	entries, err := mapEntries("map-has-key", args[0], evalContext)
+	if err != nil {
+		return err
+	}
+	key, err := mapKeyArg("map-has-key", args[1])
+	if err != nil {
+		return err
+	}
+	if findMapEntry(entries, key) >= 0 {
+		return KeywordTrue
+	}
+	return KeywordFalse
+}
+
+// MapMerge returns a map of the entries of maps, the last map winning. A key
+// keeps the position of its first occurrence
+func MapMerge(evalContext any, args []any) any {
+	var merged []*Declaration
+	positions := make(map[string]int)
+	for _, m := range args {
+		entries, err := mapEntries("map-merge", m, evalContext)
+		if err != nil {
+			return err
+		}
+		for _, d := range entries {
+			key := mapKey(d)
+			if i, exists := positions[key]; exists {
+				merged[i] = d
+				continue
+			}
+			positions[key] = len(merged)
+			merged = append(merged, d)
+		}
+	}
+	return newMap(merged)
+}
+
+// MapRemove returns a map without some keys
+func MapRemove(evalContext any, args []any) any {
+	entries, err := mapEntries("map-remove", args[0], evalContext)
+	if err != nil {
+		return err
+	}
+	for _, arg := range args[1:] {
+		key, err := mapKeyArg("map-remove", arg)
+		if err != nil {
+			return err
+		}
+		if i := findMapEntry(entries, key); i >= 0 {
+			entries = append(entries[:i:i], entries[i+1:]...)
+		}
+	}
+	return newMap(entries)
+}
+
+func init() {
+	for _, def := range []*MapFunctionDef{
+		{name: "map-get", minArgs: 2, maxArgs: -1, fn: MapGet},
+		{name: "map-keys", minArgs: 1, maxArgs: 1, fn: MapKeys},
+		{name: "map-values", minArgs: 1, maxArgs: 1, fn: MapValues},
+		{name: "map-has-key", minArgs: 2, maxArgs: 2, fn: MapHasKey},
+		{name: "map-merge", minArgs: 1, maxArgs: -1, fn: MapMerge},
+		{name: "map-remove", minArgs: 1, maxArgs: -1, fn: MapRemove},
+	} {
+		DefaultRegistry.Add(def.name, def)
+	}
+}
@@ -0,0 +1,90 @@
+package less_go
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestCompile_MapFunctions(t *testing.T) {
+	input := `@brand: red;
+@config: {
+  primary: blue;
+  @secondary: @brand;
+  size: 1px 2px;
+  primary: navy;
+  @colors: { text: black; }
+}
+#theme() { primary: green; accent: gold; }
+@merged: map-merge(@config, #theme(), { extra: 1; });
+
+.get {
+  property: map-get(@config, primary);
+  variable: map-get(@config, secondary);
+  quoted: map-get(@config, "@secondary");
+  list: map-get(@config, size);
+  nested: map-get(@config, colors, text);
+  namespace: map-get(#theme(), accent);
+}
+.keys {
+  keys: map-keys(@config);
+  values: map-values(#theme());
+  has: map-has-key(@config, secondary);
+  has-not: map-has-key(@config, "@size");
+}
+.merge {
+  keys: map-keys(@merged);
+  primary: map-get(@merged, primary);
+  removed: map-keys(map-remove(@merged, colors, "@secondary", missing));
+}
+.each {
+  each(map-remove(#theme(), primary), { --@{key}: @value; });
+}
+.call {
+  @r: map-merge(#theme(), { primary: white; });
+  @r();
+}
+`
+	result, err := Compile(input, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{
+		"property: navy;",
+		"variable: red;",
+		"quoted: red;",
+		"list: 1px 2px;",
+		"nested: black;",
+		"namespace: gold;",
+		"keys: primary, @secondary, size, @colors;",
+		"values: green, gold;",
+		"has: true;",
+		"has-not: false;",
+		"keys: primary, @secondary, size, @colors, accent, extra;",
+		"primary: green;",
+		"removed: primary, size, accent, extra;",
+		".each {\n  --accent: gold;\n}",
+		".call {\n  primary: white;\n  accent: gold;\n}",
+	} {
+		if !strings.Contains(result.CSS, want) {
+			t.Errorf("expected %q in:\n%s", want, result.CSS)
+		}
+	}
+}
+
+func TestCompile_MapFunctionErrors(t *testing.T) {
+	tests := []struct {
+		source string
+		want   string
+	}{
+		{"@m: { a: 1; }\n.x { a: map-get(@m, b); }", "map-get: key b is not in the map"},
+		{".x { a: map-keys(red); }", "map-keys expects a detached ruleset or a mixin call"},
+		{"@m: { a: 1; }\n.x { a: map-get(@m, 1px); }", "map-get expects keys"},
+		{"@m: { a: 1; }\n.x { a: map-get(@m); }", "function map-get expects at least 2 arguments, got 1"},
+	}
+	for _, tt := range tests {
+		_, err := Compile(tt.source, nil)
+		if err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("%q: expected an error with %q, got %v", tt.source, tt.want, err)
+		}
+	}
+}
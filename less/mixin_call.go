@@ -3,6 +3,7 @@ package less_go
 import (
 	"fmt"
 	"os"
+	"slices"
 	"strings"
 )
 
@@ -13,6 +14,10 @@ type MixinCallError struct {
 	Index    int
 	Filename string
 	Stack    string
+	// Suggestions, Candidates and Frames are those of LessError
+	Suggestions []string
+	Candidates  []string
+	Frames      []ErrorFrame
 }
 
 func (e *MixinCallError) Error() string {
@@ -87,14 +92,26 @@ func (mc *MixinCall) Accept(visitor any) {
 	}
 }
 
-// Eval evaluates the mixin call
+// Eval evaluates the mixin call, on the call stack of errors
 func (mc *MixinCall) Eval(context any) ([]any, error) {
+	calls := callStackOf(context)
+	depth := calls.enter("mixin", mc)
+	rules, err := mc.eval(context)
+	return rules, calls.leave(depth, err)
+}
+
+func (mc *MixinCall) eval(context any) ([]any, error) {
 	// Add recursion depth check as safety
 	if ctx, ok := context.(map[string]any); ok {
 		if depth, ok := ctx["mixinCallDepth"].(int); ok && depth > 500 {
 			return nil, fmt.Errorf("mixin call recursion limit exceeded")
 		}
 	}
+
+	// Runaway mixin recursion is aborted here when the compilation is cancelled
+	if err := evalContextErr(context); err != nil {
+		return nil, err
+	}
 	
 	var mixins []any
 	var mixin any
@@ -107,6 +124,7 @@ func (mc *MixinCall) Eval(context any) ([]any, error) {
 	var i, m, f int
 	var isRecursive bool
 	var isOneFound bool
+	var found []any // the mixins found, to list them if none matches
 	candidates := []any{}
 	conditionResult := []bool{}
 	var defaultResult int
@@ -331,6 +349,7 @@ func (mc *MixinCall) Eval(context any) ([]any, error) {
 					if len(foundMixins) > 0 {
 						mixins = foundMixins
 						isOneFound = true
+						found = append(found, foundMixins...)
 
 						// DEBUG: Print found mixins
 						if debug {
@@ -529,12 +548,24 @@ func (mc *MixinCall) Eval(context any) ([]any, error) {
 										}
 										
 										if newRuleset, err := mixinDef.EvalCall(callContext, args, mc.Important); err != nil {
-											return nil, &MixinCallError{
+											callErr := &MixinCallError{
 												Message:  err.Error(),
 												Index:    mc.GetIndex(),
 												Filename: getFilename(mc.FileInfo()),
 												Stack:    fmt.Sprintf("%v", err),
 											}
+											// Keep the hints and frames of an error raised in the mixin
+											switch e := err.(type) {
+											case *MixinCallError:
+												callErr.Suggestions, callErr.Candidates, callErr.Frames = e.Suggestions, e.Candidates, e.Frames
+											case *LessError:
+												callErr.Suggestions, callErr.Candidates, callErr.Frames = e.Suggestions, e.Candidates, e.Frames
+											}
+											// The error is now at this call, which is no longer a frame
+											if len(callErr.Frames) > 0 && callErr.Frames[0].Filename == callErr.Filename && callErr.Frames[0].index == callErr.Index {
+												callErr.Frames = callErr.Frames[1:]
+											}
+											return nil, callErr
 										} else {
 											newRules := newRuleset.Rules
 											// Only clear visibility blocks if:
@@ -577,22 +608,34 @@ func (mc *MixinCall) Eval(context any) ([]any, error) {
 
 	// Handle error cases
 	if isOneFound {
+		var signatures []string
+		for _, candidate := range found {
+			if candidateMap, ok := candidate.(map[string]any); ok {
+				if signature := mixinSignature(candidateMap["rule"]); signature != "" && !slices.Contains(signatures, signature) {
+					signatures = append(signatures, signature)
+				}
+			}
+		}
 		return nil, &MixinCallError{
-			Type:     "Runtime",
-			Message:  fmt.Sprintf("No matching definition was found for `%s`", mc.Format(args)),
-			Index:    mc.GetIndex(),
-			Filename: getFilename(mc.FileInfo()),
+			Type:       "Runtime",
+			Message:    fmt.Sprintf("No matching definition was found for `%s`", mc.Format(args)),
+			Index:      mc.GetIndex(),
+			Filename:   getFilename(mc.FileInfo()),
+			Candidates: signatures,
 		}
 	} else {
 		selectorCSS := strings.TrimSpace(mc.Selector.ToCSS(context))
 		if selectorCSS == "" {
 			selectorCSS = "<empty selector>"
 		}
+		// Namespaced calls are compared without combinators, as in #ns.m
+		called := strings.NewReplacer(" ", "", ">", "").Replace(selectorCSS)
 		return nil, &MixinCallError{
-			Type:     "Name",
-			Message:  fmt.Sprintf("%s is undefined", selectorCSS),
-			Index:    mc.GetIndex(),
-			Filename: getFilename(mc.FileInfo()),
+			Type:        "Name",
+			Message:     fmt.Sprintf("%s is undefined", selectorCSS),
+			Index:       mc.GetIndex(),
+			Filename:    getFilename(mc.FileInfo()),
+			Suggestions: suggestNames(called, mixinNames(frames)),
 		}
 	}
 }
@@ -0,0 +1,518 @@
+package less_go
+
+import (
+	"math"
+	"strings"
+)
+
+// modernColorVisitor adds a fallback before each declaration that uses
+// color-mix() or the relative color syntax of CSS Color 5, such as
+// oklch(from #00f calc(l - 0.1) c h), for browsers that support neither:
+// the same declaration with these colors computed at compile time, in sRGB.
+// Declarations with colors that are not constant, e.g. that use var(), are
+// left alone.
+type modernColorVisitor struct {
+	visitor *Visitor
+}
+
+func newModernColorVisitor() *modernColorVisitor {
+	mcv := &modernColorVisitor{}
+	mcv.visitor = NewVisitor(mcv)
+	return mcv
+}
+
+func (mcv *modernColorVisitor) Run(root any) any {
+	return mcv.visitor.Visit(root)
+}
+
+func (mcv *modernColorVisitor) IsReplacing() bool {
+	return false
+}
+
+func (mcv *modernColorVisitor) VisitNode(node any, visitArgs *VisitArgs) (any, bool) {
+	if ruleset, ok := node.(*Ruleset); ok {
+		mcv.VisitRuleset(ruleset, visitArgs)
+	}
+	return node, true
+}
+
+func (mcv *modernColorVisitor) VisitNodeOut(node any) bool {
+	return true
+}
+
+func (mcv *modernColorVisitor) VisitRuleset(ruleset *Ruleset, visitArgs *VisitArgs) {
+	var rules []any
+	for i, rule := range ruleset.Rules {
+		d, ok := rule.(*Declaration)
+		if !ok || d.variable || d.Value == nil {
+			if rules != nil {
+				rules = append(rules, rule)
+			}
+			continue
+		}
+		value, found, resolved := resolveModernColors(d.Value)
+		if found && resolved {
+			fallback, err := NewDeclaration(d.name, value, d.important, d.merge, d.GetIndex(), d.FileInfo(), d.inline, false)
+			if err == nil {
+				fallback.CopyVisibilityInfo(d.VisibilityInfo())
+				if rules == nil {
+					rules = append(make([]any, 0, len(ruleset.Rules)+1), ruleset.Rules[:i]...)
+				}
+				rules = append(rules, fallback)
+			}
+		}
+		if rules != nil {
+			rules = append(rules, rule)
+		}
+	}
+	if rules != nil {
+		ruleset.Rules = rules
+	}
+}
+
+// resolveModernColors returns node with its color-mix() and relative colors
+// replaced by the colors they compute. found is whether there were any, and
+// resolved whether all of them were constant.
+func resolveModernColors(node any) (result any, found bool, resolved bool) {
+	resolveAll := func(nodes []any) ([]any, bool, bool) {
+		out := make([]any, len(nodes))
+		found, resolved := false, true
+		for i, n := range nodes {
+			var f, r bool
+			out[i], f, r = resolveModernColors(n)
+			found = found || f
+			resolved = resolved && r
+		}
+		return out, found, resolved
+	}
+
+	switch n := node.(type) {
+	case *Value:
+		values, found, resolved := resolveAll(n.Value)
+		if !found || !resolved {
+			return node, found, resolved
+		}
+		value, err := NewValue(values)
+		if err != nil {
+			return node, true, false
+		}
+		return value, true, true
+	case *Expression:
+		values, found, resolved := resolveAll(n.Value)
+		if !found || !resolved {
+			return node, found, resolved
+		}
+		expr, err := NewExpression(values, n.NoSpacing)
+		if err != nil {
+			return node, true, false
+		}
+		return expr, true, true
+	case *Call:
+		args, found, resolved := resolveAll(n.Args)
+		if !resolved {
+			return node, found, false
+		}
+		name := strings.ToLower(n.Name)
+		if name == "color-mix" {
+			color := mixColors(args)
+			return color, true, color != nil
+		}
+		if isRelativeColor(args) {
+			color := relativeColor(name, args[0].(*Expression).Value[1:])
+			return color, true, color != nil
+		}
+		if !found {
+			return node, false, true
+		}
+		call := NewCall(n.Name, args, n.GetIndex(), n.FileInfo())
+		call.Calc = n.Calc
+		return call, true, true
+	}
+	return node, false, true
+}
+
+// isRelativeColor reports whether args are those of a color function in the
+// relative color syntax, a single Expression that starts with "from"
+func isRelativeColor(args []any) bool {
+	if len(args) != 1 {
+		return false
+	}
+	expr, ok := args[0].(*Expression)
+	if !ok || len(expr.Value) == 0 {
+		return false
+	}
+	kw, ok := expr.Value[0].(*Keyword)
+	return ok && strings.EqualFold(kw.value, "from")
+}
+
+// relativeColor computes a relative color from the values after "from": the
+// origin color and the channels, e.g. #00f calc(l - 0.1) c h for oklch(), or
+// returns nil if they are not constant
+func relativeColor(fn string, values []any) *Color {
+	if len(values) < 1 {
+		return nil
+	}
+	origin, ok := values[0].(*Color)
+	if !ok {
+		return nil
+	}
+	values = values[1:]
+
+	var space string
+	switch fn {
+	case "rgb", "rgba":
+		space = "rgb"
+	case "hsl", "hsla":
+		space = "hsl"
+	case "hwb", "lab", "lch", "oklab", "oklch":
+		space = fn
+	case "color":
+		if len(values) == 0 {
+			return nil
+		}
+		kw, ok := values[0].(*Keyword)
+		if !ok {
+			return nil
+		}
+		space = strings.ToLower(kw.value)
+		switch space {
+		case "hsl", "hwb", "lab", "lch", "oklab", "oklch":
+			return nil
+		}
+		values = values[1:]
+	default:
+		return nil
+	}
+	names, ok := channelNames(space)
+	if !ok || len(values) != 3 {
+		return nil
+	}
+
+	// The channels are numbers in the space of the function, e.g. l is 0.5
+	// for oklch() but 50 for lab()
+	coords := origin.coordsIn(space)
+	vars := map[string]float64{"alpha": origin.Alpha}
+	for i, name := range names {
+		vars[name] = coords[i]
+	}
+
+	values = append([]any(nil), values...)
+	alpha := origin.Alpha
+	if op, isOp := values[2].(*Operation); isOp && op.Op == "/" && len(op.Operands) == 2 {
+		values[2] = op.Operands[0]
+		a, ok := relativeChannel(op.Operands[1], vars, 1, false)
+		if !ok {
+			return nil
+		}
+		alpha = clampUnit(a)
+	}
+	percents := spacePercents(space)
+	var channels [3]float64
+	for i, v := range values {
+		channel, ok := relativeChannel(v, vars, percents[i], percents[i] == 0)
+		if !ok {
+			return nil
+		}
+		channels[i] = channel
+	}
+	return colorFromCoords(space, channels, alpha)
+}
+
+// relativeChannel computes a channel of a relative color: a channel keyword of
+// the origin color, a number, a percentage of hundred, an angle if hue, or a
+// calc() of them
+func relativeChannel(v any, vars map[string]float64, hundred float64, hue bool) (float64, bool) {
+	switch n := v.(type) {
+	case *Keyword:
+		if value, ok := vars[strings.ToLower(n.value)]; ok {
+			return value, true
+		}
+	case *Call:
+		if strings.EqualFold(n.Name, "calc") && len(n.Args) == 1 {
+			return relativeChannel(n.Args[0], vars, hundred, hue)
+		}
+		return 0, false
+	case *Expression:
+		if len(n.Value) == 1 {
+			return relativeChannel(n.Value[0], vars, hundred, hue)
+		}
+		return 0, false
+	case *Paren:
+		return relativeChannel(n.Value, vars, hundred, hue)
+	case *Negative:
+		value, ok := relativeChannel(n.Value, vars, hundred, hue)
+		return -value, ok
+	case *Operation:
+		if len(n.Operands) != 2 {
+			return 0, false
+		}
+		a, ok := relativeChannel(n.Operands[0], vars, hundred, hue)
+		if !ok {
+			return 0, false
+		}
+		b, ok := relativeChannel(n.Operands[1], vars, hundred, hue)
+		if !ok {
+			return 0, false
+		}
+		switch n.Op {
+		case "+":
+			return a + b, true
+		case "-":
+			return a - b, true
+		case "*":
+			return a * b, true
+		case "/":
+			if b == 0 {
+				return 0, false
+			}
+			return a / b, true
+		}
+		return 0, false
+	}
+	return colorChannel(v, hundred, hue)
+}
+
+// mixColors computes color-mix(in <space> [<hue method> hue], a [p1], b [p2])
+// as CSS Color 5 specifies, or returns nil if its arguments are not constant
+func mixColors(args []any) *Color {
+	if len(args) != 3 {
+		return nil
+	}
+	space, method, ok := mixInterpolation(args[0])
+	if !ok {
+		return nil
+	}
+	c1, p1, ok1 := mixComponent(args[1])
+	c2, p2, ok2 := mixComponent(args[2])
+	if !ok1 || !ok2 {
+		return nil
+	}
+
+	// A missing percentage is what the other leaves of 100%; they are scaled
+	// to add up to 100%, and a sum below it makes the mix transparent as much
+	switch {
+	case math.IsNaN(p1) && math.IsNaN(p2):
+		p1, p2 = 50, 50
+	case math.IsNaN(p1):
+		p1 = 100 - p2
+	case math.IsNaN(p2):
+		p2 = 100 - p1
+	}
+	sum := p1 + p2
+	if p1 < 0 || p2 < 0 || p1 > 100 || p2 > 100 || sum == 0 {
+		return nil
+	}
+	alphaScale := math.Min(sum/100, 1)
+	t := p2 / sum
+
+	a, b := c1.coordsIn(space), c2.coordsIn(space)
+	percents := spacePercents(space)
+	hueIndex := -1
+	for i, p := range percents {
+		if p == 0 {
+			hueIndex = i
+		}
+	}
+	if hueIndex >= 0 {
+		// The hue of a gray is missing and takes that of the other color
+		if achromatic(space, a) && !achromatic(space, b) {
+			a[hueIndex] = b[hueIndex]
+		} else if achromatic(space, b) {
+			b[hueIndex] = a[hueIndex]
+		}
+		a[hueIndex], b[hueIndex] = fixupHues(a[hueIndex], b[hueIndex], method)
+	}
+
+	// Channels are interpolated premultiplied by alpha, except the hue
+	alpha := c1.Alpha*(1-t) + c2.Alpha*t
+	var mixed [3]float64
+	for i := range mixed {
+		if i == hueIndex {
+			mixed[i] = math.Mod(a[i]*(1-t)+b[i]*t, 360)
+			continue
+		}
+		mixed[i] = a[i]*c1.Alpha*(1-t) + b[i]*c2.Alpha*t
+		if alpha > 0 {
+			mixed[i] /= alpha
+		}
+	}
+	return colorFromCoords(space, mixed, alpha*alphaScale)
+}
+
+// mixInterpolation reads the color space and hue interpolation method of
+// color-mix(), as in oklch longer hue
+func mixInterpolation(arg any) (space string, method string, ok bool) {
+	expr, isExpr := arg.(*Expression)
+	if !isExpr {
+		return "", "", false
+	}
+	var words []string
+	for _, v := range expr.Value {
+		kw, isKeyword := v.(*Keyword)
+		if !isKeyword {
+			return "", "", false
+		}
+		words = append(words, strings.ToLower(kw.value))
+	}
+	if len(words) < 2 || words[0] != "in" {
+		return "", "", false
+	}
+	space = words[1]
+	if _, known := channelNames(space); !known || space == "rgb" {
+		return "", "", false
+	}
+	method = "shorter"
+	switch len(words) {
+	case 2:
+	case 4:
+		percents := spacePercents(space)
+		if words[3] != "hue" || (percents[0] != 0 && percents[2] != 0) {
+			return "", "", false
+		}
+		method = words[2]
+		switch method {
+		case "shorter", "longer", "increasing", "decreasing":
+		default:
+			return "", "", false
+		}
+	default:
+		return "", "", false
+	}
+	return space, method, true
+}
+
+// mixComponent reads a color of color-mix() and its percentage, NaN if it
+// has none
+func mixComponent(arg any) (*Color, float64, bool) {
+	if color, ok := arg.(*Color); ok {
+		return color, math.NaN(), true
+	}
+	expr, ok := arg.(*Expression)
+	if !ok || len(expr.Value) != 2 {
+		return nil, 0, false
+	}
+	color, ok := expr.Value[0].(*Color)
+	percentage := expr.Value[1]
+	if !ok {
+		color, ok = expr.Value[1].(*Color)
+		percentage = expr.Value[0]
+	}
+	dim, isDim := percentage.(*Dimension)
+	if !ok || !isDim || dim.Unit == nil || dim.Unit.ToString() != "%" {
+		return nil, 0, false
+	}
+	return color, dim.Value, true
+}
+
+// fixupHues adjusts two hues in degrees for interpolating between them with a
+// hue interpolation method of CSS Color 4
+func fixupHues(h1, h2 float64, method string) (float64, float64) {
+	h1 = math.Mod(math.Mod(h1, 360)+360, 360)
+	h2 = math.Mod(math.Mod(h2, 360)+360, 360)
+	d := h2 - h1
+	switch method {
+	case "shorter":
+		if d > 180 {
+			h1 += 360
+		} else if d < -180 {
+			h2 += 360
+		}
+	case "longer":
+		if 0 < d && d < 180 {
+			h1 += 360
+		} else if -180 < d && d <= 0 {
+			h2 += 360
+		}
+	case "increasing":
+		if d < 0 {
+			h2 += 360
+		}
+	case "decreasing":
+		if d > 0 {
+			h1 += 360
+		}
+	}
+	return h1, h2
+}
+
+// achromatic reports whether a color is a gray in a space with a hue, whose
+// hue is then missing
+func achromatic(space string, coords [3]float64) bool {
+	switch space {
+	case "hsl":
+		return coords[1] <= 0
+	case "hwb":
+		return coords[1]+coords[2] >= 100
+	}
+	return coords[1] < spacePercents(space)[1]*1e-4
+}
+
+// channelNames returns the names of the channels of a color space in the
+// relative color syntax
+func channelNames(space string) ([3]string, bool) {
+	switch space {
+	case "rgb", "srgb", "srgb-linear", "display-p3":
+		return [3]string{"r", "g", "b"}, true
+	case "hsl":
+		return [3]string{"h", "s", "l"}, true
+	case "hwb":
+		return [3]string{"h", "w", "b"}, true
+	case "lab", "oklab":
+		return [3]string{"l", "a", "b"}, true
+	case "lch", "oklch":
+		return [3]string{"l", "c", "h"}, true
+	case "xyz", "xyz-d65", "xyz-d50":
+		return [3]string{"x", "y", "z"}, true
+	}
+	return [3]string{}, false
+}
+
+// spacePercents returns the values of 100% for each channel of a color space,
+// 0 for a hue; those of rgb(), hsl() and hwb() are those of CSS Color 5 for
+// relative colors, e.g. 100 for the saturation of hsl()
+func spacePercents(space string) [3]float64 {
+	switch space {
+	case "rgb":
+		return [3]float64{255, 255, 255}
+	case "hsl", "hwb":
+		return [3]float64{0, 100, 100}
+	}
+	return colorSpaces[space].percents
+}
+
+// coordsIn returns the color in a color space of CSS Color 4, or in that of
+// rgb(), hsl() or hwb() with the channels of spacePercents
+func (c *Color) coordsIn(space string) [3]float64 {
+	rgb := c.GetRGB()
+	if len(rgb) < 3 {
+		rgb = []float64{0, 0, 0}
+	}
+	switch space {
+	case "rgb":
+		return [3]float64{rgb[0], rgb[1], rgb[2]}
+	case "hsl":
+		hsl := c.ToHSL()
+		return [3]float64{hsl.H, hsl.S * 100, hsl.L * 100}
+	case "hwb":
+		hsl := c.ToHSL()
+		w := math.Min(rgb[0], math.Min(rgb[1], rgb[2])) / 255
+		b := 1 - math.Max(rgb[0], math.Max(rgb[1], rgb[2]))/255
+		return [3]float64{hsl.H, w * 100, b * 100}
+	}
+	return colorSpaces[space].fromXYZ(c.xyz())
+}
+
+// colorFromCoords returns the sRGB color of coordinates in a color space, as
+// read by coordsIn, mapped into the sRGB gamut
+func colorFromCoords(space string, coords [3]float64, alpha float64) *Color {
+	switch space {
+	case "rgb":
+		return NewColor([]float64{clamp(coords[0], 255), clamp(coords[1], 255), clamp(coords[2], 255)}, alpha, "")
+	case "hsl":
+		color := colorHSLA(math.Mod(math.Mod(coords[0], 360)+360, 360), coords[1]/100, coords[2]/100, alpha)
+		return NewColor(color.RGB, color.Alpha, "")
+	case "hwb":
+		return NewColor(hwbToRGB(coords[0], coords[1]/100, coords[2]/100), clampUnit(alpha), "")
+	}
+	return NewColor(gamutMapSRGB(colorSpaces[space].toXYZ(coords)), clampUnit(alpha), "")
+}
@@ -0,0 +1,113 @@
+package less_go
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestCompile_ResolveModernColors(t *testing.T) {
+	input := `.a {
+  mix: color-mix(in srgb, red, blue);
+  mix-oklch: color-mix(in oklch, red 40%, blue);
+  mix-alpha: color-mix(in srgb, red 20%, blue 20%);
+  mix-hue: color-mix(in hsl longer hue, red, blue);
+  mix-gray: color-mix(in oklch, white, blue);
+  relative: oklch(from #0000FF calc(l - 0.1) c h);
+  relative-rgb: rgb(from red r g b / 50%);
+  relative-hsl: hsl(from red calc(h + 120) s l);
+  relative-color: color(from red display-p3 r g b);
+  nested: color-mix(in srgb, red, oklch(from blue l c h)) 1px solid !important;
+  gradient: linear-gradient(color-mix(in srgb, red 25%, white), blue);
+  dynamic: color-mix(in srgb, var(--x), red);
+  plain: red;
+}
+@media (min-width: 1px) {
+  .b { color: color-mix(in srgb, red, blue); }
+}
+`
+	result, err := Compile(input, &CompileOptions{ResolveModernColors: ModernColors.SRGBHex})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{
+		"mix: #800080;\n  mix: color-mix(in srgb, red, blue);",
+		"mix-oklch: #a100d5;\n  mix-oklch: color-mix(in oklch, red 40%, blue);",
+		"mix-alpha: rgba(128, 0, 128, 0.4);",
+		"mix-hue: #00ff00;",
+		"mix-gray: #74a3ff;",
+		"relative: #0400c2;\n  relative: oklch(from #0000FF calc(l - 0.1) c h);",
+		"relative-rgb: rgba(255, 0, 0, 0.5);",
+		"relative-hsl: #00ff00;",
+		"relative-color: #ff0000;\n  relative-color: color(from red display-p3 r g b);",
+		"nested: #800080 1px solid !important;\n  nested: color-mix(in srgb, red, oklch(from blue l c h)) 1px solid !important;",
+		"gradient: linear-gradient(#ffbfbf, blue);",
+		"color: #800080;\n    color: color-mix(in srgb, red, blue);",
+	} {
+		if !strings.Contains(result.CSS, want) {
+			t.Errorf("expected %q in:\n%s", want, result.CSS)
+		}
+	}
+	// Declarations whose colors are not constant have no fallback
+	for _, name := range []string{"dynamic", "plain"} {
+		if n := strings.Count(result.CSS, name+":"); n != 1 {
+			t.Errorf("expected one %s declaration, got %d", name, n)
+		}
+	}
+
+	// Without the option, the declarations are output as written
+	result, err = Compile(input, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(result.CSS, "#800080") || !strings.Contains(result.CSS, "relative-color: color(from red display-p3 r g b);") {
+		t.Errorf("expected no fallbacks:\n%s", result.CSS)
+	}
+}
+
+func TestMixColors(t *testing.T) {
+	red, blue := NewColor([]float64{255, 0, 0}, 1, ""), NewColor([]float64{0, 0, 255}, 1, "")
+	in := func(words ...string) *Expression {
+		values := make([]any, len(words))
+		for i, w := range words {
+			values[i] = NewKeyword(w)
+		}
+		expr, _ := NewExpression(values, false)
+		return expr
+	}
+	percent := func(c *Color, p float64) *Expression {
+		expr, _ := NewExpression([]any{c, makeDimension(p, "%")}, false)
+		return expr
+	}
+	tests := []struct {
+		name string
+		args []any
+		want string
+	}{
+		{"default 50%", []any{in("in", "srgb"), red, blue}, "#800080"},
+		{"one percentage", []any{in("in", "srgb"), percent(red, 75), blue}, "#bf0040"},
+		{"over 100%", []any{in("in", "srgb-linear"), percent(red, 100), percent(blue, 100)}, "#bc00bc"},
+		{"hue increasing", []any{in("in", "oklch", "increasing", "hue"), red, blue}, "#008a0e"},
+		{"translucent", []any{in("in", "srgb"), red, NewColor([]float64{0, 0, 255}, 0, "")}, "rgba(255, 0, 0, 0.5)"},
+	}
+	for _, tt := range tests {
+		c := mixColors(tt.args)
+		if c == nil {
+			t.Errorf("%s: not resolved", tt.name)
+			continue
+		}
+		if got := c.ToCSS(nil); got != tt.want {
+			t.Errorf("%s: got %s, want %s", tt.name, got, tt.want)
+		}
+	}
+
+	for _, args := range [][]any{
+		{in("in", "srgb"), percent(red, 0), percent(blue, 0)},
+		{in("in", "srgb", "longer", "hue"), red, blue},
+		{in("in", "cmyk"), red, blue},
+		{in("in", "srgb"), red, NewKeyword("currentcolor")},
+	} {
+		if c := mixColors(args); c != nil {
+			t.Errorf("expected %v not to be resolved, got %s", args, c.ToCSS(nil))
+		}
+	}
+}
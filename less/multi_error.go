@@ -0,0 +1,244 @@
+package less_go
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+)
+
+// MultiError is the error of a compilation with CollectErrors set: all of its
+// errors, in the order they were found. errors.As finds the first *LessError.
+type MultiError struct {
+	Errors []*LessError
+}
+
+// Error lists the errors, one per line
+func (m *MultiError) Error() string {
+	lines := make([]string, len(m.Errors))
+	for i, e := range m.Errors {
+		lines[i] = e.Error()
+	}
+	return strings.Join(lines, "\n")
+}
+
+// Unwrap returns the errors, for errors.Is and errors.As
+func (m *MultiError) Unwrap() []error {
+	errs := make([]error, len(m.Errors))
+	for i, e := range m.Errors {
+		errs[i] = e
+	}
+	return errs
+}
+
+// errorCollector gathers the errors of a compilation with CollectErrors set,
+// so that the parser skips invalid statements and evaluation skips the rules
+// that fail instead of stopping at the first error. A nil collector collects
+// nothing and errors abort the compilation as usual.
+type errorCollector struct {
+	mu     sync.Mutex
+	errors []any // *LessError or *MixinCallError
+}
+
+func newErrorCollector() *errorCollector {
+	return &errorCollector{}
+}
+
+// errorCollectorOf returns the collector of an *Eval or map context, or nil
+func errorCollectorOf(context any) *errorCollector {
+	switch c := context.(type) {
+	case *Eval:
+		return c.errors
+	case map[string]any:
+		if errs, ok := c["errors"].(*errorCollector); ok {
+			return errs
+		}
+		if evalCtx, ok := c["_evalContext"].(*Eval); ok {
+			return evalCtx.errors
+		}
+	}
+	return nil
+}
+
+// add records err if it is a *LessError or *MixinCallError other than a
+// cancellation, and reports whether it did
+func (c *errorCollector) add(err any) bool {
+	if c == nil {
+		return false
+	}
+	switch e := err.(type) {
+	case *LessError:
+		if e.Type == "Cancel" {
+			return false
+		}
+	case *MixinCallError:
+	default:
+		return false
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.errors = append(c.errors, err)
+	return true
+}
+
+// count returns the number of errors recorded so far
+func (c *errorCollector) count() int {
+	if c == nil {
+		return 0
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return len(c.errors)
+}
+
+// locate gives the errors recorded since the first from of them that have no
+// position, such as those of operations, the position of the node they were
+// raised in, as less.js does for declarations
+func (c *errorCollector) locate(from int, index int, filename string) {
+	if c == nil {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for _, err := range c.errors[min(from, len(c.errors)):] {
+		if e, ok := err.(*LessError); ok && e.Index == nil {
+			e.Index = index
+			if e.Filename == "" {
+				e.Filename = filename
+			}
+		}
+	}
+}
+
+// collect calls eval, which evaluates one rule. With a collector, an error
+// eval returns or panics with is recorded instead, the evaluation state is
+// restored and skip is true; other errors, such as cancellation, are returned.
+// Without a collector, eval's error is returned as is.
+func (c *errorCollector) collect(context any, eval func() error) (skip bool, err error) {
+	if c == nil {
+		return false, eval()
+	}
+	restore := saveEvalState(context)
+	calls := callStackOf(context)
+	defer func() {
+		if r := recover(); r != nil {
+			calls.trace(r)
+			if !c.add(r) {
+				panic(r)
+			}
+			restore()
+			skip = true
+		}
+	}()
+	if err := eval(); err != nil {
+		calls.trace(err)
+		if !c.add(err) {
+			return false, err
+		}
+		restore()
+		return true, nil
+	}
+	return false, nil
+}
+
+// saveEvalState returns a function restoring the stacks of context that a
+// failed evaluation may leave unbalanced
+func saveEvalState(context any) func() {
+	switch c := context.(type) {
+	case *Eval:
+		frames, selectors, importantScope := c.Frames, c.SelectorStack, c.ImportantScope
+		calcStack, parensStack, inCalc := c.CalcStack, c.ParensStack, c.InCalc
+		depth := c.calls.depth()
+		return func() {
+			c.calls.leave(depth, nil)
+			c.SetFrames(frames)
+			c.SelectorStack, c.ImportantScope = selectors, importantScope
+			c.CalcStack, c.ParensStack, c.InCalc = calcStack, parensStack, inCalc
+		}
+	case map[string]any:
+		saved := make(map[string]any, 3)
+		for _, key := range []string{"frames", "selectors", "importantScope"} {
+			if v, ok := c[key]; ok {
+				saved[key] = v
+			}
+		}
+		return func() {
+			for _, key := range []string{"frames", "selectors", "importantScope"} {
+				if v, ok := saved[key]; ok {
+					c[key] = v
+				} else {
+					delete(c, key)
+				}
+			}
+		}
+	}
+	return func() {}
+}
+
+// result returns the recorded errors, followed by fatal, the error that ended
+// the compilation if any, as a *MultiError, or nil if there are none. Lines and
+// columns are worked out from contents for errors raised during evaluation, and
+// an error recorded more than once at the same place, e.g. in a mixin called
+// several times, is returned once. A fatal error that is not a *LessError, such
+// as a cancellation, is returned as is.
+func (c *errorCollector) result(fatal error, contents map[string]string, rootFilename string) error {
+	var fatalErr *LessError
+	if fatal != nil && !errors.As(fatal, &fatalErr) {
+		return fatal
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	multi := &MultiError{}
+	seen := make(map[string]bool)
+	for _, err := range append(c.errors, fatalErr) {
+		e := positionedError(err, contents, rootFilename)
+		if e == nil {
+			continue
+		}
+		key := fmt.Sprintf("%s:%v:%s", e.Filename, e.Index, e.Message)
+		if !seen[key] {
+			seen[key] = true
+			multi.Errors = append(multi.Errors, e)
+		}
+	}
+	if len(multi.Errors) == 0 {
+		return nil
+	}
+	return multi
+}
+
+// positionedError converts an error raised while evaluating, a *LessError or
+// *MixinCallError, to a *LessError with its line, column and extract, as
+// less.js does when it reports the error
+func positionedError(err any, contents map[string]string, rootFilename string) *LessError {
+	switch e := err.(type) {
+	case *LessError:
+		if e == nil {
+			return nil
+		}
+		positionFrames(e.Frames, contents)
+		if e.Line != nil {
+			return e
+		}
+		positioned := NewLessError(ErrorDetails{
+			Type:     e.Type,
+			Message:  e.Message,
+			Stack:    e.Stack,
+			Filename: e.Filename,
+			Index:    e.Index,
+		}, contents, rootFilename)
+		positioned.Suggestions, positioned.Candidates, positioned.Frames = e.Suggestions, e.Candidates, e.Frames
+		return positioned
+	case *MixinCallError:
+		positionFrames(e.Frames, contents)
+		positioned := NewLessError(ErrorDetails{
+			Type:     e.Type,
+			Message:  e.Message,
+			Filename: e.Filename,
+			Index:    e.Index,
+		}, contents, rootFilename)
+		positioned.Suggestions, positioned.Candidates, positioned.Frames = e.Suggestions, e.Candidates, e.Frames
+		return positioned
+	}
+	return nil
+}
@@ -0,0 +1,135 @@
+package less_go
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+// errorList formats the errors of a *MultiError as "Type line:column"
+func errorList(t *testing.T, err error) []string {
+	t.Helper()
+	var multi *MultiError
+	if !errors.As(err, &multi) {
+		t.Fatalf("expected a *MultiError, got %T: %v", err, err)
+	}
+	var list []string
+	for _, e := range multi.Errors {
+		list = append(list, fmt.Sprintf("%s %s:%d:%d", e.Type, e.Filename, e.LineNumber(), e.Column))
+	}
+	return list
+}
+
+func TestCompile_CollectErrors(t *testing.T) {
+	input := `@a: 1px;
+.a {
+  color: @nope;
+  .missing();
+  height: @a;
+}
+.b { color red }
+.c {
+  w: percentage("x");
+}
+}
+.d { e: @a; }
+`
+	_, err := Compile(input, &CompileOptions{Filename: "main.less"})
+	var lessErr *LessError
+	if !errors.As(err, &lessErr) || lessErr.LineNumber() != 7 {
+		t.Fatalf("expected the first error without CollectErrors, got %v", err)
+	}
+
+	_, err = Compile(input, &CompileOptions{Filename: "main.less", CollectErrors: true})
+	want := []string{
+		"Parse main.less:7:15",
+		"Parse main.less:11:0",
+		"Name main.less:4:2",
+		"Name main.less:3:9",
+		"Argument main.less:9:5",
+	}
+	if got := errorList(t, err); strings.Join(got, ", ") != strings.Join(want, ", ") {
+		t.Errorf("got %v, want %v", got, want)
+	}
+	if !errors.As(err, &lessErr) || lessErr.Message != "Unrecognised input. Possibly missing opening '{'" {
+		t.Errorf("expected errors.As to find the first error, got %v", lessErr)
+	}
+}
+
+func TestCompile_CollectErrorsRepeated(t *testing.T) {
+	// An error in a mixin called twice is reported once
+	input := ".m() { a: @nope; }\n.x { .m(); }\n.y { .m(); }"
+	_, err := Compile(input, &CompileOptions{CollectErrors: true})
+	if got := errorList(t, err); len(got) != 1 || got[0] != "Name input:1:10" {
+		t.Errorf("expected one error, got %v", got)
+	}
+}
+
+func TestCompile_CollectErrorsInImports(t *testing.T) {
+	fsys := fstest.MapFS{
+		"base.less": {Data: []byte(".a { b c }\n.d { e: f; }\n")},
+	}
+	compiler := NewCompiler(&CompileOptions{FS: fsys, CollectErrors: true})
+	for i := 0; i < 2; i++ {
+		// The second compilation would reuse the cached tree of base.less
+		_, err := compiler.Compile("@import \"base.less\";\n.g { .d(); h: 1 + ~\"x\"; }", "main.less")
+		want := []string{"Parse base.less:1:9", "Operation main.less:2:11"}
+		if got := errorList(t, err); strings.Join(got, ", ") != strings.Join(want, ", ") {
+			t.Errorf("compilation %d: got %v, want %v", i+1, got, want)
+		}
+	}
+}
+
+func TestCompile_CollectErrorsValid(t *testing.T) {
+	result, err := Compile(".a { .b { c: d; } }", &CompileOptions{CollectErrors: true})
+	if err != nil || result.CSS != ".a .b {\n  c: d;\n}\n" {
+		t.Errorf("unexpected result %v, %v", result, err)
+	}
+}
+
+// TestCompile_CollectErrorsMatchesTestSuite checks that collecting errors does
+// not change the CSS of the test suite's stylesheets or report errors in them
+func TestCompile_CollectErrorsMatchesTestSuite(t *testing.T) {
+	files, err := filepath.Glob("../testdata/less/_main/*.less")
+	if err != nil || len(files) == 0 {
+		t.Skip("test data not found")
+	}
+	for _, file := range files {
+		src, err := os.ReadFile(file)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if strings.Contains(string(src), "https:") || strings.Contains(string(src), "@plugin") {
+			continue // remote imports, or plugins defining variables
+		}
+		filename, _ := filepath.Abs(file)
+		want, err := Compile(string(src), &CompileOptions{Filename: filename, Math: Math.ParensDivision})
+		if err != nil {
+			continue
+		}
+		got, err := Compile(string(src), &CompileOptions{Filename: filename, Math: Math.ParensDivision, CollectErrors: true})
+		if err != nil {
+			t.Errorf("%s: %v", filepath.Base(file), err)
+		} else if got.CSS != want.CSS {
+			t.Errorf("%s: compiles differently when collecting errors", filepath.Base(file))
+		}
+	}
+}
+
+func TestParseStylesheet_CollectErrors(t *testing.T) {
+	sheet, err := ParseStylesheet(".a { b: c; d e; f: g; }\n@h: (;\n.i { j: k }\n", &CompileOptions{CollectErrors: true})
+	want := []string{"Parse input:1:14", "Syntax input:2:2"}
+	if got := errorList(t, err); strings.Join(got, ", ") != strings.Join(want, ", ") {
+		t.Errorf("got %v, want %v", got, want)
+	}
+	if sheet == nil {
+		t.Fatal("expected the stylesheet of the valid statements")
+	}
+	if got := sheet.String(); got != ".a {\n  b: c;\n  f: g;\n}\n.i {\n  j: k;\n}\n" {
+		t.Errorf("unexpected stylesheet:\n%s", got)
+	}
+}
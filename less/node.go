@@ -242,9 +242,6 @@ func (n *Node) Operate(context any, op string, a, b float64) float64 {
 	}
 }
 
-// precisionFormats caches format strings for common precision values
-var precisionFormats = make(map[int]string)
-
 // Fround rounds a float value based on context precision
 func (n *Node) Fround(context any, value float64) float64 {
 	var precision int
@@ -264,14 +261,7 @@ func (n *Node) Fround(context any, value float64) float64 {
 		epsilon := 2e-16
 		rounded := value + epsilon
 
-		// Use cached format string or create and cache it
-		format, ok := precisionFormats[precision]
-		if !ok {
-			format = fmt.Sprintf("%%.%df", precision)
-			precisionFormats[precision] = format
-		}
-
-		formatted := fmt.Sprintf(format, rounded)
+		formatted := strconv.FormatFloat(rounded, 'f', precision, 64)
 		result, _ := strconv.ParseFloat(formatted, 64)
 		return result
 	}
@@ -336,6 +336,16 @@ func (b *NodeJSPluginBridge) SetScope(scope *runtime.PluginScope) {
 	b.scope = scope
 }
 
+// SetCancel aborts in-flight plugin calls when done is closed.
+// It is typically the Done channel of the compilation's context.Context.
+func (b *NodeJSPluginBridge) SetCancel(done <-chan struct{}) {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	if b.runtime != nil {
+		b.runtime.SetCancel(done)
+	}
+}
+
 // Close shuts down the Node.js runtime.
 // This should be called when the compilation is complete.
 func (b *NodeJSPluginBridge) Close() error {
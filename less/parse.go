@@ -3,7 +3,7 @@ package less_go
 import (
 	"fmt"
 	"os"
-	"runtime"
+	"runtime/debug"
 	"strings"
 )
 
@@ -40,6 +40,9 @@ func NewLessContext(options map[string]any) *LessContext {
 // The returned cleanup function should be called after compilation to shut down Node.js.
 func NewLessContextWithPlugins(options map[string]any) (*LessContext, func() error) {
 	bridge := NewLazyNodeJSPluginBridge()
+	if runtimes, ok := options["pluginRuntimes"].(*pluginRuntimePool); ok && runtimes != nil {
+		bridge = newPooledLazyNodeJSPluginBridge(runtimes)
+	}
 
 	ctx := &LessContext{
 		Options:      options,
@@ -144,9 +147,14 @@ func performParse(lessContext *LessContext, input string, options map[string]any
 	var rootFileInfo map[string]any
 
 	// Create plugin manager (equivalent to: const pluginManager = new PluginManager(this, !options.reUsePluginManager))
-	// Note: reUsePluginManager affects plugin manager creation behavior in JavaScript
-	pluginManager := NewPluginManager(lessContext)
-	actualOptions["pluginManager"] = pluginManager
+	// Note: reUsePluginManager affects plugin manager creation behavior in JavaScript.
+	// Here a plugin manager passed in the options is reused, so that the plugins
+	// installed by the caller (e.g. CompileOptions.GoPlugins) apply to the parse.
+	pluginManager, _ := actualOptions["pluginManager"].(*PluginManager)
+	if pluginManager == nil {
+		pluginManager = NewPluginManager(lessContext)
+		actualOptions["pluginManager"] = pluginManager
+	}
 
 	// Pass the plugin bridge through options for use in TransformTree/Eval
 	if lessContext.PluginBridge != nil {
@@ -269,9 +277,27 @@ func performParse(lessContext *LessContext, input string, options map[string]any
 	if dumpLineNumbers, ok := normalizeDumpLineNumbersOption(context.DumpLineNumbers); ok {
 		contextMap["dumpLineNumbers"] = dumpLineNumbers
 	}
+	if ctx, ok := actualOptions["ctx"]; ok {
+		contextMap["ctx"] = ctx
+	}
+	if diagnostics, ok := actualOptions["diagnostics"]; ok {
+		contextMap["diagnostics"] = diagnostics
+	}
+	if errs, ok := actualOptions["errors"]; ok {
+		contextMap["errors"] = errs
+	}
+	if handler, ok := actualOptions["panicHandler"]; ok {
+		contextMap["panicHandler"] = handler
+	}
 
+	// As in less.js, the root file's contents are kept with those of its imports,
+	// so that warnings and errors found after parsing can be located in it
+	contents := make(map[string]string)
+	if imports != nil && imports.Contents() != nil {
+		contents = imports.Contents()
+	}
 	importsMap := map[string]any{
-		"contents":             make(map[string]string),
+		"contents":             contents,
 		"contentsIgnoredChars": make(map[string]int),
 		"rootFilename":         rootFileInfo["filename"],
 	}
@@ -324,26 +350,17 @@ func createPromise(lessContext *LessContext, input string, options map[string]an
 		// Add panic recovery for goroutine - panics in goroutines need separate recovery
 		defer func() {
 			if r := recover(); r != nil {
-				// Get stack trace for debugging
-				buf := make([]byte, 4096)
-				n := runtime.Stack(buf, false)
-				stackTrace := string(buf[:n])
+				if isInternalPanic(r) {
+					handler, _ := options["panicHandler"].(func(any, []byte))
+					r = internalError(r, debug.Stack(), "", nil, nil, "", handler)
+				}
 
-				// Format error message
-				var errMsg string
+				// Set error in promise
 				if err, ok := r.(error); ok {
-					errMsg = err.Error()
+					promise.error = err
 				} else {
-					errMsg = fmt.Sprintf("%v", r)
-				}
-
-				// Log stack trace for array bounds and nil pointer errors
-				if strings.Contains(errMsg, "index out of range") || strings.Contains(errMsg, "nil pointer") {
-					fmt.Fprintf(os.Stderr, "\n=== PANIC in parse goroutine ===\nError: %s\nStack:\n%s\n===\n", errMsg, stackTrace)
+					promise.error = fmt.Errorf("%v", r)
 				}
-
-				// Set error in promise
-				promise.error = fmt.Errorf("%s", errMsg)
 				promise.done <- true
 			}
 		}()
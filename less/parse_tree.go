@@ -1,11 +1,16 @@
 package less_go
 
 import (
+	"context"
 	"encoding/base64"
 	"fmt"
+	"io"
+	"io/fs"
 	"os"
-	"runtime"
+	"runtime/debug"
 	"strings"
+
+	"github.com/toakleaf/less.go/less/runtime"
 )
 
 // sourceMapEnv implements SourceMapEnvironment for base64 encoding
@@ -58,12 +63,32 @@ type ToCSSOptions struct {
 	Functions         any
 	ProcessImports    bool
 	ImportManager     any
-	RewriteUrls       any      // Can be string ("all", "local", "off") or RewriteUrlsType
-	Rootpath          string   // Root path for URL rewriting
-	Math              MathType // Math mode for operations (ALWAYS, PARENS_DIVISION, PARENS)
-	Paths             []string // Include paths for resolving imports and file references
-	UrlArgs           string   // Query string to append to URLs (e.g., "424242")
-	JavascriptEnabled bool     // Enable inline JavaScript evaluation
+	RewriteUrls       any                  // Can be string ("all", "local", "off") or RewriteUrlsType
+	Rootpath          string               // Root path for URL rewriting
+	Math              MathType             // Math mode for operations (ALWAYS, PARENS_DIVISION, PARENS)
+	Paths             []string             // Include paths for resolving imports and file references
+	UrlArgs           string               // Query string to append to URLs (e.g., "424242")
+	JavascriptEnabled bool                 // Enable inline JavaScript evaluation
+	Context           context.Context      // Cancellation and deadline for import loading and evaluation
+	FS                fs.FS                // Virtual file system for data-uri and image-size (nil means disk)
+	FileManagers      []FileManager        // Custom file managers for data-uri and image-size
+	Dependencies      *DependencyGraph     // Collects the files read by data-uri and image-size
+	Diagnostics       *diagnosticCollector // Collects the warnings of the compilation
+	Errors            *errorCollector      // Collects the errors of the compilation when CollectErrors is set
+	PanicHandler      func(any, []byte)    // Receives the internal panics of the compilation and their stack
+	Output            io.Writer            // Receives the CSS as it is generated, instead of ToCSSResult.CSS
+	MapOutput         io.Writer            // Receives the source map, instead of ToCSSResult.Map
+
+	// ResolveModernColors adds fallbacks with computed colors to the declarations
+	// that use color-mix() or relative colors
+	ResolveModernColors ModernColorsType
+
+	// MinContrastRatio is the WCAG contrast ratio under which the color and
+	// background of a ruleset are reported
+	MinContrastRatio float64
+
+	// OnEvaluated is called with the evaluated tree, before the visitors run
+	OnEvaluated func(root *Ruleset, context *Eval, contents map[string]string)
 }
 
 // ToCSS converts the parse tree to CSS
@@ -93,20 +118,73 @@ func (pt *ParseTree) ToCSS(options *ToCSSOptions) (*ToCSSResult, error) {
 			"urlArgs":           options.UrlArgs,
 			"javascriptEnabled": options.JavascriptEnabled,
 		}
+		if options.Context != nil {
+			optionsMap["ctx"] = options.Context
+		}
+		if options.FS != nil {
+			optionsMap["fs"] = options.FS
+		}
+		if options.FileManagers != nil {
+			optionsMap["fileManagers"] = options.FileManagers
+		}
+		if options.Dependencies != nil {
+			optionsMap["dependencies"] = options.Dependencies
+		}
+		if options.Diagnostics != nil {
+			optionsMap["diagnostics"] = options.Diagnostics
+		}
+		if options.Errors != nil {
+			optionsMap["errors"] = options.Errors
+		}
+		if options.OnEvaluated != nil {
+			optionsMap["onEvaluated"] = options.OnEvaluated
+		}
+		if options.ResolveModernColors != ModernColors.Off {
+			optionsMap["resolveModernColors"] = options.ResolveModernColors
+		}
+		if options.MinContrastRatio > 0 {
+			optionsMap["minContrastRatio"] = options.MinContrastRatio
+		}
 		if dumpLineNumbers, ok := normalizeDumpLineNumbersOption(options.DumpLineNumbers); ok {
 			optionsMap["dumpLineNumbers"] = dumpLineNumbers
 		}
 	} else {
 		optionsMap = make(map[string]any)
 	}
+	calls := newCallStack()
+	optionsMap["callStack"] = calls
 
 	// Use defer to catch panics from transform tree and convert to LessError
 	defer func() {
 		if r := recover(); r != nil {
-			// Get stack trace for debugging
-			buf := make([]byte, 4096)
-			n := runtime.Stack(buf, false)
-			stackTrace := string(buf[:n])
+			// Errors without a location, internal or map-shaped, are located at
+			// the call or rule they happened in
+			var index any
+			filename, i, ok := calls.current()
+			if ok {
+				index = i
+			}
+			if lessErr, ok := panicLessError(r, filename, index); ok {
+				r = lessErr
+			}
+
+			// Errors raised while evaluating already carry their location
+			switch r.(type) {
+			case *LessError, *MixinCallError:
+				calls.trace(r)
+				panic(positionedError(r, pt.Imports.Contents(), pt.Imports.RootFilename()))
+			}
+
+			if isInternalPanic(r) {
+				var handler func(any, []byte)
+				if options != nil {
+					handler = options.PanicHandler
+				}
+				err := internalError(r, debug.Stack(), filename, index, pt.Imports.Contents(), pt.Imports.RootFilename(), handler)
+				calls.trace(err)
+				positionFrames(err.Frames, pt.Imports.Contents())
+				panic(err)
+			}
 
 			var errMsg string
 			if err, ok := r.(error); ok {
@@ -115,11 +193,6 @@ func (pt *ParseTree) ToCSS(options *ToCSSOptions) (*ToCSSResult, error) {
 				errMsg = fmt.Sprintf("transform tree failed: %v", r)
 			}
 
-			// Log stack trace for index out of range errors
-			if strings.Contains(errMsg, "index out of range") {
-				fmt.Fprintf(os.Stderr, "\n=== DEBUG: ParseTree.ToCSS panic ===\nError: %s\nStack trace:\n%s\n===\n", errMsg, stackTrace)
-			}
-
 			panic(NewLessError(ErrorDetails{
 				Message: errMsg,
 			}, pt.Imports.Contents(), pt.Imports.RootFilename()))
@@ -132,8 +205,10 @@ func (pt *ParseTree) ToCSS(options *ToCSSOptions) (*ToCSSResult, error) {
 	compress := false
 	if options != nil && options.Compress {
 		compress = true
-		DefaultLogger.Warn("The compress option has been deprecated. " +
-			"We recommend you use a dedicated css minifier, for instance see less-plugin-clean-css.")
+		message := "The compress option has been deprecated. " +
+			"We recommend you use a dedicated css minifier, for instance see less-plugin-clean-css."
+		DefaultLogger.Warn(message)
+		options.Diagnostics.warn(Diagnostic{Code: DiagnosticCompressDeprecated, Message: message})
 	}
 
 	strictUnits := false
@@ -145,6 +220,7 @@ func (pt *ParseTree) ToCSS(options *ToCSSOptions) (*ToCSSResult, error) {
 		"compress":     compress,
 		"strictUnits":  strictUnits,
 		"numPrecision": 8, // Match less.js default precision
+		"callStack":    calls,
 	}
 	if options != nil {
 		if dumpLineNumbers, ok := normalizeDumpLineNumbersOption(options.DumpLineNumbers); ok {
@@ -152,8 +228,21 @@ func (pt *ParseTree) ToCSS(options *ToCSSOptions) (*ToCSSResult, error) {
 		}
 	}
 
+	// With an Output, the CSS is streamed to it as it is generated, unless
+	// post-processors need all of it first. No CSS is output if errors were
+	// collected.
+	failed := options != nil && options.Errors.count() > 0
+	streaming := options != nil && options.Output != nil && !failed && !hasPostProcessors(options)
+	var sink cssSink
+	var streamed *cssWriter
+	if streaming {
+		streamed = newCSSWriter(options.Output)
+		sink = streamed
+	} else {
+		sink = &strings.Builder{}
+	}
+
 	// Handle source map generation
-	var css string
 	sourceMapEnabled := options != nil && options.SourceMap != nil && pt.sourceMapBuilder != nil
 	if os.Getenv("LESS_GO_DEBUG") == "1" {
 		fmt.Fprintf(os.Stderr, "[ParseTree.ToCSS] sourceMapEnabled=%v, options.SourceMap=%v, pt.sourceMapBuilder=%T\n",
@@ -222,25 +311,31 @@ func (pt *ParseTree) ToCSS(options *ToCSSOptions) (*ToCSSResult, error) {
 					evaldRoot, func() bool { _, ok := evaldRoot.(SourceMapNode); return ok }(), pt.Imports.RootFilename())
 			}
 
+			// The source map is streamed along with the CSS
+			var mapWriter io.Writer
+			if streaming {
+				mapWriter = options.MapOutput
+			}
+
 			// Handle the root node - could be array or single node
 			if rulesetArray, ok := evaldRoot.([]any); ok {
-				// Multiple rulesets - generate CSS for each separately with source map
-				var cssBuilder strings.Builder
+				// Multiple rulesets - generate CSS for each separately with source map,
+				// which is kept whole as each ruleset makes its own
 				for i, ruleset := range rulesetArray {
 					if smNode, ok := ruleset.(SourceMapNode); ok {
-						generatedCSS := builder.ToCSS(smNode, toCSSOptions, imports, env)
-						cssBuilder.WriteString(generatedCSS)
+						builder.WriteCSS(sink, nil, smNode, toCSSOptions, imports, env)
 						if i < len(rulesetArray)-1 && !compress {
-							cssBuilder.WriteString("\n")
+							sink.WriteString("\n")
 						}
 					}
 				}
-				css = cssBuilder.String()
 			} else if smNode, ok := evaldRoot.(SourceMapNode); ok {
 				// Single ruleset
-				css = builder.ToCSS(smNode, toCSSOptions, imports, env)
+				if err := builder.WriteCSS(sink, mapWriter, smNode, toCSSOptions, imports, env); err != nil {
+					return nil, fmt.Errorf("failed to write source map: %w", err)
+				}
 				if os.Getenv("LESS_GO_DEBUG") == "1" {
-					fmt.Fprintf(os.Stderr, "[ParseTree.ToCSS] Generated CSS with source map, len=%d\n", len(css))
+					fmt.Fprintf(os.Stderr, "[ParseTree.ToCSS] Generated CSS with source map, len=%d\n", sink.Len())
 				}
 			}
 		}
@@ -252,7 +347,6 @@ func (pt *ParseTree) ToCSS(options *ToCSSOptions) (*ToCSSResult, error) {
 				fmt.Fprintf(os.Stderr, "[PARSE_TREE.ToCSS] Processing array of %d rulesets\n", len(rulesetArray))
 			}
 			// Multiple rulesets returned by ToCSSVisitor - generate CSS for each separately
-			var cssBuilder strings.Builder
 			for i, ruleset := range rulesetArray {
 				if cssGenerator, ok := ruleset.(interface {
 					ToCSS(map[string]any) (string, error)
@@ -264,39 +358,40 @@ func (pt *ParseTree) ToCSS(options *ToCSSOptions) (*ToCSSResult, error) {
 					}
 					// Mark this as a top-level ruleset to prevent extra space before first selector
 					rulesetOptions["topLevel"] = true
-					generatedCSS, err := cssGenerator.ToCSS(rulesetOptions)
-					if err != nil {
+					if err := writeRootCSS(cssGenerator, rulesetOptions, sink); err != nil {
 						return nil, NewLessError(ErrorDetails{
 							Message: err.Error(),
 						}, pt.Imports.Contents(), pt.Imports.RootFilename())
 					}
-					cssBuilder.WriteString(generatedCSS)
 					// Add separator between rulesets (except for the last one)
 					if i < len(rulesetArray)-1 && !compress {
-						cssBuilder.WriteString("\n")
+						sink.WriteString("\n")
 					}
 				}
 			}
-			css = cssBuilder.String()
 		} else if cssGenerator, ok := evaldRoot.(interface {
 			ToCSS(map[string]any) (string, error)
 		}); ok {
 			// Single ruleset
-			generatedCSS, err := cssGenerator.ToCSS(toCSSOptions)
-			if err != nil {
+			if err := writeRootCSS(cssGenerator, toCSSOptions, sink); err != nil {
 				return nil, NewLessError(ErrorDetails{
 					Message: err.Error(),
 				}, pt.Imports.Contents(), pt.Imports.RootFilename())
 			}
-			css = generatedCSS
 		}
 	}
 
-	result.CSS = css
+	if streaming {
+		if err := streamed.Flush(); err != nil {
+			return nil, fmt.Errorf("failed to write CSS: %w", err)
+		}
+	} else {
+		result.CSS = sink.(*strings.Builder).String()
+	}
 
 	// Apply post-processors if available
 	// First, check for JavaScript post-processors via the plugin bridge
-	if options != nil && options.PluginBridge != nil {
+	if !streaming && options != nil && options.PluginBridge != nil {
 		if bridge, ok := options.PluginBridge.(interface {
 			RunPostProcessors(string, map[string]any) (string, error)
 		}); ok {
@@ -314,7 +409,7 @@ func (pt *ParseTree) ToCSS(options *ToCSSOptions) (*ToCSSResult, error) {
 	}
 
 	// Then, check for Go post-processors via the plugin manager
-	if options != nil && options.PluginManager != nil {
+	if !streaming && options != nil && options.PluginManager != nil {
 		if pluginMgr, ok := options.PluginManager.(interface {
 			GetPostProcessors() []any
 		}); ok {
@@ -347,6 +442,20 @@ func (pt *ParseTree) ToCSS(options *ToCSSOptions) (*ToCSSResult, error) {
 		}
 	}
 
+	// Write the CSS that was post-processed, and the source map, to their outputs
+	if options != nil && options.Output != nil && !streaming && !failed {
+		if _, err := io.WriteString(options.Output, result.CSS); err != nil {
+			return nil, fmt.Errorf("failed to write CSS: %w", err)
+		}
+		result.CSS = ""
+	}
+	if options != nil && options.MapOutput != nil && result.Map != "" && !failed {
+		if _, err := io.WriteString(options.MapOutput, result.Map); err != nil {
+			return nil, fmt.Errorf("failed to write source map: %w", err)
+		}
+		result.Map = ""
+	}
+
 	// Collect imports (excluding root filename)
 	result.Imports = []string{}
 	if pt.Imports != nil && pt.Imports.Files() != nil {
@@ -360,6 +469,38 @@ func (pt *ParseTree) ToCSS(options *ToCSSOptions) (*ToCSSResult, error) {
 	return result, nil
 }
 
+// writeRootCSS generates the CSS of a root ruleset into sink
+func writeRootCSS(root interface {
+	ToCSS(map[string]any) (string, error)
+}, options map[string]any, sink cssSink) error {
+	if ruleset, ok := root.(*Ruleset); ok {
+		ruleset.writeCSS(options, sink)
+		return nil
+	}
+	css, err := root.ToCSS(options)
+	if err != nil {
+		return err
+	}
+	_, err = sink.WriteString(css)
+	return err
+}
+
+// hasPostProcessors reports whether plugins added post-processors, which need
+// the whole CSS
+func hasPostProcessors(options *ToCSSOptions) bool {
+	if bridge, ok := options.PluginBridge.(interface {
+		GetPostProcessors() []*runtime.JSPostProcessor
+	}); ok && len(bridge.GetPostProcessors()) > 0 {
+		return true
+	}
+	if pluginMgr, ok := options.PluginManager.(interface {
+		GetPostProcessors() []any
+	}); ok && len(pluginMgr.GetPostProcessors()) > 0 {
+		return true
+	}
+	return false
+}
+
 // Release releases all AST nodes in the parse tree back to their pools.
 // Call this method when you're completely done with the parse tree and won't
 // use it again. This helps reduce memory allocations for subsequent compilations.
@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"os"
 	"regexp"
+	"runtime/debug"
 	"strings"
 )
 
@@ -195,8 +196,40 @@ func (p *Parser) error(msg string, errorType string) {
 	panic(NewLessError(errorDetails, contents, filename))
 }
 
-// warn logs a warning message
-func (p *Parser) warn(msg string, index any, warnType string) {
+// internalError converts an internal panic of the parser to an Internal error
+// at the position it was parsing
+func (p *Parser) internalError(r any, stack []byte) *LessError {
+	filename, _ := p.fileInfo["filename"].(string)
+	contents, _ := p.imports["contents"].(map[string]string)
+	handler, _ := p.context["panicHandler"].(func(any, []byte))
+	var index any
+	if p.parserInput != nil {
+		index = p.parserInput.GetIndex()
+	}
+	return internalError(r, stack, filename, index, contents, filename, handler)
+}
+
+// errors returns the collector of the compilation's errors if they are
+// collected, in which case the parser skips invalid statements
+func (p *Parser) errors() *errorCollector {
+	errs, _ := p.context["errors"].(*errorCollector)
+	return errs
+}
+
+// recordError records a syntax error at index without stopping parsing
+func (p *Parser) recordError(errs *errorCollector, msg string, index int) {
+	filename, _ := p.fileInfo["filename"].(string)
+	contents, _ := p.imports["contents"].(map[string]string)
+	errs.add(NewLessError(ErrorDetails{
+		Index:    index,
+		Filename: filename,
+		Type:     "Parse",
+		Message:  msg,
+	}, contents, filename))
+}
+
+// warn logs a warning message and reports it to the compilation's diagnostics under code
+func (p *Parser) warn(msg string, index any, warnType string, code string) {
 	if quiet, ok := p.context["quiet"].(bool); ok && quiet {
 		return
 	}
@@ -222,6 +255,11 @@ func (p *Parser) warn(msg string, index any, warnType string) {
 	contents, _ := p.imports["contents"].(map[string]string)
 	lessError := NewLessError(errorDetails, contents, filename)
 	parserLogger.Warn(lessError.ToString(nil))
+
+	if diagnostics, ok := p.context["diagnostics"].(*diagnosticCollector); ok {
+		position, _ := index.(int)
+		diagnostics.warnAt(code, msg, filename, position, contents)
+	}
 }
 
 // expect expects a token and throws an error if not found
@@ -420,6 +458,10 @@ func (p *Parser) parseInternal(str string, callback func(*LessError, *Ruleset),
 				callback(lessErr, nil)
 				return
 			}
+			if isInternalPanic(r) {
+				callback(p.internalError(r, debug.Stack()), nil)
+				return
+			}
 			panic(r) // Re-panic if it's not a LessError
 		}
 	}()
@@ -516,7 +558,17 @@ func (p *Parser) parseInternal(str string, callback func(*LessError, *Ruleset),
 	// This would be handled differently in Go, probably through interfaces
 
 	// Create root ruleset
-	root = NewRuleset(nil, p.parsers.Primary(), false, nil, p.CreateSelectorsParseFunc(), p.CreateValueParseFunc(), p.context, p.imports)
+	rules := p.parsers.Primary()
+	if errs := p.errors(); errs != nil {
+		// Skip the '}'s that close no block, which end the root's statements
+		for !p.parserInput.Finished() {
+			p.recordError(errs, unrecognisedInputMessage("", p.parserInput.CurrentChar(), false), p.parserInput.GetIndex())
+			p.parserInput.SetIndex(p.parserInput.GetIndex() + 1)
+			p.parserInput.skipWhitespace(0)
+			rules = append(rules, p.parsers.Primary()...)
+		}
+	}
+	root = NewRuleset(nil, rules, false, nil, p.CreateSelectorsParseFunc(), p.CreateValueParseFunc(), p.context, p.imports)
 	root.Root = true
 	root.FirstRoot = true
 
@@ -532,21 +584,9 @@ func (p *Parser) parseInternal(str string, callback func(*LessError, *Ruleset),
 	// Check if parsing completed
 	endInfo := p.parserInput.End()
 	if !endInfo.IsFinished {
-		message := endInfo.FurthestPossibleErrorMessage
-		if message == "" {
-			message = "Unrecognised input"
-			if endInfo.FurthestChar == '}' {
-				message += ". Possibly missing opening '{'"
-			} else if endInfo.FurthestChar == ')' {
-				message += ". Possibly missing opening '('"
-			} else if endInfo.FurthestReachedEnd {
-				message += ". Possibly missing something"
-			}
-		}
-
 		err = NewLessError(ErrorDetails{
 			Type:     "Parse",
-			Message:  message,
+			Message:  unrecognisedInputMessage(endInfo.FurthestPossibleErrorMessage, endInfo.FurthestChar, endInfo.FurthestReachedEnd),
 			Index:    endInfo.Furthest,
 			Filename: filename,
 		}, contents, filename)
@@ -597,6 +637,23 @@ func (p *Parser) parseInternal(str string, callback func(*LessError, *Ruleset),
 	}
 }
 
+// unrecognisedInputMessage returns message, the error of the parser that got
+// furthest, or describes the input no parser recognised
+func unrecognisedInputMessage(message string, furthestChar byte, furthestReachedEnd bool) string {
+	if message != "" {
+		return message
+	}
+	message = "Unrecognised input"
+	if furthestChar == '}' {
+		message += ". Possibly missing opening '{'"
+	} else if furthestChar == ')' {
+		message += ". Possibly missing opening '('"
+	} else if furthestReachedEnd {
+		message += ". Possibly missing something"
+	}
+	return message
+}
+
 // SerializeVars serializes variables from a map to Less format
 // Go 1.21+ preserves insertion order for string keys like JavaScript objects
 func SerializeVars(vars map[string]any) string {
@@ -653,6 +710,11 @@ func (p *Parsers) Primary() []any {
 			break
 		}
 
+		// Abort parsing when the compilation has been cancelled
+		if err := optionsContextErr(p.parser.context); err != nil {
+			p.parser.error(err.Error(), "Cancel")
+		}
+
 		// Additional safety check: if we're at the end of input, break
 		if p.parser.parserInput.GetIndex() >= len(p.parser.parserInput.GetInput()) {
 			break
@@ -686,68 +748,16 @@ func (p *Parsers) Primary() []any {
 			break
 		}
 
-		// Try extend rule
-		node = p.ExtendRule()
-		if node != nil {
-			// Check if it's an empty slice - treat as nil
-			if nodes, ok := node.([]any); ok {
-				if len(nodes) > 0 {
-					root = append(root, nodes...)
-					continue
-				}
-				// Empty slice should be treated as no node found
-			} else {
-				root = append(root, node)
-				continue
-			}
-		}
-
-		// Try other rules
-		tracer := GetParserTracer()
-		node = p.mixin.Definition()
-		if node != nil {
-			tracer.TraceCheckpoint("Parsed MixinDefinition", p.parser)
-		}
-		if node == nil {
-			node = p.Declaration()
-			if node != nil {
-				tracer.TraceCheckpoint("Parsed Declaration", p.parser)
-			}
-		}
-		if node == nil {
-			node = p.mixin.Call(false, false)
-			if node != nil {
-				tracer.TraceCheckpoint("Parsed MixinCall", p.parser)
-			}
-		}
-		if node == nil {
-			node = p.Ruleset()
-			if node != nil {
-				tracer.TraceCheckpoint("Parsed Ruleset", p.parser)
-			}
-		}
-		if node == nil {
-			node = p.VariableCall()
-			if node != nil {
-				tracer.TraceCheckpoint("Parsed VariableCall", p.parser)
-			}
-		}
-		if node == nil {
-			// CRITICAL: entities.Call() should only be tried if all else fails
-			tracer.TraceCallStackAt("About to try entities.Call() in Primary()", p.parser)
-			node = p.entities.Call()
-			if node != nil {
-				tracer.TraceCheckpoint("Parsed entities.Call()", p.parser)
-			}
-		}
-		if node == nil {
-			node = p.AtRule()
-			if node != nil {
-				tracer.TraceCheckpoint("Parsed AtRule", p.parser)
-			}
+		errs := p.parser.errors()
+		if errs != nil {
+			node = p.recoverStatement(errs)
+		} else {
+			node = p.statement()
 		}
-
-		if node != nil {
+		if nodes, ok := node.([]any); ok {
+			root = append(root, nodes...)
+			continue
+		} else if node != nil {
 			root = append(root, node)
 			continue
 		} else {
@@ -757,6 +767,10 @@ func (p *Parsers) Primary() []any {
 				foundSemiColon = true
 			}
 			if !foundSemiColon {
+				if errs != nil && !p.parser.parserInput.Finished() && p.parser.parserInput.CurrentChar() != '}' {
+					p.skipUnrecognised(errs)
+					continue
+				}
 				// JavaScript parser simply breaks here without additional checks
 				// This allows trailing comments to be picked up in the next iteration
 				break
@@ -777,6 +791,174 @@ func (p *Parsers) Primary() []any {
 	return root
 }
 
+// statement parses the next statement, returning nil if there is none and a
+// slice for an extend rule
+func (p *Parsers) statement() any {
+	// Try extend rule
+	node := p.ExtendRule()
+	if node != nil {
+		// An empty slice is treated as no node found
+		if nodes, ok := node.([]any); !ok || len(nodes) > 0 {
+			return node
+		}
+	}
+
+	// Try other rules
+	tracer := GetParserTracer()
+	node = p.mixin.Definition()
+	if node != nil {
+		tracer.TraceCheckpoint("Parsed MixinDefinition", p.parser)
+	}
+	if node == nil {
+		node = p.Declaration()
+		if node != nil {
+			tracer.TraceCheckpoint("Parsed Declaration", p.parser)
+		}
+	}
+	if node == nil {
+		node = p.mixin.Call(false, false)
+		if node != nil {
+			tracer.TraceCheckpoint("Parsed MixinCall", p.parser)
+		}
+	}
+	if node == nil {
+		node = p.Ruleset()
+		if node != nil {
+			tracer.TraceCheckpoint("Parsed Ruleset", p.parser)
+		}
+	}
+	if node == nil {
+		node = p.VariableCall()
+		if node != nil {
+			tracer.TraceCheckpoint("Parsed VariableCall", p.parser)
+		}
+	}
+	if node == nil {
+		// CRITICAL: entities.Call() should only be tried if all else fails
+		tracer.TraceCallStackAt("About to try entities.Call() in Primary()", p.parser)
+		node = p.entities.Call()
+		if node != nil {
+			tracer.TraceCheckpoint("Parsed entities.Call()", p.parser)
+		}
+	}
+	if node == nil {
+		node = p.AtRule()
+		if node != nil {
+			tracer.TraceCheckpoint("Parsed AtRule", p.parser)
+		}
+	}
+	return node
+}
+
+// recoverStatement parses the next statement when errors are collected. A
+// syntax error is recorded and the statement skipped, returning an empty slice.
+func (p *Parsers) recoverStatement(errs *errorCollector) (node any) {
+	input := p.parser.parserInput
+	start := input.GetIndex()
+	autoCommentAbsorb := input.GetAutoCommentAbsorb()
+	input.Save()
+	depth := len(input.saveStack)
+
+	defer func() {
+		if r := recover(); r != nil {
+			if !errs.add(r) {
+				panic(r)
+			}
+			// Drop the states saved by the parsers the error unwound
+			input.saveStack = input.saveStack[:depth]
+			input.Restore("")
+			input.SetAutoCommentAbsorb(autoCommentAbsorb)
+			p.skipStatement(start)
+			node = []any{}
+		}
+	}()
+
+	node = p.statement()
+	input.Forget()
+	return node
+}
+
+// skipUnrecognised records the input that no parser recognised, as the end of
+// parsing does, and skips the statement it is in
+func (p *Parsers) skipUnrecognised(errs *errorCollector) {
+	input := p.parser.parserInput
+	start := input.GetIndex()
+	index, message := start, ""
+	if input.furthest > start {
+		index, message = input.furthest, input.furthestPossibleErrorMessage
+	}
+	var furthestChar byte
+	if index < len(input.GetInput()) {
+		furthestChar = input.GetInput()[index]
+	}
+	p.parser.recordError(errs, unrecognisedInputMessage(message, furthestChar, index >= len(input.GetInput())-1), index)
+	p.skipStatement(start)
+}
+
+// skipStatement moves the input past the statement starting at start: after
+// its ';' or the '}' that closes its block, or up to the '}' that closes the
+// block it is in. Strings and comments are skipped as a whole. Parentheses are
+// not matched, as an unclosed one is a common error.
+func (p *Parsers) skipStatement(start int) {
+	input := p.parser.parserInput
+	src := input.GetInput()
+	braces, parens := 0, 0
+	i := start
+scan:
+	for i < len(src) {
+		switch src[i] {
+		case '"', '\'':
+			quote := src[i]
+			for i++; i < len(src) && src[i] != quote && src[i] != '\n'; i++ {
+				if src[i] == '\\' {
+					i++
+				}
+			}
+		case '/':
+			if strings.HasPrefix(src[i:], "/*") {
+				end := strings.Index(src[i+2:], "*/")
+				if end < 0 {
+					i = len(src)
+					break scan
+				}
+				i += end + 3
+			} else if strings.HasPrefix(src[i:], "//") && parens == 0 {
+				end := strings.IndexByte(src[i:], '\n')
+				if end < 0 {
+					i = len(src)
+					break scan
+				}
+				i += end
+			}
+		case '(':
+			parens++
+		case ')':
+			if parens > 0 {
+				parens--
+			}
+		case '{':
+			braces++
+		case '}':
+			if braces == 0 {
+				break scan
+			}
+			braces--
+			if braces == 0 {
+				i++
+				break scan
+			}
+		case ';':
+			if braces == 0 {
+				i++
+				break scan
+			}
+		}
+		i++
+	}
+	input.SetIndex(min(i, len(src)))
+	input.skipWhitespace(0)
+}
+
 // Comment parses comments
 func (p *Parsers) Comment() any {
 	if comments := p.parser.parserInput.GetComments(); len(comments) > 0 {
@@ -2203,7 +2385,7 @@ func (p *Parsers) Extend() []any {
 			// Warn about complex selectors targeting
 			if !first {
 				if element, ok := e.(*Element); ok && element.Combinator != nil && element.Combinator.Value != "" {
-					p.parser.warn("Targeting complex selectors can have unexpected behavior, and this behavior may change in the future.", index, "")
+					p.parser.warn("Targeting complex selectors can have unexpected behavior, and this behavior may change in the future.", index, "", DiagnosticExtendComplexSelector)
 				}
 			}
 			first = false
@@ -2521,7 +2703,7 @@ func (p *Parsers) Multiplication() any {
 		} else {
 			index := p.parser.parserInput.GetIndex()
 			if p.parser.parserInput.Str("./") != nil {
-				p.parser.warn("./ operator is deprecated", index, "DEPRECATED")
+				p.parser.warn("./ operator is deprecated", index, "DEPRECATED", DiagnosticDotSlashDeprecated)
 				op = "./"
 			}
 		}
@@ -2966,7 +3148,7 @@ func (m *MixinParsers) Call(inValue bool, getLookup bool) any {
 			m.parsers.parser.expectChar(')', "")
 			hasParens = true
 			if parensWS {
-				m.parsers.parser.warn("Whitespace between a mixin name and parentheses for a mixin call is deprecated", parensIndex, "DEPRECATED")
+				m.parsers.parser.warn("Whitespace between a mixin name and parentheses for a mixin call is deprecated", parensIndex, "DEPRECATED", DiagnosticMixinCallWhitespace)
 			}
 		}
 
@@ -2998,7 +3180,7 @@ func (m *MixinParsers) Call(inValue bool, getLookup bool) any {
 				return NewNamespaceValue(mixin, lookups, index+m.parsers.parser.currentIndex, m.parsers.parser.fileInfo)
 			} else {
 				if !hasParens {
-					m.parsers.parser.warn("Calling a mixin without parentheses is deprecated", parensIndex, "DEPRECATED")
+					m.parsers.parser.warn("Calling a mixin without parentheses is deprecated", parensIndex, "DEPRECATED", DiagnosticMixinCallNoParens)
 				}
 				return mixin
 			}
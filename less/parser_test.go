@@ -677,7 +677,7 @@ func TestParser_LoggerIntegration(t *testing.T) {
 	fileInfo := map[string]any{"filename": "test.less"}
 	parser := NewParser(context, imports, fileInfo, 0)
 
-	parser.warn("test warning", nil, "TEST")
+	parser.warn("test warning", nil, "TEST", "")
 
 	if len(tl.warnings) != 1 {
 		t.Errorf("Expected 1 warning, got %d", len(tl.warnings))
@@ -52,10 +52,7 @@ func InitParserTracer() *ParserTracer {
 
 // GetParserTracer returns the global tracer instance
 func GetParserTracer() *ParserTracer {
-	if globalTracer == nil {
-		InitParserTracer()
-	}
-	return globalTracer
+	return InitParserTracer()
 }
 
 // IsEnabled returns whether tracing is enabled
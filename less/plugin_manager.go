@@ -1,6 +1,9 @@
 package less_go
 
-import "slices"
+import (
+	"slices"
+	"sync"
+)
 
 // PluginLoader interface represents the minimal interface needed for plugin loading
 type PluginLoader interface {
@@ -85,6 +88,46 @@ type Plugin interface {
 	Install(less LessInterface, pluginManager *PluginManager, functionRegistry any) error
 }
 
+// GoPlugin is a plugin written in Go, installed in-process for a compilation
+// through CompileOptions.GoPlugins. Install registers the plugin's extensions
+// with the plugin manager:
+//
+//   - AddVisitor takes a PluginVisitor
+//   - AddPreProcessor takes a PreProcessor, AddPostProcessor a PostProcessor;
+//     lower priorities run first
+//   - AddFileManager takes a FileManager, tried before those of CompileOptions
+type GoPlugin interface {
+	Install(pluginManager *PluginManager) error
+}
+
+// GoPluginFunc adapts a function to a GoPlugin
+type GoPluginFunc func(pluginManager *PluginManager) error
+
+func (f GoPluginFunc) Install(pluginManager *PluginManager) error {
+	return f(pluginManager)
+}
+
+// PluginVisitor is a visitor added by a plugin. Run is called with the evaluated
+// tree and returns the tree to use from then on. A visitor that implements
+// IsPreVisitor returning true runs before the others; one that implements
+// IsPreEvalVisitor returning true is instead run on the parsed tree, before
+// evaluation, and modifies it in place.
+type PluginVisitor interface {
+	Run(root any) any
+}
+
+// PreProcessor transforms the source of each file before it is parsed. extra
+// holds the parser's "context", "imports" and "fileInfo".
+type PreProcessor interface {
+	Process(src string, extra map[string]any) string
+}
+
+// PostProcessor transforms the generated CSS. extra holds the "sourceMap"
+// builder, the "options" and the "imports".
+type PostProcessor interface {
+	Process(css string, extra map[string]any) (string, error)
+}
+
 // AddPlugin adds a single plugin
 func (pm *PluginManager) AddPlugin(plugin any, filename string, functionRegistry any) {
 	pm.installedPlugins = append(pm.installedPlugins, plugin)
@@ -201,11 +244,17 @@ func (pm *PluginManager) GetFileManagers() []any {
 	return pm.fileManagers
 }
 
-// Global plugin manager instance
-var pm *PluginManager
+// Global plugin manager instance, guarded by pmMu. Compilations do not use it:
+// each has its own PluginManager.
+var (
+	pm   *PluginManager
+	pmMu sync.Mutex
+)
 
 // PluginManagerFactory creates or returns the global PluginManager instance
 func PluginManagerFactory(less LessInterface, newFactory bool) *PluginManager {
+	pmMu.Lock()
+	defer pmMu.Unlock()
 	if newFactory || pm == nil {
 		pm = NewPluginManager(less)
 	}
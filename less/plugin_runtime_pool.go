@@ -0,0 +1,82 @@
+package less_go
+
+import (
+	"sync"
+
+	"github.com/toakleaf/less.go/less/runtime"
+)
+
+// pluginRuntimePool keeps Node.js processes alive between the compilations of
+// a Compiler, so that only the first compilation using JavaScript plugins pays
+// for starting Node.js. A runtime serves one compilation at a time and is
+// reset before it is handed out again.
+type pluginRuntimePool struct {
+	mu     sync.Mutex
+	idle   []*runtime.NodeJSRuntime
+	closed bool
+}
+
+func newPluginRuntimePool() *pluginRuntimePool {
+	return &pluginRuntimePool{}
+}
+
+// get returns an idle runtime, or starts a new one if there is none
+func (p *pluginRuntimePool) get() (*runtime.NodeJSRuntime, error) {
+	p.mu.Lock()
+	for len(p.idle) > 0 {
+		rt := p.idle[len(p.idle)-1]
+		p.idle = p.idle[:len(p.idle)-1]
+		if rt.IsAlive() {
+			p.mu.Unlock()
+			return rt, nil
+		}
+	}
+	p.mu.Unlock()
+
+	rt, err := runtime.NewNodeJSRuntime()
+	if err != nil {
+		return nil, err
+	}
+	if err := rt.Start(); err != nil {
+		return nil, err
+	}
+	return rt, nil
+}
+
+// put resets rt and keeps it for the next compilation. Runtimes that fail to
+// reset or are returned after close are stopped.
+func (p *pluginRuntimePool) put(rt *runtime.NodeJSRuntime) error {
+	rt.CloseSHMProtocol()
+	if err := rt.Reset(); err != nil {
+		rt.Stop()
+		return err
+	}
+
+	p.mu.Lock()
+	if !p.closed {
+		p.idle = append(p.idle, rt)
+		rt = nil
+	}
+	p.mu.Unlock()
+	if rt != nil {
+		return rt.Stop()
+	}
+	return nil
+}
+
+// close stops the idle runtimes. Runtimes still in use are stopped when they are returned.
+func (p *pluginRuntimePool) close() error {
+	p.mu.Lock()
+	idle := p.idle
+	p.idle = nil
+	p.closed = true
+	p.mu.Unlock()
+
+	var firstErr error
+	for _, rt := range idle {
+		if err := rt.Stop(); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	return firstErr
+}
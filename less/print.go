@@ -0,0 +1,632 @@
+package less_go
+
+import (
+	"fmt"
+	"io"
+	"strings"
+)
+
+// printIndent is the indentation of one nesting level in Print's output
+const printIndent = "  "
+
+// Print serializes a parsed tree, or any node of it, back to Less source. The
+// output is normalized rather than a copy of the original text: statements go
+// on their own lines, blocks are indented with two spaces and comments are
+// kept. Parsing the output again gives an equivalent tree, so Print can be
+// used to write back a tree modified by a codemod.
+//
+// Passing the Root of a Stylesheet prints its statements without braces.
+func Print(node any) string {
+	p := &printer{}
+	if root, ok := node.(*Ruleset); ok && root.Root {
+		p.statements(root.Rules)
+	} else if isStatement(node) {
+		p.statement(node)
+	} else {
+		p.value(node)
+	}
+	return p.String()
+}
+
+// Fprint writes Print(node) to w
+func Fprint(w io.Writer, node any) error {
+	_, err := io.WriteString(w, Print(node))
+	return err
+}
+
+type printer struct {
+	strings.Builder
+	depth int
+}
+
+// isStatement reports whether node is printed on its own line inside a block
+func isStatement(node any) bool {
+	switch node.(type) {
+	case *Ruleset, *Declaration, *Comment, *MixinDefinition, *MixinCall, *AtRule, *Media, *Container,
+		*Import, *Extend, *VariableCall:
+		return true
+	}
+	return false
+}
+
+func (p *printer) newline() {
+	p.WriteString("\n")
+	p.WriteString(strings.Repeat(printIndent, p.depth))
+}
+
+func (p *printer) statements(rules []any) {
+	for i, rule := range rules {
+		if i > 0 {
+			p.newline()
+		}
+		p.statement(rule)
+	}
+	if len(rules) > 0 && p.depth == 0 {
+		p.WriteString("\n")
+	}
+}
+
+// block prints rules between braces
+func (p *printer) block(rules []any) {
+	if len(rules) == 0 {
+		p.WriteString("{}")
+		return
+	}
+	p.WriteString("{")
+	p.depth++
+	for _, rule := range rules {
+		p.newline()
+		p.statement(rule)
+	}
+	p.depth--
+	p.newline()
+	p.WriteString("}")
+}
+
+func (p *printer) statement(node any) {
+	switch n := node.(type) {
+	case *Comment:
+		p.WriteString(n.Value)
+	case *Declaration:
+		p.declaration(n)
+	case *MixinDefinition:
+		p.mixinDefinition(n)
+	case *Ruleset:
+		for i, selector := range n.Selectors {
+			if i > 0 {
+				p.WriteString(", ")
+			}
+			p.value(selector)
+		}
+		p.WriteString(" ")
+		p.block(n.Rules)
+	case *MixinCall:
+		p.mixinCall(n)
+		p.WriteString(";")
+	case *Media:
+		p.WriteString("@media")
+		p.features(n.Features)
+		p.WriteString(" ")
+		p.block(blockRules(n.Rules))
+	case *Container:
+		p.WriteString("@container")
+		p.features(n.Features)
+		p.WriteString(" ")
+		p.block(blockRules(n.Rules))
+	case *AtRule:
+		p.WriteString(n.Name)
+		if n.Value != nil {
+			p.WriteString(" ")
+			p.value(n.Value)
+		}
+		switch {
+		case n.SimpleBlock:
+			p.WriteString(" ")
+			p.block(n.Declarations)
+		case n.Rules != nil:
+			p.WriteString(" ")
+			p.block(blockRules(n.Rules))
+		default:
+			p.WriteString(";")
+		}
+	case *Import:
+		p.importStatement(n)
+	case *Extend:
+		p.WriteString("&")
+		p.extend([]any{n})
+		p.WriteString(";")
+	default:
+		p.value(node)
+		p.WriteString(";")
+	}
+}
+
+// blockRules returns the statements of an at-rule's block, which the parser
+// wraps in a ruleset without selectors
+func blockRules(rules []any) []any {
+	if len(rules) != 1 {
+		return rules
+	}
+	ruleset, ok := rules[0].(*Ruleset)
+	if !ok {
+		return rules
+	}
+	for _, s := range ruleset.Selectors {
+		if selector, ok := s.(*Selector); !ok || !selector.MediaEmpty {
+			return rules
+		}
+	}
+	return ruleset.Rules
+}
+
+func (p *printer) features(features any) {
+	if features == nil {
+		return
+	}
+	p.WriteString(" ")
+	p.value(features)
+}
+
+func (p *printer) declaration(d *Declaration) {
+	p.declarationName(d)
+	if merge, ok := d.merge.(string); ok {
+		p.WriteString(merge)
+	}
+	p.WriteString(":")
+
+	if d.Value != nil && len(d.Value.Value) == 1 {
+		switch value := d.Value.Value[0].(type) {
+		case *DetachedRuleset:
+			// The semicolon is optional, but a comment after the closing brace
+			// would be dropped without it
+			p.WriteString(" ")
+			p.detachedRuleset(value)
+			p.WriteString(";")
+			return
+		case *MixinDefinition:
+			p.WriteString(" ")
+			p.mixinParams(value)
+			p.WriteString(" ")
+			p.block(value.Rules)
+			p.WriteString(";")
+			return
+		}
+	}
+	if d.Value != nil && !isEmptyValue(d.Value) {
+		p.WriteString(" ")
+		p.value(d.Value)
+	}
+	p.WriteString(d.important)
+	p.WriteString(";")
+}
+
+// declarationName prints the name of a declaration, with its interpolations
+func (p *printer) declarationName(d *Declaration) {
+	switch name := d.name.(type) {
+	case string:
+		p.WriteString(name)
+	case []any:
+		for _, part := range name {
+			switch part := part.(type) {
+			case *Keyword:
+				p.WriteString(part.value)
+			case *Variable:
+				p.WriteString("@{" + strings.TrimPrefix(part.name, "@") + "}")
+			case *Property:
+				p.WriteString("${" + strings.TrimPrefix(part.name, "$") + "}")
+			default:
+				p.value(part)
+			}
+		}
+	}
+}
+
+// isEmptyValue reports whether v is the empty value of a declaration like "--x: ;"
+func isEmptyValue(v *Value) bool {
+	if len(v.Value) != 1 {
+		return false
+	}
+	anonymous, ok := v.Value[0].(*Anonymous)
+	return ok && anonymous.Value == ""
+}
+
+func (p *printer) detachedRuleset(dr *DetachedRuleset) {
+	var rules []any
+	if ruleset, ok := dr.ruleset.(*Ruleset); ok {
+		rules = ruleset.Rules
+	}
+	p.block(rules)
+}
+
+func (p *printer) mixinDefinition(md *MixinDefinition) {
+	if md.Name == "" || md.Name == "anonymous mixin" {
+		// A mixin passed to a function, as in each(@list, .(@value) { ... })
+		p.WriteString(".")
+	} else {
+		p.WriteString(md.Name)
+	}
+	p.mixinParams(md)
+	if md.Condition != nil {
+		p.WriteString(" when ")
+		p.condition(md.Condition, false)
+	}
+	p.WriteString(" ")
+	p.block(md.Rules)
+}
+
+func (p *printer) mixinParams(md *MixinDefinition) {
+	p.WriteString("(")
+	separator := argumentSeparator(md.Params)
+	for i, param := range md.Params {
+		if i > 0 {
+			p.WriteString(separator)
+		}
+		arg, _ := param.(map[string]any)
+		name, _ := arg["name"].(string)
+		value := arg["value"]
+		variadic, _ := arg["variadic"].(bool)
+		p.WriteString(name)
+		if name != "" && value != nil {
+			p.WriteString(": ")
+		}
+		if value != nil {
+			p.value(value)
+		}
+		if variadic {
+			p.WriteString("...")
+		}
+	}
+	if len(md.Params) == 1 && separator == "; " {
+		p.WriteString(";")
+	}
+	p.WriteString(")")
+}
+
+// argumentSeparator returns "; " if an argument of a mixin call or definition
+// is a comma-separated list, which requires semicolons between arguments
+func argumentSeparator(args []any) string {
+	for _, a := range args {
+		arg, _ := a.(map[string]any)
+		if value, ok := arg["value"].(*Value); ok && len(value.Value) > 1 {
+			return "; "
+		}
+	}
+	return ", "
+}
+
+func (p *printer) mixinCall(mc *MixinCall) {
+	p.selector(mc.Selector)
+	p.WriteString("(")
+	separator := argumentSeparator(mc.Arguments)
+	for i, a := range mc.Arguments {
+		if i > 0 {
+			p.WriteString(separator)
+		}
+		arg, _ := a.(map[string]any)
+		if name, _ := arg["name"].(string); name != "" {
+			p.WriteString(name + ": ")
+		}
+		p.value(arg["value"])
+		if expand, _ := arg["expand"].(bool); expand {
+			p.WriteString("...")
+		}
+	}
+	if len(mc.Arguments) == 1 && separator == "; " {
+		p.WriteString(";")
+	}
+	p.WriteString(")")
+	if mc.Important {
+		p.WriteString(" !important")
+	}
+}
+
+func (p *printer) importStatement(i *Import) {
+	if isPlugin, _ := i.options["isPlugin"].(bool); isPlugin {
+		p.WriteString("@plugin ")
+		if args, _ := i.options["pluginArgs"].(string); args != "" {
+			p.WriteString("(" + args + ") ")
+		}
+		p.value(i.path)
+		p.WriteString(";")
+		return
+	}
+
+	p.WriteString("@import ")
+	var options []string
+	for _, name := range []string{"reference", "inline", "less", "multiple", "optional"} {
+		value, ok := i.options[name].(bool)
+		switch {
+		case !ok:
+		case name == "less" && !value:
+			options = append(options, "css")
+		case name == "multiple" && !value:
+			options = append(options, "once")
+		case value:
+			options = append(options, name)
+		}
+	}
+	if len(options) > 0 {
+		p.WriteString("(" + strings.Join(options, ", ") + ") ")
+	}
+	p.value(i.path)
+	p.features(i.features)
+	p.WriteString(";")
+}
+
+func (p *printer) selector(s *Selector) {
+	if s == nil {
+		return
+	}
+	for i, el := range s.Elements {
+		combinator := ""
+		if el.Combinator != nil {
+			combinator = el.Combinator.Value
+		}
+		switch {
+		case combinator == "":
+		case combinator == " ":
+			if i > 0 {
+				p.WriteString(" ")
+			}
+		case i == 0:
+			p.WriteString(combinator + " ")
+		default:
+			p.WriteString(" " + combinator + " ")
+		}
+		p.interpolated(el.Value)
+	}
+	p.extend(s.ExtendList)
+	if s.Condition != nil {
+		p.WriteString(" when ")
+		p.condition(s.Condition, false)
+	}
+}
+
+// interpolated prints a node of a selector, where variables are interpolated
+// with @{name}
+func (p *printer) interpolated(node any) {
+	if v, ok := node.(*Variable); ok {
+		p.WriteString("@{" + strings.TrimPrefix(v.name, "@") + "}")
+	} else {
+		p.value(node)
+	}
+}
+
+// extend prints a list of extends as one :extend()
+func (p *printer) extend(extends []any) {
+	if len(extends) == 0 {
+		return
+	}
+	p.WriteString(":extend(")
+	for i, e := range extends {
+		if i > 0 {
+			p.WriteString(", ")
+		}
+		extend, ok := e.(*Extend)
+		if !ok {
+			continue
+		}
+		p.value(extend.Selector)
+		if extend.Option != "" {
+			p.WriteString(" " + extend.Option)
+		}
+	}
+	p.WriteString(")")
+}
+
+// condition prints a guard. Comparisons are parenthesized; nested is set for
+// an and/or inside another, which needs parentheses too.
+func (p *printer) condition(node any, nested bool) {
+	c, ok := node.(*Condition)
+	if !ok {
+		p.WriteString("(")
+		p.value(node)
+		p.WriteString(")")
+		return
+	}
+	if c.Negate {
+		p.WriteString("not ")
+	}
+	if c.Op == "and" || c.Op == "or" {
+		if nested || c.Negate {
+			p.WriteString("(")
+		}
+		p.condition(c.Lvalue, true)
+		p.WriteString(" " + c.Op + " ")
+		p.condition(c.Rvalue, true)
+		if nested || c.Negate {
+			p.WriteString(")")
+		}
+		return
+	}
+	p.WriteString("(")
+	p.value(c.Lvalue)
+	// The parser reads a guard such as (iscolor(@a)) as (iscolor(@a) = true)
+	if keyword, ok := c.Rvalue.(*Keyword); !ok || c.Op != "=" || keyword.value != "true" {
+		p.WriteString(" " + c.Op + " ")
+		p.value(c.Rvalue)
+	}
+	p.WriteString(")")
+}
+
+// value prints a node that is part of a statement, such as a selector or the
+// value of a declaration
+func (p *printer) value(node any) {
+	switch n := node.(type) {
+	case nil:
+	case string:
+		p.WriteString(n)
+	case *Value:
+		for i, v := range n.Value {
+			if i > 0 {
+				p.WriteString(", ")
+			}
+			p.value(v)
+		}
+	case *Expression:
+		if n.Parens {
+			p.WriteString("(")
+		}
+		for i, v := range n.Value {
+			if i > 0 && !n.NoSpacing {
+				if a, ok := v.(*Anonymous); !ok || (a.Value != "," && a.Value != ":") {
+					if paren, ok := v.(*Paren); !ok || !paren.NoSpacing {
+						p.WriteString(" ")
+					}
+				}
+			}
+			if q, ok := v.(*Quoted); ok && n.NoSpacing && q.escaped {
+				// Raw text of a value the parser could not tokenize, as in
+				// @-moz-document regexp(...), split at quotes
+				p.WriteString(q.value)
+				continue
+			}
+			p.value(v)
+		}
+		if n.Parens {
+			p.WriteString(")")
+		}
+	case *Selector:
+		p.selector(n)
+	case *Element:
+		p.selector(&Selector{Elements: []*Element{n}})
+	case *Variable:
+		p.WriteString(n.name)
+	case *Property:
+		p.WriteString(n.name)
+	case *VariableCall:
+		p.WriteString(n.variable + "()")
+	case *MixinCall:
+		p.mixinCall(n)
+	case *NamespaceValue:
+		if call, ok := n.value.(*VariableCall); ok {
+			// @config[key] rather than @config()[key]
+			p.WriteString(call.variable)
+		} else {
+			p.value(n.value)
+		}
+		for _, lookup := range n.lookups {
+			p.WriteString("[" + lookup + "]")
+		}
+	case *Operation:
+		for i, operand := range n.Operands {
+			if i > 0 {
+				if n.IsSpaced {
+					p.WriteString(" " + n.Op + " ")
+				} else {
+					p.WriteString(n.Op)
+				}
+			}
+			p.value(operand)
+		}
+	case *Negative:
+		p.WriteString("-")
+		p.value(n.Value)
+	case *Paren:
+		p.WriteString("(")
+		p.value(n.Value)
+		p.WriteString(")")
+	case *Call:
+		p.WriteString(n.Name + "(")
+		separator := ", "
+		for _, arg := range n.Args {
+			if list, ok := arg.(*Value); ok && len(list.Value) > 1 {
+				separator = "; "
+			}
+		}
+		for i, arg := range n.Args {
+			if i > 0 {
+				p.WriteString(separator)
+			}
+			p.value(arg)
+		}
+		if len(n.Args) == 1 && separator == "; " {
+			p.WriteString(";")
+		}
+		p.WriteString(")")
+	case *URL:
+		p.WriteString("url(")
+		p.value(n.Value)
+		p.WriteString(")")
+	case *Quoted:
+		quote := n.quote
+		if quote == "" {
+			// Values the parser keeps as is, like alpha(opacity=@x), which it
+			// stores with @{x} interpolation
+			quote = `"`
+			if strings.Contains(n.value, `"`) {
+				quote = "'"
+			}
+		}
+		if n.escaped {
+			p.WriteString("~")
+		}
+		p.WriteString(quote + n.value + quote)
+	case *JavaScript:
+		if n.escaped {
+			p.WriteString("~")
+		}
+		p.WriteString("`" + n.expression + "`")
+	case *Condition:
+		p.condition(n, false)
+	case *Declaration:
+		// An inline declaration, as in @supports (display: grid)
+		p.declarationName(n)
+		p.WriteString(": ")
+		p.value(n.Value)
+	case *Keyword:
+		p.WriteString(n.value)
+	case *QueryInParens:
+		p.value(n.lvalue)
+		p.WriteString(" " + n.op + " ")
+		p.value(n.mvalue)
+		if n.rvalue != nil {
+			p.WriteString(" " + n.op2 + " ")
+			p.value(n.rvalue)
+		}
+	case *SelectorList:
+		for _, selector := range n.Selectors {
+			if a, ok := selector.(*Anonymous); ok && a.Value == "," {
+				p.WriteString(", ")
+			} else {
+				p.value(selector)
+			}
+		}
+	case *Anonymous:
+		p.value(n.Value)
+	case *Attribute:
+		p.WriteString("[")
+		p.interpolated(n.Key)
+		if n.Op != "" {
+			p.WriteString(n.Op)
+			p.interpolated(n.Value)
+		}
+		if n.Cif != "" {
+			p.WriteString(" " + n.Cif)
+		}
+		p.WriteString("]")
+	case *Assignment:
+		p.value(n.Key)
+		p.WriteString("=")
+		p.value(n.Value)
+	case *DetachedRuleset:
+		p.detachedRuleset(n)
+	case *Ruleset, *MixinDefinition, *AtRule, *Media, *Container, *Import, *Comment, *Extend:
+		p.statement(n)
+	case interface{ ToCSS(any) string }:
+		p.WriteString(n.ToCSS(map[string]any{}))
+	case interface{ GenCSS(any, *CSSOutput) }:
+		n.GenCSS(map[string]any{}, &CSSOutput{
+			Add: func(chunk any, fileInfo any, index any) {
+				if chunk != nil {
+					p.WriteString(fmt.Sprint(chunk))
+				}
+			},
+			IsEmpty: func() bool {
+				return p.Len() == 0
+			},
+		})
+	default:
+		p.WriteString(fmt.Sprint(n))
+	}
+}
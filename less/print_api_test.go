@@ -0,0 +1,36 @@
+package less_go_test
+
+import (
+	"testing"
+
+	less "github.com/toakleaf/less.go/less"
+)
+
+// TestPrint_ModifiedTree checks that the exported API is enough for a codemod:
+// parse, modify the tree and print it back
+func TestPrint_ModifiedTree(t *testing.T) {
+	sheet, err := less.ParseStylesheet(".a { color: @old-brand; }\n", nil)
+	if err != nil {
+		t.Fatalf("ParseStylesheet failed: %v", err)
+	}
+	// A codemod renaming a variable
+	less.Walk(sheet.Root, func(v *less.Variable) {
+		if v.GetName() == "@old-brand" {
+			v.SetName("@brand")
+		}
+	})
+	decl, err := less.NewDeclaration("margin", less.NewKeyword("0"), nil, nil, 0, nil, false, nil)
+	if err != nil {
+		t.Fatalf("NewDeclaration failed: %v", err)
+	}
+	ruleset := sheet.Root.Rules[0].(*less.Ruleset)
+	ruleset.Rules = append(ruleset.Rules, decl)
+
+	want := ".a {\n  color: @brand;\n  margin: 0;\n}\n"
+	if got := less.Print(sheet.Root); got != want {
+		t.Errorf("unexpected output:\n%s\nwant:\n%s", got, want)
+	}
+	if got := less.Print(decl); got != "margin: 0;" {
+		t.Errorf("expected a single declaration to print on its own, got %q", got)
+	}
+}
@@ -0,0 +1,102 @@
+package less_go
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestPrint_Normalizes(t *testing.T) {
+	input := `@import (reference) "base.less";
+@brand:#336699;
+.button,.link{color:@brand;&:hover{color:darken(@brand,10%)}
+.rounded(4px;2px) !important;}
+.rounded(@r;@s: 1px) when (@r > 0){border-radius:@r}
+@media screen and (min-width:@bp){.x{y:z}}
+`
+	want := `@import (reference) "base.less";
+@brand: #336699;
+.button, .link {
+  color: @brand;
+  &:hover {
+    color: darken(@brand, 10%);
+  }
+  .rounded(4px, 2px) !important;
+}
+.rounded(@r, @s: 1px) when (@r > 0) {
+  border-radius: @r;
+}
+@media screen and (min-width: @bp) {
+  .x {
+    y: z;
+  }
+}
+`
+	sheet, err := ParseStylesheet(input, nil)
+	if err != nil {
+		t.Fatalf("ParseStylesheet failed: %v", err)
+	}
+	if got := sheet.String(); got != want {
+		t.Errorf("unexpected output:\n%s\nwant:\n%s", got, want)
+	}
+}
+
+// TestPrint_RoundTrip checks that printing the test suite's stylesheets gives
+// Less that compiles to the same CSS, and that printing is stable
+func TestPrint_RoundTrip(t *testing.T) {
+	files, err := filepath.Glob("../testdata/less/_main/*.less")
+	if err != nil || len(files) == 0 {
+		t.Skip("test data not found")
+	}
+	for _, file := range files {
+		src, err := os.ReadFile(file)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if strings.Contains(string(src), "url(https:") || strings.Contains(string(src), `url("https:`) {
+			continue // remote imports
+		}
+		filename, _ := filepath.Abs(file)
+		want, err := Compile(string(src), &CompileOptions{Filename: filename, Math: Math.ParensDivision})
+		if err != nil {
+			continue // needs options or plugins of its own
+		}
+		t.Run(filepath.Base(file), func(t *testing.T) {
+			sheet, err := ParseStylesheet(string(src), &CompileOptions{Filename: filename})
+			if err != nil {
+				t.Fatalf("ParseStylesheet failed: %v", err)
+			}
+			printed := sheet.String()
+			got, err := Compile(printed, &CompileOptions{Filename: filename, Math: Math.ParensDivision})
+			if err != nil {
+				t.Fatalf("printed Less does not compile: %v\n%s", err, printed)
+			}
+			if got.CSS != want.CSS {
+				t.Errorf("printed Less compiles differently:\n%s", printed)
+			}
+			again, err := ParseStylesheet(printed, &CompileOptions{Filename: filename})
+			if err != nil {
+				t.Fatalf("printed Less does not parse: %v", err)
+			}
+			if reprinted := again.String(); reprinted != printed {
+				t.Errorf("printing is not stable:\n%s", firstDifference(printed, reprinted))
+			}
+		})
+	}
+}
+
+// firstDifference returns the lines of a and b where they first differ
+func firstDifference(a, b string) string {
+	linesA, linesB := strings.Split(a, "\n"), strings.Split(b, "\n")
+	for i := range linesA {
+		if i >= len(linesB) || linesA[i] != linesB[i] {
+			other := ""
+			if i < len(linesB) {
+				other = linesB[i]
+			}
+			return linesA[i] + "\n" + other
+		}
+	}
+	return ""
+}
@@ -5,6 +5,7 @@ import (
 	"os"
 	"reflect"
 	"runtime"
+	"slices"
 	"strings"
 	"sync"
 )
@@ -212,6 +213,13 @@ func (r *Ruleset) IsRulesetLike() bool {
 // ToCSS converts the ruleset to CSS output (original signature)
 func (r *Ruleset) ToCSS(options map[string]any) (string, error) {
 	var output strings.Builder
+	r.writeCSS(options, &output)
+	return output.String(), nil
+}
+
+// writeCSS generates the CSS of the ruleset into output as it goes
+func (r *Ruleset) writeCSS(options map[string]any, output cssSink) {
+	start := output.Len()
 
 	// Create context map from options
 	contextMap := make(map[string]any)
@@ -245,17 +253,12 @@ func (r *Ruleset) ToCSS(options map[string]any) (string, error) {
 			}
 		},
 		IsEmpty: func() bool {
-			return output.Len() == 0
+			return output.Len() == start
 		},
 	}
 
 	// Generate CSS using the GenCSS method
 	r.GenCSS(contextMap, cssOutput)
-
-	// Return the generated CSS output
-	result := output.String()
-
-	return result, nil
 }
 
 // ToCSSString converts the ruleset to CSS output (Node interface version)
@@ -434,6 +437,11 @@ func (r *Ruleset) Eval(context any) (any, error) {
 		return nil, fmt.Errorf("context must be *Eval or map[string]any, got %T", context)
 	}
 
+	// Stop evaluating as soon as the compilation has been cancelled
+	if err := evalContextErr(context); err != nil {
+		return nil, err
+	}
+
 	// Enter a new plugin scope for this ruleset.
 	// This ensures that any @plugin directives inside this ruleset only affect
 	// this scope and its children, not parent scopes.
@@ -775,6 +783,10 @@ func (r *Ruleset) Eval(context any) (any, error) {
 			firstSelContent, mediaBlockCount, r.Root)
 	}
 
+	// With CollectErrors, the rules that fail are recorded and left out
+	errs := errorCollectorOf(context)
+	calls := callStackOf(context)
+
 	// Evaluate mixin calls and variable calls - match JavaScript logic closely
 	if rsRules != nil {
 		i := 0
@@ -784,10 +796,17 @@ func (r *Ruleset) Eval(context any) (any, error) {
 				switch r.GetType() {
 				case "MixinCall":
 					if eval, ok := rule.(interface{ Eval(any) ([]any, error) }); ok {
-						rules, err := eval.Eval(context)
+						var rules []any
+						skip, err := errs.collect(context, func() (err error) {
+							rules, err = eval.Eval(context)
+							return err
+						})
 						if err != nil {
 							return nil, err
 						}
+						if skip {
+							rules = nil
+						}
 						filtered := filterMixinReplacementRules(ruleset, rules)
 						rsRules = replaceRuleAtIndex(rsRules, i, filtered)
 						ruleset.Rules = rsRules
@@ -796,10 +815,17 @@ func (r *Ruleset) Eval(context any) (any, error) {
 					}
 				case "VariableCall":
 					if eval, ok := rule.(interface{ Eval(any) (any, error) }); ok {
-						evaluated, err := eval.Eval(context)
+						var evaluated any
+						skip, err := errs.collect(context, func() (err error) {
+							evaluated, err = eval.Eval(context)
+							return err
+						})
 						if err != nil {
 							return nil, err
 						}
+						if skip {
+							evaluated = &Ruleset{Rules: []any{}}
+						}
 						// Handle the result - it could be a map with "rules" key or a Ruleset
 						var evalRules []any
 						if evalMap, ok := evaluated.(map[string]any); ok {
@@ -830,6 +856,7 @@ func (r *Ruleset) Eval(context any) (any, error) {
 	}
 
 	// Evaluate everything else
+	failed := false
 	if os.Getenv("LESS_GO_DEBUG") == "1" {
 		fmt.Fprintf(os.Stderr, "[Ruleset.Eval] Evaluating %d rules, r=%p\n", len(rsRules), r)
 	}
@@ -855,10 +882,21 @@ func (r *Ruleset) Eval(context any) (any, error) {
 			rsRules[i] = evaluated
 		case interface{ Eval(any) (any, error) }:
 			// Handle generic Eval
-			evaluated, err := evalRule.Eval(context)
+			var evaluated any
+			depth := calls.visit(rule)
+			skip, err := errs.collect(context, func() (err error) {
+				evaluated, err = evalRule.Eval(context)
+				return err
+			})
+			calls.leave(depth, nil)
 			if err != nil {
 				return nil, err
 			}
+			if skip {
+				rsRules[i] = nil
+				failed = true
+				continue
+			}
 			if os.Getenv("LESS_GO_DEBUG") == "1" {
 				if _, isMedia := rule.(*Media); isMedia {
 					fmt.Fprintf(os.Stderr, "[RULESET.Eval] Media node evaluated to type=%T\n", evaluated)
@@ -874,6 +912,10 @@ func (r *Ruleset) Eval(context any) (any, error) {
 		}
 	}
 
+	if failed {
+		rsRules = slices.DeleteFunc(rsRules, func(rule any) bool { return rule == nil })
+	}
+
 	// CRITICAL FIX: Store evaluated rules back to ruleset
 	// Without this, all rule modifications during evaluation (including Media nodes from inline imports) are lost
 	ruleset.Rules = rsRules
@@ -1878,6 +1920,7 @@ func (r *Ruleset) GenCSS(context any, output *CSSOutput) {
 	if !ok {
 		ctx = make(map[string]any)
 	}
+	calls := callStackOf(ctx)
 
 	// Check if this ruleset should be treated as top-level
 	// When rulesets are extracted from parent rulesets and output separately,
@@ -2229,7 +2272,9 @@ func (r *Ruleset) GenCSS(context any, output *CSSOutput) {
 
 			// Generate CSS for the rule
 			if gen, ok := rule.(interface{ GenCSS(any, *CSSOutput) }); ok {
+				depth := calls.visit(rule)
 				gen.GenCSS(childContext, output)
+				calls.leave(depth, nil)
 			} else if val, ok := rule.(interface{ GetValue() any }); ok {
 				output.Add(fmt.Sprintf("%v", val.GetValue()), nil, nil)
 			}
@@ -3,6 +3,7 @@ package runtime
 import (
 	"bufio"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"os"
@@ -109,6 +110,11 @@ type NodeJSRuntime struct {
 	// Prefetch binary data cache - avoids re-serializing variables on every plugin call
 	prefetchCache   *PrefetchCache
 	prefetchCacheMu sync.RWMutex
+
+	// Cancellation channel of the compilation currently using this runtime.
+	// Commands waiting for a response give up when it is closed.
+	cancel   <-chan struct{}
+	cancelMu sync.RWMutex
 }
 
 // PrefetchCache caches the serialized binary prefetch buffer for plugin function calls.
@@ -360,6 +366,13 @@ func (rt *NodeJSRuntime) SendCommandWithContext(ctx context, cmd Command) (Respo
 		return Response{}, fmt.Errorf("runtime not alive")
 	}
 
+	// Don't start new work for a compilation that has already been cancelled
+	select {
+	case <-rt.cancelChan():
+		return Response{}, ErrCommandCanceled
+	default:
+	}
+
 	// Assign command ID
 	cmd.ID = rt.cmdID.Add(1)
 
@@ -403,11 +416,32 @@ func (rt *NodeJSRuntime) SendCommandWithContext(ctx context, cmd Command) (Respo
 		return resp, nil
 	case <-ctx.done():
 		return Response{}, fmt.Errorf("command timed out")
+	case <-rt.cancelChan():
+		return Response{}, ErrCommandCanceled
 	case <-rt.done:
 		return Response{}, fmt.Errorf("runtime shutting down")
 	}
 }
 
+// ErrCommandCanceled is returned by SendCommand when the compilation that issued
+// the command is cancelled before Node.js responds.
+var ErrCommandCanceled = errors.New("command canceled")
+
+// SetCancel sets the channel that aborts in-flight commands when closed.
+// It is typically the Done channel of the compilation's context.Context.
+// Passing nil removes any previously set channel.
+func (rt *NodeJSRuntime) SetCancel(done <-chan struct{}) {
+	rt.cancelMu.Lock()
+	rt.cancel = done
+	rt.cancelMu.Unlock()
+}
+
+func (rt *NodeJSRuntime) cancelChan() <-chan struct{} {
+	rt.cancelMu.RLock()
+	defer rt.cancelMu.RUnlock()
+	return rt.cancel
+}
+
 // SendCommandFireAndForget sends a command without waiting for a response.
 // This is useful for commands that don't need a response or when the response
 // can be safely ignored. The command is still sent with an ID for logging purposes.
@@ -638,6 +672,26 @@ func (rt *NodeJSRuntime) Ping() error {
 	return nil
 }
 
+// Reset clears the plugins, functions, visitors and processors registered in
+// the Node.js process and the caches kept for them on the Go side, so that the
+// runtime can be reused by another compilation. Plugin files are reloaded
+// from disk the next time they are loaded.
+func (rt *NodeJSRuntime) Reset() error {
+	// The previous compilation's cancellation must not abort the reset
+	rt.SetCancel(nil)
+	resp, err := rt.SendCommand(Command{Cmd: "reset"})
+	if err != nil {
+		return err
+	}
+	if !resp.Success {
+		return fmt.Errorf("reset failed: %s", resp.Error)
+	}
+	rt.ClearFunctionCache()
+	rt.InvalidatePrefetchCache()
+	rt.scopeDepth.Store(0)
+	return nil
+}
+
 // Echo sends a value to Node.js and expects it back (for testing).
 func (rt *NodeJSRuntime) Echo(value any) (any, error) {
 	resp, err := rt.SendCommand(Command{
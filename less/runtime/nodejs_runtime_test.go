@@ -3,6 +3,7 @@
 package runtime
 
 import (
+	"errors"
 	"os"
 	"path/filepath"
 	"testing"
@@ -298,6 +299,36 @@ func TestNodeJSRuntime_CommandAfterStop(t *testing.T) {
 	}
 }
 
+func TestNodeJSRuntime_CommandAfterCancel(t *testing.T) {
+	path := getPluginHostPath(t)
+	rt, err := NewNodeJSRuntime(WithPluginHostPath(path))
+	if err != nil {
+		t.Fatalf("NewNodeJSRuntime failed: %v", err)
+	}
+
+	if err := rt.Start(); err != nil {
+		t.Fatalf("Start failed: %v", err)
+	}
+	defer rt.Stop()
+
+	done := make(chan struct{})
+	rt.SetCancel(done)
+	if err := rt.Ping(); err != nil {
+		t.Fatalf("Ping before cancel failed: %v", err)
+	}
+
+	close(done)
+	if _, err := rt.SendCommand(Command{Cmd: "ping"}); !errors.Is(err, ErrCommandCanceled) {
+		t.Errorf("SendCommand after cancel = %v, want ErrCommandCanceled", err)
+	}
+
+	// Clearing the channel makes the runtime usable for the next compilation
+	rt.SetCancel(nil)
+	if err := rt.Ping(); err != nil {
+		t.Errorf("Ping after clearing cancel failed: %v", err)
+	}
+}
+
 func TestNodeJSRuntime_GetRegisteredFunctions(t *testing.T) {
 	path := getPluginHostPath(t)
 	rt, err := NewNodeJSRuntime(WithPluginHostPath(path))
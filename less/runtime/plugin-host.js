@@ -1055,6 +1055,10 @@ function handleCommand(cmd) {
         setImmediate(() => process.exit(0));
         break;
 
+      case 'reset':
+        handleReset(id);
+        break;
+
       case 'loadPlugin':
         handleLoadPlugin(id, data);
         break;
@@ -1410,6 +1414,36 @@ function handleAddFunctionToScopeNoReply(args) {
   // NO response sent - this is fire-and-forget
 }
 
+/**
+ * Forget everything registered by plugins so the process can serve another
+ * compilation. Plugin modules are dropped from the require cache so that
+ * edited plugin files are loaded afresh; npm packages are kept.
+ * @param {number} id - Command ID
+ */
+function handleReset(id) {
+  loadedPlugins.clear();
+  registeredFunctions.clear();
+  contextFreeFunctions.clear();
+  registeredVisitors.length = 0;
+  registeredPreProcessors.length = 0;
+  registeredPostProcessors.length = 0;
+  registeredFileManagers.length = 0;
+  functionScopeStack.length = 0;
+  functionScopeStack.push(new Map());
+  lastPrefetchPath = null;
+  lastPrefetchSize = 0;
+  cachedPrefetchVars = null;
+
+  const nodeModules = `${path.sep}node_modules${path.sep}`;
+  for (const key of Object.keys(require.cache)) {
+    if (!key.startsWith(__dirname + path.sep) && !key.includes(nodeModules)) {
+      delete require.cache[key];
+    }
+  }
+
+  sendResponse(id, true, 'reset');
+}
+
 /**
  * Load a plugin from a file path
  * @param {number} id - Command ID
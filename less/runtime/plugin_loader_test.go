@@ -5,6 +5,7 @@ package runtime
 import (
 	"os"
 	"path/filepath"
+	"strconv"
 	"strings"
 	"testing"
 )
@@ -599,3 +600,57 @@ func TestJSPluginLoader_GetVisitors(t *testing.T) {
 		t.Error("Should be a replacing visitor")
 	}
 }
+
+func TestJSPluginLoader_ResetReloadsPlugins(t *testing.T) {
+	dir := t.TempDir()
+	pluginPath := filepath.Join(dir, "answer.js")
+	writePlugin := func(value int) {
+		code := "functions.add('answer', function() { return less.dimension(" + strconv.Itoa(value) + "); });\n"
+		if err := os.WriteFile(pluginPath, []byte(code), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	rt, err := NewNodeJSRuntime()
+	if err != nil {
+		t.Fatalf("NewNodeJSRuntime failed: %v", err)
+	}
+	if err := rt.Start(); err != nil {
+		t.Fatalf("Start failed: %v", err)
+	}
+	defer rt.Stop()
+
+	callAnswer := func() any {
+		loader := NewJSPluginLoader(rt)
+		if _, ok := loader.LoadPlugin(pluginPath, dir, nil, nil, nil).(*Plugin); !ok {
+			t.Fatal("plugin failed to load")
+		}
+		result, err := loader.CallFunction("answer", nil)
+		if err != nil {
+			t.Fatalf("CallFunction failed: %v", err)
+		}
+		return result.(map[string]any)["value"]
+	}
+
+	writePlugin(1)
+	if got := callAnswer(); got != float64(1) {
+		t.Fatalf("answer() = %v, want 1", got)
+	}
+
+	if err := rt.Reset(); err != nil {
+		t.Fatalf("Reset failed: %v", err)
+	}
+	resp, err := rt.SendCommand(Command{Cmd: "getRegisteredFunctions"})
+	if err != nil || !resp.Success {
+		t.Fatalf("getRegisteredFunctions failed: %v %s", err, resp.Error)
+	}
+	if functions, _ := resp.Result.([]any); len(functions) != 0 {
+		t.Errorf("functions should be cleared by Reset, got %v", functions)
+	}
+
+	// The edited plugin file is read again
+	writePlugin(2)
+	if got := callAnswer(); got != float64(2) {
+		t.Errorf("answer() after Reset = %v, want 2", got)
+	}
+}
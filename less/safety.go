@@ -144,6 +144,14 @@ func SafeEval(value any, context any) any {
 				if lessErr.Type == "JavaScript" {
 					panic(err)
 				}
+				// With CollectErrors, undefined variables and operations on invalid types are reported
+				// and otherwise undefined names are warned about
+				if errs := errorCollectorOf(context); errs != nil && (lessErr.Type == "Name" || lessErr.Type == "Operation") {
+					callStackOf(context).trace(lessErr)
+					errs.add(lessErr)
+				} else if errs == nil && lessErr.Type == "Name" {
+					diagnosticsOf(context).warnUndefined(lessErr)
+				}
 			}
 			// Return original value on other errors to support late binding and graceful degradation
 			// This allows undefined variables and other recoverable errors to be handled gracefully
@@ -2,6 +2,7 @@ package less_go
 
 import (
 	"fmt"
+	"io"
 	"os"
 	"strings"
 )
@@ -42,6 +43,29 @@ func NewSourceMapBuilder(options SourceMapBuilderOptions) *SourceMapBuilder {
 }
 
 func (smb *SourceMapBuilder) ToCSS(rootNode SourceMapNode, options map[string]any, imports *Imports, environment SourceMapEnvironment) string {
+	css, _ := smb.toCSS(rootNode, options, imports, nil, nil)
+	return css + smb.getCSSAppendage(environment)
+}
+
+// WriteCSS is like ToCSS but writes the CSS to w as it is generated. With a
+// mapWriter, the source map is written to it as the CSS is generated, and
+// WriteCSS returns the first error writing it. Otherwise, or if the source map
+// is inline, which needs it whole, GetExternalSourceMap returns the source map
+// once WriteCSS returns.
+func (smb *SourceMapBuilder) WriteCSS(w, mapWriter io.Writer, rootNode SourceMapNode, options map[string]any, imports *Imports, environment SourceMapEnvironment) error {
+	if smb.options.SourceMapFileInline {
+		mapWriter = nil
+	}
+	_, err := smb.toCSS(rootNode, options, imports, w, mapWriter)
+	io.WriteString(w, smb.getCSSAppendage(environment))
+	return err
+}
+
+func (smb *SourceMapBuilder) toCSS(rootNode SourceMapNode, options map[string]any, imports *Imports, w, mapWriter io.Writer) (string, error) {
+	var streamed *streamingSourceMapGenerator
+	if mapWriter != nil {
+		streamed = newStreamingSourceMapGenerator(mapWriter)
+	}
 	sourceMapOutputOptions := SourceMapOutputOptions{
 		ContentsIgnoredCharsMap:        imports.ContentsIgnoredChars,
 		RootNode:                       rootNode,
@@ -53,6 +77,9 @@ func (smb *SourceMapBuilder) ToCSS(rootNode SourceMapNode, options map[string]an
 		SourceMapRootpath:              smb.options.SourceMapRootpath,
 		OutputSourceFiles:              smb.options.OutputSourceFiles,
 		SourceMapGeneratorConstructor:  func() SourceMapGenerator { 
+			if streamed != nil {
+				return streamed
+			}
 			// This should be injected from the caller in real usage
 			// For now, we'll use a default implementation
 			return &defaultSourceMapGenerator{
@@ -60,10 +87,15 @@ func (smb *SourceMapBuilder) ToCSS(rootNode SourceMapNode, options map[string]an
 				sourceContents: make(map[string]string),
 			}
 		},
+		Output: w,
 	}
 
 	sourceMapOutput := NewSourceMapOutput(sourceMapOutputOptions)
 	css := sourceMapOutput.ToCSS(options)
+	var err error
+	if streamed != nil && !sourceMapOutput.IsEmpty() {
+		err = streamed.Finish()
+	}
 	smb.sourceMap = sourceMapOutput.SourceMap
 	smb.sourceMapURL = sourceMapOutput.sourceMapURL
 	
@@ -75,7 +107,7 @@ func (smb *SourceMapBuilder) ToCSS(rootNode SourceMapNode, options map[string]an
 		smb.sourceMapURL = sourceMapOutput.RemoveBasepath(smb.sourceMapURL)
 	}
 	
-	return css + smb.getCSSAppendage(environment)
+	return css, err
 }
 
 func (smb *SourceMapBuilder) getCSSAppendage(environment SourceMapEnvironment) string {
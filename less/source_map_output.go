@@ -3,6 +3,7 @@ package less_go
 import (
 	"encoding/json"
 	"fmt"
+	"io"
 	"os"
 	"strings"
 )
@@ -23,6 +24,8 @@ type SourceMapOutput struct {
 	column                         int
 	sourceMapGenerator             SourceMapGenerator
 	SourceMap                      string
+	output                         io.Writer
+	written                        bool
 }
 
 type SourceMapGenerator interface {
@@ -58,6 +61,9 @@ type SourceMapOutputOptions struct {
 	SourceMapRootpath              string
 	OutputSourceFiles              bool
 	SourceMapGeneratorConstructor  func() SourceMapGenerator
+	// Output, if set, receives the CSS as it is generated instead of ToCSS
+	// returning it
+	Output io.Writer
 }
 
 func NewSourceMapOutput(options SourceMapOutputOptions) *SourceMapOutput {
@@ -69,6 +75,7 @@ func NewSourceMapOutput(options SourceMapOutputOptions) *SourceMapOutput {
 		outputFilename:         options.OutputFilename,
 		outputSourceFiles:      options.OutputSourceFiles,
 		sourceMapGeneratorConstructor: options.SourceMapGeneratorConstructor,
+		output:                 options.Output,
 		lineNumber:             0,
 		column:                 0,
 	}
@@ -151,7 +158,7 @@ func (smo *SourceMapOutput) Add(chunk string, fileInfo *FileInfo, index int, map
 
 		// ignore empty content, or failsafe if contents map is incorrect
 		if !exists {
-			smo.css = append(smo.css, chunk)
+			smo.write(chunk)
 			return
 		}
 
@@ -200,11 +207,21 @@ func (smo *SourceMapOutput) Add(chunk string, fileInfo *FileInfo, index int, map
 		smo.column = len(columns)
 	}
 
+	smo.write(chunk)
+}
+
+// write appends chunk to the CSS, or writes it to the Output
+func (smo *SourceMapOutput) write(chunk string) {
+	smo.written = true
+	if smo.output != nil {
+		io.WriteString(smo.output, chunk)
+		return
+	}
 	smo.css = append(smo.css, chunk)
 }
 
 func (smo *SourceMapOutput) IsEmpty() bool {
-	return len(smo.css) == 0
+	return !smo.written
 }
 
 func (smo *SourceMapOutput) ToCSS(context map[string]any) string {
@@ -224,18 +241,19 @@ func (smo *SourceMapOutput) ToCSS(context map[string]any) string {
 
 	smo.rootNode.GenCSSSourceMap(context, smo)
 
-	if len(smo.css) > 0 {
+	if smo.written {
 		var sourceMapURL string
-		sourceMapJSON := smo.sourceMapGenerator.ToJSON()
-		sourceMapContent, _ := json.Marshal(sourceMapJSON)
-
 		if smo.sourceMapURL != "" {
 			sourceMapURL = smo.sourceMapURL
 		} else if smo.sourceMapFilename != "" {
 			sourceMapURL = smo.sourceMapFilename
 		}
 		smo.sourceMapURL = sourceMapURL
-		smo.SourceMap = string(sourceMapContent)
+		// A streaming generator has written the mappings already
+		if _, streamed := smo.sourceMapGenerator.(*streamingSourceMapGenerator); !streamed {
+			sourceMapContent, _ := json.Marshal(smo.sourceMapGenerator.ToJSON())
+			smo.SourceMap = string(sourceMapContent)
+		}
 	}
 
 	return strings.Join(smo.css, "")
@@ -0,0 +1,130 @@
+package less_go
+
+import (
+	"bufio"
+	"encoding/json"
+	"io"
+	"strings"
+)
+
+// streamingSourceMapGenerator writes the mappings of a source map to w as they
+// are added, instead of keeping them until the CSS is done, so that the source
+// map of a large stylesheet is never held in memory whole. The mappings must
+// come in the order of the generated CSS, as SourceMapOutput adds them. Finish
+// writes the sources once the CSS is done: the JSON is the same as that of
+// defaultSourceMapGenerator, whose keys json.Marshal sorts.
+type streamingSourceMapGenerator struct {
+	w       *bufio.Writer
+	err     error
+	started bool
+
+	// line is the generated line being written, and lineStarted whether it
+	// has a mapping yet
+	line        int
+	lineStarted bool
+
+	prevGenCol    int
+	prevSourceIdx int
+	prevOrigLine  int
+	prevOrigCol   int
+
+	sourceContents map[string]string
+	sources        []string
+	sourceIndexMap map[string]int
+}
+
+func newStreamingSourceMapGenerator(w io.Writer) *streamingSourceMapGenerator {
+	return &streamingSourceMapGenerator{
+		w:              bufio.NewWriterSize(w, 64*1024),
+		line:           1,
+		sourceContents: make(map[string]string),
+		sourceIndexMap: make(map[string]int),
+	}
+}
+
+func (s *streamingSourceMapGenerator) writeString(str string) {
+	if s.err == nil {
+		_, s.err = s.w.WriteString(str)
+	}
+}
+
+func (s *streamingSourceMapGenerator) start() {
+	if !s.started {
+		s.started = true
+		s.writeString(`{"mappings":"`)
+	}
+}
+
+func (s *streamingSourceMapGenerator) AddMapping(mapping SourceMapMapping) {
+	s.start()
+	if mapping.Generated.Line > s.line {
+		s.writeString(strings.Repeat(";", mapping.Generated.Line-s.line))
+		s.line = mapping.Generated.Line
+		s.lineStarted = false
+		s.prevGenCol = 0
+	}
+	if s.lineStarted {
+		s.writeString(",")
+	}
+	s.lineStarted = true
+
+	if mapping.Source != "" {
+		if _, exists := s.sourceIndexMap[mapping.Source]; !exists {
+			s.sourceIndexMap[mapping.Source] = len(s.sources)
+			s.sources = append(s.sources, mapping.Source)
+		}
+	}
+	sourceIdx := s.sourceIndexMap[mapping.Source]
+
+	s.writeString(encodeVLQ(mapping.Generated.Column - s.prevGenCol))
+	s.writeString(encodeVLQ(sourceIdx - s.prevSourceIdx))
+	s.writeString(encodeVLQ((mapping.Original.Line - 1) - s.prevOrigLine))
+	s.writeString(encodeVLQ(mapping.Original.Column - s.prevOrigCol))
+
+	s.prevGenCol = mapping.Generated.Column
+	s.prevSourceIdx = sourceIdx
+	s.prevOrigLine = mapping.Original.Line - 1
+	s.prevOrigCol = mapping.Original.Column
+}
+
+func (s *streamingSourceMapGenerator) SetSourceContent(source, content string) {
+	s.sourceContents[source] = content
+}
+
+// ToJSON returns the fields of the source map other than the mappings, which
+// were written already
+func (s *streamingSourceMapGenerator) ToJSON() map[string]any {
+	result := map[string]any{
+		"version": 3,
+		"sources": s.sources,
+	}
+	var sourcesContent []any
+	for _, src := range s.sources {
+		if content, ok := s.sourceContents[src]; ok {
+			sourcesContent = append(sourcesContent, content)
+		} else {
+			sourcesContent = append(sourcesContent, nil)
+		}
+	}
+	if len(sourcesContent) > 0 {
+		result["sourcesContent"] = sourcesContent
+	}
+	return result
+}
+
+// Finish writes the rest of the source map and returns the first write error
+func (s *streamingSourceMapGenerator) Finish() error {
+	s.start()
+	fields := s.ToJSON()
+	sources, _ := json.Marshal(fields["sources"])
+	s.writeString(`","sources":` + string(sources))
+	if sourcesContent, ok := fields["sourcesContent"]; ok {
+		content, _ := json.Marshal(sourcesContent)
+		s.writeString(`,"sourcesContent":` + string(content))
+	}
+	s.writeString(`,"version":3}`)
+	if s.err == nil {
+		s.err = s.w.Flush()
+	}
+	return s.err
+}
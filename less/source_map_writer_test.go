@@ -0,0 +1,62 @@
+package less_go
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+)
+
+func TestStreamingSourceMapGenerator_MatchesDefault(t *testing.T) {
+	mapping := func(genLine, genCol, origLine, origCol int, source string) SourceMapMapping {
+		return SourceMapMapping{
+			Generated: SourceMapPosition{Line: genLine, Column: genCol},
+			Original:  SourceMapPosition{Line: origLine, Column: origCol},
+			Source:    source,
+		}
+	}
+	tests := []struct {
+		name     string
+		mappings []SourceMapMapping
+		contents map[string]string
+	}{
+		{name: "no mappings"},
+		{
+			name: "lines and sources",
+			mappings: []SourceMapMapping{
+				mapping(1, 0, 1, 0, "main.less"),
+				mapping(1, 4, 1, 5, "main.less"),
+				mapping(2, 2, 3, 2, "<lib>.less"),
+				// Lines without mappings in between
+				mapping(5, 0, 2, 0, "main.less"),
+				mapping(5, 12, 4, 8, ""),
+			},
+			contents: map[string]string{"main.less": ".a { b: c; }", "<lib>.less": "@x: 1;"},
+		},
+		{
+			name:     "first mapping below the first line",
+			mappings: []SourceMapMapping{mapping(3, 2, 1, 0, "main.less")},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			want := &defaultSourceMapGenerator{sourceContents: make(map[string]string)}
+			var out bytes.Buffer
+			streamed := newStreamingSourceMapGenerator(&out)
+			for source, content := range tt.contents {
+				want.SetSourceContent(source, content)
+				streamed.SetSourceContent(source, content)
+			}
+			for _, m := range tt.mappings {
+				want.AddMapping(m)
+				streamed.AddMapping(m)
+			}
+			if err := streamed.Finish(); err != nil {
+				t.Fatal(err)
+			}
+			wantJSON, _ := json.Marshal(want.ToJSON())
+			if out.String() != string(wantJSON) {
+				t.Errorf("got %s, want %s", out.String(), wantJSON)
+			}
+		})
+	}
+}
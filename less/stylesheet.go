@@ -0,0 +1,140 @@
+package less_go
+
+import (
+	"fmt"
+	"runtime/debug"
+)
+
+// Stylesheet is the syntax tree of a Less file, as returned by ParseStylesheet.
+// Nothing in it is evaluated: variables, mixin calls, operations and guards
+// appear as written, so the tree can be inspected with Walk and Inspect,
+// modified, and serialized back to Less source with Print.
+type Stylesheet struct {
+	// Root holds the statements of the file in its Rules
+	Root *Ruleset
+	// Filename is the name the input was parsed as
+	Filename string
+	// Source is the input as parsed, with line endings normalized and any BOM removed.
+	// Node indexes are byte offsets into it.
+	Source string
+	// Warnings are the deprecation warnings reported by the parser
+	Warnings []Diagnostic
+}
+
+// ParseStylesheet parses Less source into a Stylesheet without evaluating it,
+// so that tools such as linters, codemods and documentation generators can work
+// on the real syntax tree. (The name Parse is taken by the parse context type.)
+//
+// @import statements are kept as Import nodes and not followed. Of options, only
+// Filename, OnDiagnostic, CollectErrors and PanicHandler are used; options may
+// be nil. A syntax error is returned as a *LessError. With CollectErrors, invalid
+// statements are skipped and the stylesheet is returned with a *MultiError of
+// all syntax errors.
+//
+// Example usage:
+//
+//	sheet, err := less_go.ParseStylesheet(`.a { color: @brand; }`, nil)
+//	if err != nil {
+//	    log.Fatal(err)
+//	}
+//	less_go.Walk(sheet.Root, func(d *less_go.Declaration) {
+//	    fmt.Println(d.GetName())
+//	})
+func ParseStylesheet(input string, options *CompileOptions) (sheet *Stylesheet, err error) {
+	if options == nil {
+		options = &CompileOptions{}
+	}
+	filename := options.Filename
+	if filename == "" {
+		filename = "input"
+	}
+	diagnostics := newDiagnosticCollector(options.OnDiagnostic)
+
+	context := map[string]any{
+		"processImports": false,
+		"diagnostics":    diagnostics,
+	}
+	var errs *errorCollector
+	if options.CollectErrors {
+		errs = newErrorCollector()
+		context["errors"] = errs
+	}
+	if options.PanicHandler != nil {
+		context["panicHandler"] = options.PanicHandler
+	}
+	imports := map[string]any{
+		"contents":             make(map[string]string),
+		"contentsIgnoredChars": make(map[string]int),
+		"rootFilename":         filename,
+	}
+	fileInfo := map[string]any{
+		"filename":         filename,
+		"rootFilename":     filename,
+		"currentDirectory": "",
+		"entryPath":        "",
+	}
+
+	defer func() {
+		if r := recover(); r != nil {
+			sheet = nil
+			if isInternalPanic(r) {
+				r = internalError(r, debug.Stack(), "", nil, nil, "", options.PanicHandler)
+			}
+			if e, ok := r.(error); ok {
+				err = fmt.Errorf("parse failed: %w", e)
+			} else {
+				err = fmt.Errorf("parse failed: %v", r)
+			}
+		}
+	}()
+
+	var root *Ruleset
+	var parseErr *LessError
+	NewParser(context, imports, fileInfo, 0).Parse(input, func(e *LessError, r *Ruleset) {
+		parseErr = e
+		root = r
+	}, nil)
+	if parseErr != nil {
+		return nil, parseErr
+	}
+
+	contents := imports["contents"].(map[string]string)
+	sheet = &Stylesheet{
+		Root:     root,
+		Filename: filename,
+		Source:   contents[filename],
+		Warnings: diagnostics.list(),
+	}
+	if errs != nil {
+		return sheet, errs.result(nil, contents, filename)
+	}
+	return sheet, nil
+}
+
+// Position returns the 1-based line and column where node starts in the
+// stylesheet, or 0, 0 if the node has no index or belongs to another file.
+func (s *Stylesheet) Position(node any) (line, column int) {
+	n, ok := node.(interface{ GetIndex() int })
+	if !ok {
+		return 0, 0
+	}
+	if f, ok := node.(interface{ FileInfo() map[string]any }); ok {
+		if filename, _ := f.FileInfo()["filename"].(string); filename != "" && filename != s.Filename {
+			return 0, 0
+		}
+	}
+	index := n.GetIndex()
+	if index < 0 || index > len(s.Source) {
+		return 0, 0
+	}
+	loc := GetLocation(index, s.Source)
+	if loc.Line == nil {
+		return 0, 0
+	}
+	return *loc.Line + 1, loc.Column + 1
+}
+
+// String returns the stylesheet as Less source, as printed by Print
+func (s *Stylesheet) String() string {
+	return Print(s.Root)
+}
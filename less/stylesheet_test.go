@@ -0,0 +1,140 @@
+package less_go
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestParseStylesheet_Tree(t *testing.T) {
+	input := "@brand: #336699;\n.button {\n  color: @brand;\n  .rounded(4px);\n}\n"
+	sheet, err := ParseStylesheet(input, &CompileOptions{Filename: "button.less"})
+	if err != nil {
+		t.Fatalf("ParseStylesheet failed: %v", err)
+	}
+	if sheet.Filename != "button.less" || sheet.Source != input {
+		t.Errorf("unexpected filename %q or source %q", sheet.Filename, sheet.Source)
+	}
+	if len(sheet.Root.Rules) != 2 {
+		t.Fatalf("expected 2 statements, got %d", len(sheet.Root.Rules))
+	}
+	if d, ok := sheet.Root.Rules[0].(*Declaration); !ok || d.GetName() != "@brand" || !d.GetVariable() {
+		t.Errorf("expected the @brand declaration, got %#v", sheet.Root.Rules[0])
+	}
+	ruleset, ok := sheet.Root.Rules[1].(*Ruleset)
+	if !ok {
+		t.Fatalf("expected a ruleset, got %T", sheet.Root.Rules[1])
+	}
+	if _, ok := ruleset.Rules[1].(*MixinCall); !ok {
+		t.Errorf("expected the mixin call to be kept unevaluated, got %T", ruleset.Rules[1])
+	}
+}
+
+func TestParseStylesheet_DoesNotFollowImports(t *testing.T) {
+	sheet, err := ParseStylesheet(`@import "missing.less"; .a { b: c; }`, nil)
+	if err != nil {
+		t.Fatalf("ParseStylesheet failed: %v", err)
+	}
+	if _, ok := sheet.Root.Rules[0].(*Import); !ok {
+		t.Errorf("expected an Import node, got %T", sheet.Root.Rules[0])
+	}
+}
+
+func TestParseStylesheet_SyntaxError(t *testing.T) {
+	_, err := ParseStylesheet(".a {\n  color: red;\n", &CompileOptions{Filename: "broken.less"})
+	var lessErr *LessError
+	if !errors.As(err, &lessErr) {
+		t.Fatalf("expected a *LessError, got %v", err)
+	}
+	if lessErr.Filename != "broken.less" || !lessErr.HasLineColumn() {
+		t.Errorf("expected a located error in broken.less, got %+v", lessErr)
+	}
+}
+
+func TestParseStylesheet_Warnings(t *testing.T) {
+	var reported []Diagnostic
+	sheet, err := ParseStylesheet(".m() { a: b; }\n.x { .m; }\n", &CompileOptions{
+		OnDiagnostic: func(d Diagnostic) { reported = append(reported, d) },
+	})
+	if err != nil {
+		t.Fatalf("ParseStylesheet failed: %v", err)
+	}
+	if len(sheet.Warnings) != 1 || sheet.Warnings[0].Code != DiagnosticMixinCallNoParens {
+		t.Fatalf("expected a %s warning, got %v", DiagnosticMixinCallNoParens, sheet.Warnings)
+	}
+	if len(reported) != 1 {
+		t.Errorf("expected OnDiagnostic to be called once, got %v", reported)
+	}
+}
+
+func TestStylesheet_Position(t *testing.T) {
+	sheet, err := ParseStylesheet(".a {\n  color: @brand;\n}\n", nil)
+	if err != nil {
+		t.Fatalf("ParseStylesheet failed: %v", err)
+	}
+	var found bool
+	Walk(sheet.Root, func(v *Variable) {
+		found = true
+		if line, column := sheet.Position(v); line != 2 || column != 10 {
+			t.Errorf("expected @brand at 2:10, got %d:%d", line, column)
+		}
+	})
+	if !found {
+		t.Fatal("expected to find the variable")
+	}
+	if line, column := sheet.Position("not a node"); line != 0 || column != 0 {
+		t.Errorf("expected 0:0 for a value without position, got %d:%d", line, column)
+	}
+}
+
+func TestInspect_ReachesValuesAndArguments(t *testing.T) {
+	input := `
+.a > .b:hover {
+  width: (@w + 2px) * -@x;
+  background: url(@img) lighten(@c, 10%);
+  .mixin(@arg; 1px);
+}
+.m(@p: @default) when (@mode = dark) { a: b; }
+@media (min-width: @bp) { .c { d: e; } }
+`
+	sheet, err := ParseStylesheet(input, nil)
+	if err != nil {
+		t.Fatalf("ParseStylesheet failed: %v", err)
+	}
+	var names []string
+	Walk(sheet.Root, func(v *Variable) {
+		names = append(names, v.GetName())
+	})
+	got := strings.Join(names, " ")
+	for _, name := range []string{"@w", "@x", "@img", "@c", "@arg", "@default", "@mode", "@bp"} {
+		if !strings.Contains(got+" ", name+" ") {
+			t.Errorf("expected to visit %s, visited %s", name, got)
+		}
+	}
+
+	var selectors int
+	Walk(sheet.Root, func(*Selector) { selectors++ })
+	if selectors == 0 {
+		t.Error("expected selectors to be visited")
+	}
+}
+
+func TestInspect_SkipsChildren(t *testing.T) {
+	sheet, err := ParseStylesheet(`.a { color: @skipped; } .b { color: @kept; }`, nil)
+	if err != nil {
+		t.Fatalf("ParseStylesheet failed: %v", err)
+	}
+	var names []string
+	Inspect(sheet.Root, func(node any) bool {
+		if r, ok := node.(*Ruleset); ok && !r.Root && strings.Contains(Print(r.Selectors[0]), ".a") {
+			return false
+		}
+		if v, ok := node.(*Variable); ok {
+			names = append(names, v.GetName())
+		}
+		return true
+	})
+	if strings.Join(names, ",") != "@kept" {
+		t.Errorf("expected only @kept to be visited, got %v", names)
+	}
+}
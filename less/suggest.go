@@ -0,0 +1,182 @@
+package less_go
+
+import (
+	"slices"
+	"strings"
+)
+
+// maxSuggestions is the number of names suggested for an undefined one
+const maxSuggestions = 3
+
+// suggestNames returns the candidates closest to name, a misspelled variable,
+// mixin or function, by edit distance: at most maxSuggestions of them, closest
+// first, within a third of the length of name. Case is ignored when comparing,
+// so that @Brand suggests @brand.
+func suggestNames(name string, candidates []string) []string {
+	target := strings.ToLower(name)
+	limit := max(1, len(strings.TrimLeft(target, "@$.#"))/3)
+	type suggestion struct {
+		name     string
+		distance int
+	}
+	var found []suggestion
+	seen := make(map[string]bool)
+	for _, candidate := range candidates {
+		if candidate == name || seen[candidate] {
+			continue
+		}
+		seen[candidate] = true
+		if d := editDistance(target, strings.ToLower(candidate)); d <= limit {
+			found = append(found, suggestion{candidate, d})
+		}
+	}
+	slices.SortFunc(found, func(a, b suggestion) int {
+		if a.distance != b.distance {
+			return a.distance - b.distance
+		}
+		return strings.Compare(a.name, b.name)
+	})
+	var names []string
+	for _, s := range found[:min(len(found), maxSuggestions)] {
+		names = append(names, s.name)
+	}
+	return names
+}
+
+// editDistance returns the number of insertions, deletions, substitutions and
+// transpositions of adjacent characters turning a into b
+func editDistance(a, b string) int {
+	s, t := []rune(a), []rune(b)
+	// Three rows of the distance matrix: two back for transpositions
+	prev2 := make([]int, len(t)+1)
+	prev := make([]int, len(t)+1)
+	cur := make([]int, len(t)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(s); i++ {
+		cur[0] = i
+		for j := 1; j <= len(t); j++ {
+			cost := 1
+			if s[i-1] == t[j-1] {
+				cost = 0
+			}
+			cur[j] = min(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+			if i > 1 && j > 1 && s[i-1] == t[j-2] && s[i-2] == t[j-1] {
+				cur[j] = min(cur[j], prev2[j-2]+1)
+			}
+		}
+		prev2, prev, cur = prev, cur, prev2
+	}
+	return prev[len(t)]
+}
+
+// evalFrames returns the frames of an *Eval or map context
+func evalFrames(context any) []any {
+	switch c := context.(type) {
+	case *Eval:
+		return c.Frames
+	case map[string]any:
+		frames, _ := c["frames"].([]any)
+		return frames
+	}
+	return nil
+}
+
+// variableNames returns the names of the variables in scope in frames
+func variableNames(frames []any) []string {
+	var names []string
+	for _, frame := range frames {
+		if f, ok := frame.(interface{ Variables() map[string]any }); ok {
+			for name := range f.Variables() {
+				names = append(names, name)
+			}
+		}
+	}
+	return names
+}
+
+// mixinNames returns the names a mixin call can use in frames: those of mixin
+// definitions and of rulesets with a class or id selector, and those of the
+// mixins inside these rulesets prefixed with their namespace, as in #ns.m
+func mixinNames(frames []any) []string {
+	var names []string
+	for _, frame := range frames {
+		rules, _ := frame.(interface{ GetRules() []any })
+		if rules == nil {
+			continue
+		}
+		for _, rule := range rules.GetRules() {
+			name := mixinName(rule)
+			if name == "" {
+				continue
+			}
+			names = append(names, name)
+			if ns, ok := rule.(interface{ GetRules() []any }); ok {
+				for _, member := range ns.GetRules() {
+					if memberName := mixinName(member); memberName != "" {
+						names = append(names, name+memberName)
+					}
+				}
+			}
+		}
+	}
+	return names
+}
+
+// mixinName returns the name a mixin call can use for rule, or "" if it cannot
+// be called
+func mixinName(rule any) string {
+	switch r := rule.(type) {
+	case *MixinDefinition:
+		return r.Name
+	case *Ruleset:
+		if r.Root || len(r.Selectors) != 1 {
+			return ""
+		}
+		selector, ok := r.Selectors[0].(*Selector)
+		if !ok || len(selector.Elements) != 1 {
+			return ""
+		}
+		if value, ok := selector.Elements[0].Value.(string); ok && (strings.HasPrefix(value, ".") || strings.HasPrefix(value, "#")) {
+			return value
+		}
+	}
+	return ""
+}
+
+// names returns the names of the functions of r and the registries it inherits
+func (r *Registry) names() []string {
+	var names []string
+	for ; r != nil; r = r.base {
+		for name := range r.data {
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+// mixinSignature returns the signature of a mixin candidate for a call, as
+// .m(@a; @b: 2px) when (iscolor(@a)), to list it when no candidate matched
+func mixinSignature(mixin any) string {
+	p := &printer{}
+	switch m := mixin.(type) {
+	case *MixinDefinition:
+		p.WriteString(m.Name)
+		p.mixinParams(m)
+		if m.Condition != nil {
+			p.WriteString(" when ")
+			p.condition(m.Condition, false)
+		}
+	case *Ruleset:
+		for i, selector := range m.Selectors {
+			if i > 0 {
+				p.WriteString(", ")
+			}
+			if s, ok := selector.(*Selector); ok {
+				p.selector(s)
+			}
+		}
+	}
+	return p.String()
+}
@@ -0,0 +1,142 @@
+package less_go
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestSuggestNames(t *testing.T) {
+	candidates := []string{"@brand", "@brand-color", "@border", "@band", "@background"}
+	tests := []struct {
+		name string
+		want []string
+	}{
+		{"@brnad", []string{"@brand"}},
+		{"@Brand", []string{"@brand", "@band"}},
+		{"@brand-colour", []string{"@brand-color"}},
+		{"@backgruond", []string{"@background"}},
+		{"@width", nil},
+	}
+	for _, tt := range tests {
+		if got := suggestNames(tt.name, candidates); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("suggestNames(%q) = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestEditDistance(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want int
+	}{
+		{"", "abc", 3},
+		{"lighten", "lighten", 0},
+		{"lighen", "lighten", 1},
+		{"widht", "width", 1},
+		{"darken", "fade", 4},
+	}
+	for _, tt := range tests {
+		if got := editDistance(tt.a, tt.b); got != tt.want {
+			t.Errorf("editDistance(%q, %q) = %d, want %d", tt.a, tt.b, got, tt.want)
+		}
+	}
+}
+
+// compileError compiles input and returns its *LessError
+func compileError(t *testing.T, input string) *LessError {
+	t.Helper()
+	_, err := Compile(input, nil)
+	var lessErr *LessError
+	if !errors.As(err, &lessErr) {
+		t.Fatalf("expected a *LessError, got %v", err)
+	}
+	return lessErr
+}
+
+func TestCompile_SuggestsVariables(t *testing.T) {
+	err := compileError(t, "@brand: red;\n.m() { color: @Brand; }\n.a { @brand-color: blue; .m(); }")
+	if err.Message != "variable @Brand is undefined" || !reflect.DeepEqual(err.Suggestions, []string{"@brand"}) {
+		t.Errorf("unexpected error %q with suggestions %q", err.Message, err.Suggestions)
+	}
+
+	// Undefined variables are errors when collecting errors, with suggestions too
+	_, collected := Compile(".a { @gap: 1px; margin: @gpa; }", &CompileOptions{CollectErrors: true})
+	var multi *MultiError
+	if !errors.As(collected, &multi) || !reflect.DeepEqual(multi.Errors[0].Suggestions, []string{"@gap"}) {
+		t.Errorf("expected @gap to be suggested, got %v", collected)
+	}
+}
+
+func TestCompile_WarnsOfUndefinedVariables(t *testing.T) {
+	input := "@brand: red;\n@border: 1px solid @brnad;\n.a { color: @brnad; border: @border; }\n.b { border: @border; }\n"
+	result, err := Compile(input, &CompileOptions{Filename: "main.less"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The variable is output as written, and warned about once where it is used,
+	// however often @border is evaluated
+	if !strings.Contains(result.CSS, "color: @brnad;") {
+		t.Errorf("expected @brnad to be output as written, got %s", result.CSS)
+	}
+	want := []string{
+		"main.less:2:20: warning: variable @brnad is undefined; did you mean @brand? [undefined-variable]",
+		"main.less:3:13: warning: variable @brnad is undefined; did you mean @brand? [undefined-variable]",
+	}
+	var got []string
+	for _, d := range result.Warnings {
+		got = append(got, d.String())
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("expected warnings %q, got %q", want, got)
+	}
+
+	// Collecting errors, it is an error instead
+	var warnings []Diagnostic
+	_, err = Compile(input, &CompileOptions{CollectErrors: true, OnDiagnostic: func(d Diagnostic) { warnings = append(warnings, d) }})
+	if err == nil || len(warnings) != 0 {
+		t.Errorf("expected an error and no warnings, got %v and %v", err, warnings)
+	}
+}
+
+func TestCompile_SuggestsMixins(t *testing.T) {
+	err := compileError(t, ".button-primary() { a: b; }\n.x { .buton-primary(); }")
+	if !reflect.DeepEqual(err.Suggestions, []string{".button-primary"}) {
+		t.Errorf("unexpected suggestions %q", err.Suggestions)
+	}
+
+	err = compileError(t, "#ns { .mixin() { a: b; } }\n.x { #ns > .mixn(); }")
+	if !reflect.DeepEqual(err.Suggestions, []string{"#ns.mixin"}) {
+		t.Errorf("unexpected suggestions %q", err.Suggestions)
+	}
+
+	// Suggestions are kept when the call is inside another mixin
+	err = compileError(t, ".b-p() {}\n.m() { .b_p(); }\n.x { .m(); }")
+	if !reflect.DeepEqual(err.Suggestions, []string{".b-p"}) {
+		t.Errorf("unexpected suggestions %q", err.Suggestions)
+	}
+}
+
+func TestCompile_ListsMixinCandidates(t *testing.T) {
+	err := compileError(t, ".m(@a) when (iscolor(@a)) { a: @a; }\n.m(@a; @b: 2px) { b: @b; }\n.x { .m(1; 2; 3); }")
+	want := []string{".m(@a) when (iscolor(@a))", ".m(@a, @b: 2px)"}
+	if err.Type != "Runtime" || !reflect.DeepEqual(err.Candidates, want) {
+		t.Errorf("got %s error with candidates %q, want %q", err.Type, err.Candidates, want)
+	}
+}
+
+func TestCompile_SuggestsFunctions(t *testing.T) {
+	err := compileError(t, "lighen(red, 10%);")
+	if !reflect.DeepEqual(err.Suggestions, []string{"lighten"}) {
+		t.Errorf("unexpected suggestions %q", err.Suggestions)
+	}
+
+	_, compileErr := Compile("shde(red, 10%);", &CompileOptions{
+		Functions: map[string]FunctionDefinition{"shade": Function(func(ctx *Context, args ...any) (any, error) { return nil, nil })},
+	})
+	var lessErr *LessError
+	if !errors.As(compileErr, &lessErr) || !reflect.DeepEqual(lessErr.Suggestions, []string{"shade"}) {
+		t.Errorf("expected the custom function to be suggested, got %v", compileErr)
+	}
+}
@@ -923,10 +923,18 @@ func (v *ToCSSVisitor) CheckValidNodes(rules []any, isRoot bool) error {
 						}
 					}
 				}
+				// Suggest the functions of the compilation if it is misspelled
+				registry := DefaultRegistry
+				if ctx, ok := v.context.(map[string]any); ok {
+					if r, ok := ctx["functionRegistry"].(*Registry); ok && r != nil {
+						registry = r
+					}
+				}
 				return &LessError{
-					Message:  "Function '" + callNode.GetName() + "' did not return a root node",
-					Index:    callNode.GetIndex(),
-					Filename: filename,
+					Message:     "Function '" + callNode.GetName() + "' did not return a root node",
+					Index:       callNode.GetIndex(),
+					Filename:    filename,
+					Suggestions: suggestNames(callNode.GetName(), registry.names()),
 				}
 			}
 		}
@@ -202,9 +202,17 @@ func TransformTree(root any, options map[string]any) any {
 	joinSelectorVisitor := GetJoinSelectorVisitor()
 	setTreeVisibilityVisitor := GetSetTreeVisibilityVisitor(true)
 	extendVisitor := GetProcessExtendsVisitor()
+	extendVisitor.diagnostics = evalEnv.diagnostics
+	if importManager, ok := options["importManager"].(*ImportManager); ok && importManager != nil {
+		extendVisitor.contents = importManager.Contents()
+		if evalEnv.diagnostics != nil {
+			evalEnv.diagnostics.contents = extendVisitor.contents
+		}
+	}
 	toCSSVisitor := GetToCSSVisitor(map[string]any{
-		"compress":    getBoolOption(options, "compress"),
-		"strictUnits": getBoolOption(options, "strictUnits"),
+		"compress":         getBoolOption(options, "compress"),
+		"strictUnits":      getBoolOption(options, "strictUnits"),
+		"functionRegistry": functionRegistry,
 	})
 	// Defer release of visitors back to pools
 	defer ReleaseJoinSelectorVisitor(joinSelectorVisitor)
@@ -218,6 +226,11 @@ func TransformTree(root any, options map[string]any) any {
 		extendVisitor,
 		toCSSVisitor,
 	}
+	if resolveModernColors, _ := options["resolveModernColors"].(ModernColorsType); resolveModernColors != ModernColors.Off {
+		visitorList = append(visitorList, newModernColorVisitor())
+	}
+	minContrastRatio, _ := options["minContrastRatio"].(float64)
+	visitorList = append(visitorList, newContrastVisitor(minContrastRatio, evalEnv.diagnostics, extendVisitor.contents))
 
 	preEvalVisitors := make([]any, 0)
 	var v any
@@ -229,8 +242,12 @@ func TransformTree(root any, options map[string]any) any {
 	 * @todo Add scoping for visitors just like functions for @plugin; right now they're global
 	 */
 	if pluginManager := options["pluginManager"]; pluginManager != nil {
-		if pm, ok := pluginManager.(interface{ Visitor() any }); ok {
+		if pm, ok := pluginManager.(*PluginManager); ok {
+			visitorIterator = pluginVisitorIterator{pm.Visitor()}
+		} else if pm, ok := pluginManager.(interface{ Visitor() any }); ok {
 			visitorIterator = pm.Visitor()
+		}
+		if visitorIterator != nil {
 			for i := 0; i < 2; i++ {
 				if vi, ok := visitorIterator.(interface{ First() }); ok {
 					vi.First()
@@ -316,6 +333,18 @@ func TransformTree(root any, options map[string]any) any {
 		evaldRoot = processedRoot
 	}
 
+	// Let the caller read the evaluated tree before the visitors remove its
+	// variables, see ResolveVariables
+	if onEvaluated, ok := options["onEvaluated"].(func(*Ruleset, *Eval, map[string]string)); ok {
+		if ruleset, ok := evaldRoot.(*Ruleset); ok {
+			var contents map[string]string
+			if importManager, ok := options["importManager"].(*ImportManager); ok && importManager != nil {
+				contents = importManager.Contents()
+			}
+			onEvaluated(ruleset, evalEnv, contents)
+		}
+	}
+
 	// Run all visitors exactly like JavaScript
 	for _, visitor := range visitorList {
 		if runner, ok := visitor.(interface{ Run(any) any }); ok {
@@ -352,6 +381,16 @@ func TransformTree(root any, options map[string]any) any {
 	return evaldRoot
 }
 
+// pluginVisitorIterator adapts the visitor iterator of a PluginManager to the
+// First/Get interface used above
+type pluginVisitorIterator struct {
+	*VisitorIterator
+}
+
+func (vi pluginVisitorIterator) First() {
+	vi.VisitorIterator.First()
+}
+
 // Helper function to check if a value is an array
 func isArray(value any) bool {
 	if value == nil {
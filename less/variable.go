@@ -49,6 +49,11 @@ func (v *Variable) GetName() string {
 	return v.name
 }
 
+// SetName renames the variable, e.g. to "@brand", for codemods on parsed trees
+func (v *Variable) SetName(name string) {
+	v.name = Intern(name)
+}
+
 func (v *Variable) Eval(context any) (any, error) {
 	name := v.name
 
@@ -321,10 +326,11 @@ func (v *Variable) Eval(context any) (any, error) {
 		}
 	}
 	return nil, &LessError{
-		Type:     "Name",
-		Message:  fmt.Sprintf("variable %s is undefined", name),
-		Filename: filename,
-		Index:    v.GetIndex(),
+		Type:        "Name",
+		Message:     fmt.Sprintf("variable %s is undefined", name),
+		Filename:    filename,
+		Index:       v.GetIndex(),
+		Suggestions: suggestNames(name, variableNames(evalFrames(context))),
 	}
 }
 
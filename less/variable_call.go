@@ -40,8 +40,16 @@ func (vc *VariableCall) FileInfo() map[string]any {
 	return vc._fileInfo
 }
 
-// Eval evaluates the variable call - match JavaScript implementation
-func (vc *VariableCall) Eval(context any) (result any, err error) {
+// Eval evaluates the variable call, on the call stack of errors
+func (vc *VariableCall) Eval(context any) (any, error) {
+	calls := callStackOf(context)
+	depth := calls.enter("ruleset", vc)
+	result, err := vc.eval(context)
+	return result, calls.leave(depth, err)
+}
+
+// eval evaluates the variable call - match JavaScript implementation
+func (vc *VariableCall) eval(context any) (result any, err error) {
 	// Debug: trace incoming context for all variable calls
 	if os.Getenv("LESS_GO_DEBUG") == "1" {
 		switch ctx := context.(type) {
@@ -0,0 +1,209 @@
+package less_go
+
+import "math"
+
+// ResolvedVariable is a root-level variable of a stylesheet, or a member of a
+// detached ruleset, with its evaluated value and where it is defined
+type ResolvedVariable struct {
+	// Name is the name as declared: "@brand" for a variable, "primary" for a
+	// property of a detached ruleset
+	Name string `json:"name"`
+	ResolvedValue
+	Filename string `json:"filename,omitempty"`
+	Line     int    `json:"line,omitempty"`
+	Column   int    `json:"column,omitempty"`
+}
+
+// ResolvedValue is an evaluated value, typed for export to other languages
+type ResolvedValue struct {
+	// Type is "color", "dimension", "string", "keyword", "list", "ruleset"
+	// or, for values such as url() or function calls, "other"
+	Type string `json:"type"`
+	// CSS is the value as it would be output, except for rulesets
+	CSS string `json:"css,omitempty"`
+	// Value is the hex code of a color (#rrggbb, or #rrggbbaa if it is
+	// translucent), the number of a dimension, or the text of a string or keyword
+	Value any `json:"value,omitempty"`
+	// RGBA is the red, green and blue (0-255) and alpha (0-1) of a color
+	RGBA []float64 `json:"rgba,omitempty"`
+	// Unit is the unit of a dimension
+	Unit string `json:"unit,omitempty"`
+	// Separator is "comma" or "space" for a list
+	Separator string          `json:"separator,omitempty"`
+	Items     []ResolvedValue `json:"items,omitempty"`
+	// Members are the declarations of a detached ruleset
+	Members []ResolvedVariable `json:"members,omitempty"`
+}
+
+// ResolveVariables compiles a stylesheet and returns its root-level variables,
+// those of its imports included, with their evaluated values, in order of
+// definition. A variable defined more than once is returned with its last
+// definition, which is the one in effect. Detached rulesets are evaluated and
+// returned with their members, e.g. to generate design tokens from a theme.
+func ResolveVariables(input string, options *CompileOptions) ([]ResolvedVariable, error) {
+	opts := CompileOptions{}
+	if options != nil {
+		opts = *options
+	}
+	var variables []ResolvedVariable
+	opts.onEvaluated = func(root *Ruleset, context *Eval, contents map[string]string) {
+		r := variableResolver{context: context, contents: contents, rootFilename: opts.Filename}
+		if r.rootFilename == "" {
+			r.rootFilename = "input"
+		}
+		// The parser adds GlobalVars before the root file's source and ModifyVars after it
+		if len(opts.GlobalVars) > 0 {
+			r.prefix = len(SerializeVars(opts.GlobalVars)) + 1
+		}
+		if len(opts.ModifyVars) > 0 {
+			r.suffix = len(SerializeVars(opts.ModifyVars)) + 1
+		}
+		variables = r.declarations(root.Rules)
+	}
+	if _, err := Compile(input, &opts); err != nil {
+		return nil, err
+	}
+	return variables, nil
+}
+
+// variableResolver converts evaluated declarations to ResolvedVariables
+type variableResolver struct {
+	context      *Eval
+	contents     map[string]string
+	rootFilename string
+	// prefix and suffix are the lengths of the text added to the root file
+	prefix, suffix int
+}
+
+// declarations returns the declarations among rules, keeping the last of
+// those with the same name
+func (r *variableResolver) declarations(rules []any) []ResolvedVariable {
+	last := make(map[string]*Declaration)
+	for _, rule := range rules {
+		if d, ok := rule.(*Declaration); ok {
+			if name, ok := d.name.(string); ok {
+				last[name] = d
+			}
+		}
+	}
+	var variables []ResolvedVariable
+	for _, rule := range rules {
+		d, ok := rule.(*Declaration)
+		if !ok || last[d.GetName()] != d {
+			continue
+		}
+		v := ResolvedVariable{Name: d.GetName(), ResolvedValue: r.value(d.Value)}
+		v.Filename, v.Line, v.Column = r.position(d)
+		variables = append(variables, v)
+	}
+	return variables
+}
+
+// position returns where d is defined, or no position if it was added by
+// GlobalVars or ModifyVars
+func (r *variableResolver) position(d *Declaration) (filename string, line, column int) {
+	filename, _ = d.FileInfo()["filename"].(string)
+	source, ok := r.contents[filename]
+	if !ok {
+		return filename, 0, 0
+	}
+	index := d.GetIndex()
+	if filename == r.rootFilename {
+		if index < r.prefix || index >= len(source)-r.suffix {
+			return "", 0, 0
+		}
+		source, index = source[r.prefix:], index-r.prefix
+	}
+	if index < 0 || index > len(source) {
+		return filename, 0, 0
+	}
+	loc := GetLocation(index, source)
+	if loc.Line == nil {
+		return filename, 0, 0
+	}
+	return filename, *loc.Line + 1, loc.Column + 1
+}
+
+// value converts an evaluated value
+func (r *variableResolver) value(node any) ResolvedValue {
+	switch n := node.(type) {
+	case *Value:
+		if len(n.Value) == 1 {
+			return r.value(n.Value[0])
+		}
+		return r.list(node, "comma", n.Value)
+	case *Expression:
+		if len(n.Value) == 1 {
+			return r.value(n.Value[0])
+		}
+		return r.list(node, "space", n.Value)
+	case *Color:
+		rgba := make([]float64, 0, 4)
+		for _, c := range n.RGB {
+			rgba = append(rgba, math.Round(clamp(c, 255)))
+		}
+		hex := n.ToRGB()
+		if n.Alpha < 1 && len(n.RGB) == 3 {
+			hex = toHex(append(append([]float64{}, n.RGB...), clamp(n.Alpha, 1)*255))
+		}
+		return ResolvedValue{Type: "color", CSS: Print(n), Value: hex, RGBA: append(rgba, n.Alpha)}
+	case *Dimension:
+		v := ResolvedValue{Type: "dimension", CSS: Print(n), Value: n.Value}
+		if n.Unit != nil {
+			v.Unit = n.Unit.ToString()
+		}
+		return v
+	case *Quoted:
+		return ResolvedValue{Type: "string", CSS: Print(n), Value: n.value}
+	case *Keyword:
+		return ResolvedValue{Type: "keyword", CSS: Print(n), Value: n.GetValue()}
+	case *Anonymous:
+		// Simple values such as 4px or red are kept as text by the parser
+		if text, ok := n.Value.(string); ok {
+			if parsed := r.parseValue(text); parsed != nil {
+				return r.value(parsed)
+			}
+		}
+	case *DetachedRuleset:
+		v := ResolvedValue{Type: "ruleset"}
+		if ruleset, ok := n.CallEval(r.context).(*Ruleset); ok {
+			v.Members = r.declarations(ruleset.Rules)
+		}
+		return v
+	}
+	return ResolvedValue{Type: "other", CSS: Print(node)}
+}
+
+// parseValue parses and evaluates the text of an anonymous value, returning
+// nil if it is not a value or is anonymous again
+func (r *variableResolver) parseValue(text string) any {
+	nodes, err := (&Parser{}).CreateValueParseFunc()(text, map[string]any{}, map[string]any{}, map[string]any{}, 0)
+	if err != nil || len(nodes) == 0 {
+		return nil
+	}
+	value, ok := nodes[0].(*Value)
+	if !ok {
+		return nil
+	}
+	evaluated, err := value.Eval(r.context)
+	if err != nil {
+		return nil
+	}
+	if v, ok := evaluated.(*Value); ok && len(v.Value) == 1 {
+		if e, ok := v.Value[0].(*Expression); ok && len(e.Value) == 1 {
+			if _, ok := e.Value[0].(*Anonymous); ok {
+				return nil
+			}
+		}
+	}
+	return evaluated
+}
+
+// list converts a comma or space separated list
+func (r *variableResolver) list(node any, separator string, items []any) ResolvedValue {
+	v := ResolvedValue{Type: "list", CSS: Print(node), Separator: separator}
+	for _, item := range items {
+		v.Items = append(v.Items, r.value(item))
+	}
+	return v
+}
@@ -0,0 +1,129 @@
+package less_go
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+func TestResolveVariables_Types(t *testing.T) {
+	input := `@brand: #336699;
+@overlay: fade(@brand, 50%);
+@named: red;
+@gap: 4px * 2;
+@ratio: 1.5;
+@font: "Helvetica Neue", Arial, sans-serif;
+@padding: 1px 2px;
+@mode: dark;
+@label: "Save";
+@bg: url("a.png");
+`
+	vars, err := ResolveVariables(input, nil)
+	if err != nil {
+		t.Fatalf("ResolveVariables failed: %v", err)
+	}
+	got := make(map[string]ResolvedValue)
+	for _, v := range vars {
+		got[v.Name] = v.ResolvedValue
+	}
+
+	tests := []struct {
+		name string
+		want ResolvedValue
+	}{
+		{"@brand", ResolvedValue{Type: "color", CSS: "#336699", Value: "#336699", RGBA: []float64{51, 102, 153, 1}}},
+		{"@overlay", ResolvedValue{Type: "color", CSS: "rgba(51, 102, 153, 0.5)", Value: "#33669980", RGBA: []float64{51, 102, 153, 0.5}}},
+		{"@named", ResolvedValue{Type: "color", CSS: "red", Value: "#ff0000", RGBA: []float64{255, 0, 0, 1}}},
+		{"@gap", ResolvedValue{Type: "dimension", CSS: "8px", Value: 8.0, Unit: "px"}},
+		{"@ratio", ResolvedValue{Type: "dimension", CSS: "1.5", Value: 1.5}},
+		{"@mode", ResolvedValue{Type: "keyword", CSS: "dark", Value: "dark"}},
+		{"@label", ResolvedValue{Type: "string", CSS: `"Save"`, Value: "Save"}},
+		{"@bg", ResolvedValue{Type: "other", CSS: `url("a.png")`}},
+		{"@padding", ResolvedValue{Type: "list", CSS: "1px 2px", Separator: "space", Items: []ResolvedValue{
+			{Type: "dimension", CSS: "1px", Value: 1.0, Unit: "px"},
+			{Type: "dimension", CSS: "2px", Value: 2.0, Unit: "px"},
+		}}},
+		{"@font", ResolvedValue{Type: "list", CSS: `"Helvetica Neue", Arial, sans-serif`, Separator: "comma", Items: []ResolvedValue{
+			{Type: "string", CSS: `"Helvetica Neue"`, Value: "Helvetica Neue"},
+			{Type: "keyword", CSS: "Arial", Value: "Arial"},
+			{Type: "keyword", CSS: "sans-serif", Value: "sans-serif"},
+		}}},
+	}
+	for _, tt := range tests {
+		if !reflect.DeepEqual(got[tt.name], tt.want) {
+			t.Errorf("%s: got %+v, want %+v", tt.name, got[tt.name], tt.want)
+		}
+	}
+	if len(vars) != 10 || vars[0].Name != "@brand" || vars[9].Name != "@bg" {
+		t.Errorf("expected the variables in order of definition, got %d", len(vars))
+	}
+}
+
+func TestResolveVariables_Locations(t *testing.T) {
+	fsys := fstest.MapFS{
+		"tokens.less": {Data: []byte("@space: 4px;\n@brand: red;\n")},
+	}
+	input := "@import \"tokens.less\";\n@brand: #336699;\n.a { @local: 1px; }\n@b: @g;\n"
+	vars, err := ResolveVariables(input, &CompileOptions{
+		Filename:   "theme.less",
+		FS:         fsys,
+		GlobalVars: map[string]any{"g": "2px"},
+		ModifyVars: map[string]any{"space": "8px"},
+	})
+	if err != nil {
+		t.Fatalf("ResolveVariables failed: %v", err)
+	}
+	var got []string
+	for _, v := range vars {
+		got = append(got, fmt.Sprintf("%s=%s@%s:%d:%d", v.Name, v.CSS, v.Filename, v.Line, v.Column))
+	}
+	want := []string{"@g=2px@:0:0", "@brand=#336699@theme.less:2:1", "@b=2px@theme.less:4:1", "@space=8px@:0:0"}
+	if strings.Join(got, " ") != strings.Join(want, " ") {
+		t.Errorf("got %v, want %v", got, want)
+	}
+}
+
+func TestResolveVariables_DetachedRulesets(t *testing.T) {
+	input := `@brand: #336699;
+@theme: {
+  primary: @brand;
+  @radius: 2px;
+  @spacing: { small: 4px; }
+};
+`
+	vars, err := ResolveVariables(input, nil)
+	if err != nil {
+		t.Fatalf("ResolveVariables failed: %v", err)
+	}
+	theme := vars[1]
+	if theme.Name != "@theme" || theme.Type != "ruleset" || len(theme.Members) != 3 {
+		t.Fatalf("expected @theme with 3 members, got %+v", theme)
+	}
+	if m := theme.Members[0]; m.Name != "primary" || m.Type != "color" || m.Value != "#336699" || m.Line != 3 || m.Column != 3 {
+		t.Errorf("unexpected member %+v", m)
+	}
+	spacing := theme.Members[2]
+	if spacing.Type != "ruleset" || len(spacing.Members) != 1 || spacing.Members[0].CSS != "4px" {
+		t.Errorf("expected the nested ruleset to be evaluated, got %+v", spacing)
+	}
+
+	data, err := json.Marshal(theme)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(data), `"name":"@theme","type":"ruleset","members":[{"name":"primary","type":"color"`) {
+		t.Errorf("unexpected JSON %s", data)
+	}
+}
+
+func TestResolveVariables_Error(t *testing.T) {
+	_, err := ResolveVariables("@a: 1px;\n@b: percentage(\"x\");", &CompileOptions{Filename: "main.less"})
+	var lessErr *LessError
+	if !errors.As(err, &lessErr) || lessErr.Type != "Argument" || lessErr.LineNumber() != 2 {
+		t.Errorf("expected an Argument error on line 2, got %v", err)
+	}
+}
@@ -4,9 +4,13 @@ import (
 	"reflect"
 	"strconv"
 	"sync"
+	"sync/atomic"
 )
 
-var _hasIndexed = false
+// _hasIndexed is set once the node types of treeRegistry have been indexed,
+// which the first visitor or node created does under treeIndexMu
+var _hasIndexed atomic.Bool
+var treeIndexMu sync.Mutex
 
 type VisitArgs struct {
 	VisitDeeper bool
@@ -74,6 +78,9 @@ type Visitor struct {
 	cachedIsReplacing bool                     // Cached result of isReplacing() - computed once at construction
 	visitArgs         [64]VisitArgs
 	visitDepth        int
+	// visitUnindexed also dispatches nodes whose constructors do not set a type
+	// index, which the compiler's visitors never need; see Inspect
+	visitUnindexed bool
 }
 
 func (v *Visitor) acquireVisitArgs() (*VisitArgs, bool) {
@@ -103,19 +110,7 @@ func NewVisitor(implementation any) *Visitor {
 		visitOutCache:  make(map[int]VisitOutFunc),
 	}
 
-	if !_hasIndexed {
-		// Initialize tree with actual go_parser node types
-		initializeTree()
-		// Index the NodeTypes map directly since that's where our prototypes are
-		ticker := 1
-		for _, nodeProto := range treeRegistry.NodeTypes {
-			if proto, ok := nodeProto.(*NodePrototype); ok {
-				proto.SetTypeIndex(ticker)
-				ticker++
-			}
-		}
-		_hasIndexed = true
-	}
+	indexTree()
 
 	// Only build method lookup map if NOT using direct dispatch
 	// This avoids reflection overhead for implementations that handle all node types
@@ -164,7 +159,7 @@ func (v *Visitor) Visit(node any) any {
 		nodeTypeIndex = nodeWithTypeIndex.GetTypeIndex()
 	}
 
-	if nodeTypeIndex == 0 {
+	if _, isNode := node.(NodeWithType); nodeTypeIndex == 0 && !(v.visitUnindexed && isNode) {
 		// MixinCall args aren't a node type? - exact JS comment
 		if nodeWithValue, ok := node.(NodeWithValue); ok {
 			if value := nodeWithValue.GetValue(); value != nil {
@@ -480,6 +475,29 @@ func (v *Visitor) convertToSlice(obj any) []any {
 	return nil
 }
 
+// indexTree initializes the tree registry and indexes its node types, once
+func indexTree() {
+	if _hasIndexed.Load() {
+		return
+	}
+	treeIndexMu.Lock()
+	defer treeIndexMu.Unlock()
+	if _hasIndexed.Load() {
+		return
+	}
+	// Initialize tree with actual go_parser node types
+	initializeTree()
+	// Index the NodeTypes map directly since that's where our prototypes are
+	ticker := 1
+	for _, nodeProto := range treeRegistry.NodeTypes {
+		if proto, ok := nodeProto.(*NodePrototype); ok {
+			proto.SetTypeIndex(ticker)
+			ticker++
+		}
+	}
+	_hasIndexed.Store(true)
+}
+
 // initializeTree initializes the tree registry with node type prototypes
 // This matches the JS behavior of indexing node constructors by their prototype.type
 func initializeTree() {
@@ -551,10 +569,8 @@ func createNodePrototype(nodeType string) *NodePrototype {
 // GetTypeIndexForNodeType returns the TypeIndex for a given node type string.
 // Used by node constructors to set the TypeIndex field.
 func GetTypeIndexForNodeType(nodeType string) int {
-	if !_hasIndexed {
-		// Initialize if needed - this ensures prototypes are indexed
-		NewVisitor(nil)
-	}
+	// Initialize if needed - this ensures prototypes are indexed
+	indexTree()
 
 	if nodeProto, ok := treeRegistry.NodeTypes[nodeType]; ok {
 		if proto, ok := nodeProto.(*NodePrototype); ok {
@@ -135,9 +135,9 @@ func TestNewVisitor(t *testing.T) {
 
 func TestNewVisitorIndexesNodeTypes(t *testing.T) {
 	// Reset _hasIndexed for testing
-	original_hasIndexed := _hasIndexed
-	_hasIndexed = false
-	defer func() { _hasIndexed = original_hasIndexed }()
+	original_hasIndexed := _hasIndexed.Load()
+	_hasIndexed.Store(false)
+	defer func() { _hasIndexed.Store(original_hasIndexed) }()
 
 	impl := &MockImplementation{}
 	NewVisitor(impl)
@@ -899,9 +899,9 @@ func TestFlattenMissingScenarios(t *testing.T) {
 func TestTreeNodePrototypeIndexing(t *testing.T) {
 	t.Run("should set typeIndex on tree node prototypes", func(t *testing.T) {
 		// Reset indexing for test
-		original_hasIndexed := _hasIndexed
-		_hasIndexed = false
-		defer func() { _hasIndexed = original_hasIndexed }()
+		original_hasIndexed := _hasIndexed.Load()
+		_hasIndexed.Store(false)
+		defer func() { _hasIndexed.Store(original_hasIndexed) }()
 		
 		// Create visitor to trigger indexing
 		NewVisitor(&MockImplementation{})
@@ -925,9 +925,9 @@ func TestTreeNodePrototypeIndexing(t *testing.T) {
 func TestMultipleVisitorInstances(t *testing.T) {
 	t.Run("should index node types only once", func(t *testing.T) {
 		// Reset indexing for test
-		original_hasIndexed := _hasIndexed
-		_hasIndexed = false
-		defer func() { _hasIndexed = original_hasIndexed }()
+		original_hasIndexed := _hasIndexed.Load()
+		_hasIndexed.Store(false)
+		defer func() { _hasIndexed.Store(original_hasIndexed) }()
 		
 		// Create multiple visitors
 		impl1 := &MockImplementation{}